@@ -0,0 +1,88 @@
+// Command jwt-keys manages the key pairs signing the access tokens, with JWT_ALGORITHM
+// set to RS256 or EdDSA. The API picks up changes within JWT_KEY_REFRESH_INTERVAL.
+//
+//	go run ./cmd/jwt-keys list
+//	go run ./cmd/jwt-keys rotate -alg EdDSA -keep 2
+//	go run ./cmd/jwt-keys retire <kid>
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"go-sober/internal/auth"
+	"go-sober/internal/database"
+	"go-sober/platform"
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+	}
+
+	platform.InitPlatform()
+	db, err := database.NewSQLiteDB(platform.AppConfig.Database)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer db.Close()
+	repo := auth.NewRepository(db)
+
+	switch command, args := os.Args[1], os.Args[2:]; command {
+	case "list":
+		list(repo)
+	case "rotate":
+		flags := flag.NewFlagSet("rotate", flag.ExitOnError)
+		algorithm := flags.String("alg", platform.AppConfig.Auth.JWT.Algorithm, "algorithm of the new key: RS256 or EdDSA")
+		keep := flags.Int("keep", 2, "active keys to keep, the oldest ones are retired")
+		flags.Parse(args)
+
+		key, retired, err := auth.RotateSigningKeys(repo, *algorithm, *keep)
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Printf("Created %s key %s\n", key.Algorithm, key.ID)
+		for _, id := range retired {
+			fmt.Printf("Retired key %s\n", id)
+		}
+	case "retire":
+		if len(args) != 1 {
+			usage()
+		}
+		err := repo.RetireSigningKey(args[0])
+		if errors.Is(err, sql.ErrNoRows) {
+			log.Fatalf("no active key %s", args[0])
+		}
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Printf("Retired key %s\n", args[0])
+	default:
+		usage()
+	}
+}
+
+func list(repo *auth.Repository) {
+	keys, err := repo.GetSigningKeys(true)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	for _, key := range keys {
+		status := "active"
+		if key.RetiredAt != nil {
+			status = "retired " + key.RetiredAt.Format(time.RFC3339)
+		}
+		fmt.Printf("%s  %-5s  created %s  %s\n", key.ID, key.Algorithm, key.CreatedAt.Format(time.RFC3339), status)
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: jwt-keys list | rotate [-alg RS256|EdDSA] [-keep n] | retire <kid>")
+	os.Exit(2)
+}
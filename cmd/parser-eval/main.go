@@ -0,0 +1,99 @@
+// Command parser-eval runs the golden dataset of drink phrases through a
+// drink parser backend and reports its accuracy, errors and latency.
+//
+//	go run ./cmd/parser-eval -backend replay
+//	go run ./cmd/parser-eval -backend llm -record
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"go-sober/internal/llm"
+	"go-sober/internal/parser"
+	"go-sober/platform"
+)
+
+const (
+	backendLLM    = "llm"    // Live LLM configured by the platform (needs network and API key)
+	backendReplay = "replay" // LLM parser answering with recorded responses
+	backendRules  = "rules"  // Rule-based parser
+)
+
+func main() {
+	backend := flag.String("backend", backendReplay, "parser backend: llm, replay or rules")
+	corpusPath := flag.String("corpus", "internal/parser/testdata/golden.json", "golden dataset")
+	recordingPath := flag.String("recording", "internal/parser/testdata/llm_recording.json", "recorded LLM responses")
+	record := flag.Bool("record", false, "with the llm backend, save the responses to the recording file")
+	asJSON := flag.Bool("json", false, "print the full report as JSON")
+	showFailures := flag.Bool("failures", false, "print the phrases with wrong fields")
+	flag.Parse()
+
+	cases, err := parser.LoadEvalCorpus(*corpusPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	var recorder *llm.RecordingProvider
+	var drinkParser parser.DrinkParser
+
+	switch *backend {
+	case backendLLM:
+		platform.InitPlatform()
+		groqProvider, err := llm.NewGroqProvider(platform.AppConfig.LLM)
+		if err != nil {
+			log.Fatal(err)
+		}
+		var provider llm.Provider = groqProvider
+		if *record {
+			recorder = llm.NewRecordingProvider(provider, platform.AppConfig.LLM.Groq.Model)
+			provider = recorder
+		}
+		drinkParser = parser.NewLLMParser(provider)
+	case backendReplay:
+		recording, err := llm.LoadRecording(*recordingPath)
+		if err != nil {
+			log.Fatal(err)
+		}
+		drinkParser = parser.NewLLMParser(llm.NewReplayProvider(recording))
+	case backendRules:
+		drinkParser = parser.NewRuleParser()
+	default:
+		log.Fatalf("unknown backend %q", *backend)
+	}
+
+	report := parser.Evaluate(*backend, drinkParser, cases)
+
+	if recorder != nil {
+		if err := recorder.Recording().Save(*recordingPath); err != nil {
+			log.Fatal(err)
+		}
+		log.Printf("Saved %d responses to %s", len(recorder.Recording().Responses), *recordingPath)
+	}
+
+	if *asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(report); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	fmt.Print(report.String())
+
+	if *showFailures {
+		fmt.Println("Failures:")
+		for _, failure := range report.Failures() {
+			if failure.Error != "" {
+				fmt.Printf("  %q: error: %s\n", failure.Input, failure.Error)
+				continue
+			}
+			fmt.Printf("  %q: %+v -> %s | %s | %.0f %s | %.3f\n", failure.Input, failure.FieldsOK,
+				failure.Parsed.Name, failure.Parsed.Type, failure.Parsed.SizeValue, failure.Parsed.SizeUnit, failure.Parsed.ABV)
+		}
+	}
+}
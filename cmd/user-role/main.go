@@ -0,0 +1,50 @@
+// Command user-role changes the role of a user, to appoint the first admin. The user is
+// logged out of every device, so that the new role is in the next access tokens.
+//
+//	go run ./cmd/user-role someone@example.com admin
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+
+	"go-sober/internal/auth"
+	"go-sober/internal/database"
+	"go-sober/internal/models"
+	"go-sober/platform"
+)
+
+func main() {
+	if len(os.Args) != 3 || !models.Role(os.Args[2]).Valid() {
+		fmt.Fprintln(os.Stderr, "usage: user-role <email> user|admin")
+		os.Exit(2)
+	}
+	email, role := os.Args[1], models.Role(os.Args[2])
+
+	platform.InitPlatform()
+	db, err := database.NewSQLiteDB(platform.AppConfig.Database)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer db.Close()
+	repo := auth.NewRepository(db)
+
+	user, err := repo.GetUserByEmail(email)
+	if errors.Is(err, sql.ErrNoRows) {
+		log.Fatalf("no user %s", email)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if err := repo.SetUserRole(user.ID, role); err != nil {
+		log.Fatal(err)
+	}
+	if err := repo.RevokeUserSessions(user.ID); err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("%s is now %s\n", email, role)
+}
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_parse_cache_expires_at;
+
+DROP TABLE IF EXISTS parse_cache;
@@ -0,0 +1,10 @@
+-- Cache of LLM parse results, keyed by the normalised input text
+CREATE TABLE
+    IF NOT EXISTS parse_cache (
+        cache_key TEXT PRIMARY KEY,
+        result TEXT NOT NULL,
+        created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+        expires_at DATETIME NOT NULL
+    );
+
+CREATE INDEX idx_parse_cache_expires_at ON parse_cache (expires_at);
@@ -0,0 +1,20 @@
+-- Refresh tokens, one row per token. Rotating a token adds a row to the same family,
+-- a family is a logged in device.
+CREATE TABLE
+    IF NOT EXISTS sessions (
+        id INTEGER PRIMARY KEY AUTOINCREMENT,
+        user_id INTEGER NOT NULL,
+        family_id TEXT NOT NULL,
+        token_hash TEXT UNIQUE NOT NULL,
+        user_agent TEXT NOT NULL DEFAULT '',
+        ip_address TEXT NOT NULL DEFAULT '',
+        created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+        expires_at DATETIME NOT NULL,
+        rotated_at DATETIME DEFAULT NULL,
+        revoked_at DATETIME DEFAULT NULL,
+        FOREIGN KEY (user_id) REFERENCES users (id)
+    );
+
+CREATE INDEX idx_sessions_user_id ON sessions (user_id);
+
+CREATE INDEX idx_sessions_family_id ON sessions (family_id);
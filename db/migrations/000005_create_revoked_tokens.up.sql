@@ -0,0 +1,11 @@
+-- Access tokens revoked before their expiry, by JWT ID
+CREATE TABLE
+    IF NOT EXISTS revoked_tokens (
+        jti TEXT PRIMARY KEY,
+        user_id INTEGER NOT NULL,
+        expires_at DATETIME NOT NULL,
+        revoked_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+        FOREIGN KEY (user_id) REFERENCES users (id)
+    );
+
+CREATE INDEX idx_revoked_tokens_expires_at ON revoked_tokens (expires_at);
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_user_tokens_user_id;
+
+DROP TABLE IF EXISTS user_tokens;
+
+ALTER TABLE users DROP COLUMN email_verified_at;
@@ -0,0 +1,16 @@
+ALTER TABLE users ADD COLUMN email_verified_at DATETIME DEFAULT NULL;
+
+-- Single-use tokens sent by email (email verification, password reset)
+CREATE TABLE
+    IF NOT EXISTS user_tokens (
+        id INTEGER PRIMARY KEY AUTOINCREMENT,
+        user_id INTEGER NOT NULL,
+        purpose TEXT NOT NULL CHECK (purpose IN ('email_verification', 'password_reset')),
+        token_hash TEXT UNIQUE NOT NULL,
+        created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+        expires_at DATETIME NOT NULL,
+        used_at DATETIME DEFAULT NULL,
+        FOREIGN KEY (user_id) REFERENCES users (id)
+    );
+
+CREATE INDEX idx_user_tokens_user_id ON user_tokens (user_id);
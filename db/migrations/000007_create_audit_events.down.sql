@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_audit_events_created_at;
+
+DROP INDEX IF EXISTS idx_audit_events_user_id;
+
+DROP TABLE IF EXISTS audit_events;
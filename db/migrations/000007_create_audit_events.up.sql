@@ -0,0 +1,16 @@
+-- Security relevant events (failed logins, lockouts...), kept when the user is deleted
+CREATE TABLE
+    IF NOT EXISTS audit_events (
+        id INTEGER PRIMARY KEY AUTOINCREMENT,
+        user_id INTEGER DEFAULT NULL,
+        event_type TEXT NOT NULL,
+        email TEXT NOT NULL DEFAULT '',
+        ip_address TEXT NOT NULL DEFAULT '',
+        user_agent TEXT NOT NULL DEFAULT '',
+        details TEXT NOT NULL DEFAULT '{}',
+        created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+    );
+
+CREATE INDEX idx_audit_events_user_id ON audit_events (user_id);
+
+CREATE INDEX idx_audit_events_created_at ON audit_events (created_at);
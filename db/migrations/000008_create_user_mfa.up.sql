@@ -0,0 +1,23 @@
+-- TOTP second factor, enabled once the user has confirmed a first code
+CREATE TABLE
+    IF NOT EXISTS user_mfa (
+        user_id INTEGER PRIMARY KEY,
+        totp_secret TEXT NOT NULL,
+        last_used_step INTEGER NOT NULL DEFAULT 0, -- Codes of this step or older are rejected
+        created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+        enabled_at DATETIME DEFAULT NULL,
+        FOREIGN KEY (user_id) REFERENCES users (id)
+    );
+
+-- Single-use codes to log in without the authenticator app, stored hashed
+CREATE TABLE
+    IF NOT EXISTS mfa_recovery_codes (
+        id INTEGER PRIMARY KEY AUTOINCREMENT,
+        user_id INTEGER NOT NULL,
+        code_hash TEXT UNIQUE NOT NULL,
+        created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+        used_at DATETIME DEFAULT NULL,
+        FOREIGN KEY (user_id) REFERENCES users (id)
+    );
+
+CREATE INDEX idx_mfa_recovery_codes_user_id ON mfa_recovery_codes (user_id);
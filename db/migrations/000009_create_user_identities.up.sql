@@ -0,0 +1,25 @@
+-- Accounts of external identity providers (OpenID Connect) linked to users
+CREATE TABLE
+    IF NOT EXISTS user_identities (
+        id INTEGER PRIMARY KEY AUTOINCREMENT,
+        user_id INTEGER NOT NULL,
+        issuer TEXT NOT NULL,
+        subject TEXT NOT NULL,
+        email TEXT NOT NULL DEFAULT '',
+        created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+        last_login_at DATETIME DEFAULT NULL,
+        UNIQUE (issuer, subject),
+        FOREIGN KEY (user_id) REFERENCES users (id)
+    );
+
+CREATE INDEX idx_user_identities_user_id ON user_identities (user_id);
+
+-- Pending logins at the provider: the state sent to it, with the nonce and PKCE verifier to check its answer
+CREATE TABLE
+    IF NOT EXISTS oidc_login_states (
+        state_hash TEXT PRIMARY KEY,
+        nonce TEXT NOT NULL,
+        code_verifier TEXT NOT NULL,
+        created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+        expires_at DATETIME NOT NULL
+    );
@@ -0,0 +1,17 @@
+-- Long-lived tokens for scripts and integrations, limited to some scopes
+CREATE TABLE
+    IF NOT EXISTS personal_access_tokens (
+        id INTEGER PRIMARY KEY AUTOINCREMENT,
+        user_id INTEGER NOT NULL,
+        name TEXT NOT NULL,
+        token_hash TEXT UNIQUE NOT NULL,
+        token_prefix TEXT NOT NULL, -- Start of the token, to recognise it in the list
+        scopes TEXT NOT NULL, -- Space separated, e.g. "drinks:write bac:read"
+        created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+        expires_at DATETIME DEFAULT NULL,
+        last_used_at DATETIME DEFAULT NULL,
+        revoked_at DATETIME DEFAULT NULL,
+        FOREIGN KEY (user_id) REFERENCES users (id)
+    );
+
+CREATE INDEX idx_personal_access_tokens_user_id ON personal_access_tokens (user_id);
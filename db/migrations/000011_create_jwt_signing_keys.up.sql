@@ -0,0 +1,9 @@
+-- Key pairs signing the access tokens. The newest active key signs, every active key verifies.
+CREATE TABLE
+    IF NOT EXISTS jwt_signing_keys (
+        id TEXT PRIMARY KEY, -- The kid header of the tokens
+        algorithm TEXT NOT NULL, -- RS256 or EdDSA
+        private_key TEXT NOT NULL, -- PKCS #8 PEM
+        created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+        retired_at DATETIME DEFAULT NULL -- Tokens signed with a retired key are rejected
+    );
@@ -0,0 +1,4 @@
+-- Operators are admins, and admins can disable accounts
+ALTER TABLE users ADD COLUMN role TEXT NOT NULL DEFAULT 'user' CHECK (role IN ('user', 'admin'));
+
+ALTER TABLE users ADD COLUMN disabled_at DATETIME DEFAULT NULL;
@@ -0,0 +1,2 @@
+-- Accounts are deleted for good once the grace period after this date is over, logging in cancels it
+ALTER TABLE users ADD COLUMN deletion_requested_at DATETIME DEFAULT NULL;
@@ -0,0 +1,16 @@
+-- Every weight of a user since when it applies, the BAC of a drink uses the weight of its time
+CREATE TABLE
+    IF NOT EXISTS user_weight_history (
+        id INTEGER PRIMARY KEY AUTOINCREMENT,
+        user_id INTEGER NOT NULL,
+        weight_kg REAL NOT NULL CHECK (weight_kg > 0),
+        recorded_at DATETIME NOT NULL,
+        FOREIGN KEY (user_id) REFERENCES users (id)
+    );
+
+CREATE INDEX idx_user_weight_history_user_id_recorded_at ON user_weight_history (user_id, recorded_at);
+
+-- The current weights are all there is, they apply since the profiles were created
+INSERT INTO user_weight_history (user_id, weight_kg, recorded_at)
+SELECT user_id, weight_kg, COALESCE(created_at, CURRENT_TIMESTAMP)
+FROM user_profiles;
@@ -0,0 +1,2 @@
+-- IANA time zone of the user, the analytics group the drinks by local day, week, month and year
+ALTER TABLE user_profiles ADD COLUMN timezone TEXT NOT NULL DEFAULT 'UTC';
@@ -0,0 +1,2 @@
+-- Local hour at which a drinking day ends, the drinks of a night out before it count for the previous day
+ALTER TABLE user_profiles ADD COLUMN day_rollover_hour INTEGER NOT NULL DEFAULT 6;
@@ -0,0 +1,10 @@
+-- Limits a user set for themselves, in standard drinks, compared with the drinks like the guidelines
+CREATE TABLE
+    IF NOT EXISTS user_drink_limits (
+        user_id INTEGER PRIMARY KEY,
+        daily_limit REAL CHECK (daily_limit > 0),
+        weekly_limit REAL CHECK (weekly_limit > 0),
+        dry_days_per_week INTEGER NOT NULL DEFAULT 0 CHECK (dry_days_per_week BETWEEN 0 AND 7),
+        updated_at DATETIME NOT NULL,
+        FOREIGN KEY (user_id) REFERENCES users (id)
+    );
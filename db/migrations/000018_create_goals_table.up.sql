@@ -0,0 +1,17 @@
+-- Goals of the users, their dates are days of the calendar of the user (2025-01-31), both included
+CREATE TABLE
+    IF NOT EXISTS goals (
+        id INTEGER PRIMARY KEY AUTOINCREMENT,
+        user_id INTEGER NOT NULL,
+        name TEXT NOT NULL,
+        type TEXT NOT NULL,
+        period TEXT NOT NULL DEFAULT '',
+        target REAL NOT NULL DEFAULT 0 CHECK (target >= 0),
+        start_date TEXT NOT NULL,
+        end_date TEXT,
+        created_at DATETIME NOT NULL,
+        updated_at DATETIME NOT NULL,
+        FOREIGN KEY (user_id) REFERENCES users (id)
+    );
+
+CREATE INDEX idx_goals_user_id ON goals (user_id);
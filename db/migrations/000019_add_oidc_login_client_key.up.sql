@@ -0,0 +1,2 @@
+-- Hash of the secret kept by the client that started the login, the callback must send it back
+ALTER TABLE oidc_login_states ADD COLUMN client_key_hash TEXT NOT NULL DEFAULT '';
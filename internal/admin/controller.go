@@ -0,0 +1,191 @@
+package admin
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"go-sober/internal/constants"
+	"go-sober/internal/dtos"
+	"go-sober/internal/models"
+	"go-sober/internal/params"
+	"go-sober/internal/validation"
+)
+
+type Controller struct {
+	service *Service
+}
+
+func NewController(service *Service) *Controller {
+	return &Controller{service: service}
+}
+
+// @Summary List the users
+// @Description List the user accounts, oldest first. Admins only.
+// @Tags admin
+// @Produce json
+// @Param Authorization header string true "Bearer token"
+// @Param page query int false "Page number (default: 1)" minimum(1)
+// @Param page_size query int false "Page size (default: 20, max: 50)" minimum(1) maximum(50)
+// @Success 200 {object} dtos.AdminUsersResponse
+// @Failure 401 {object} dtos.ClientError
+// @Failure 403 {object} dtos.ClientError
+// @Failure 500 {object} dtos.ClientError
+// @Router /admin/users [get]
+func (c *Controller) ListUsers(w http.ResponseWriter, r *http.Request) {
+	page, pageSize := params.ParsePaginationParams(r)
+
+	users, total, err := c.service.ListUsers(page, pageSize)
+	if err != nil {
+		slog.Error("Could not list users", "error", err)
+		http.Error(w, "Could not list users", http.StatusInternalServerError)
+		return
+	}
+
+	response := dtos.AdminUsersResponse{
+		Users:    make([]dtos.AdminUserResponse, 0, len(users)),
+		Total:    total,
+		Page:     page,
+		PageSize: pageSize,
+	}
+	for _, user := range users {
+		response.Users = append(response.Users, dtos.AdminUserResponse{
+			ID:              user.ID,
+			Email:           user.Email,
+			Role:            user.Role,
+			EmailVerifiedAt: user.EmailVerifiedAt,
+			DisabledAt:      user.DisabledAt,
+			CreatedAt:       user.CreatedAt,
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
+
+// @Summary Disable a user
+// @Description Disable an account: the user is logged out everywhere and cannot log in. Admins only.
+// @Tags admin
+// @Param Authorization header string true "Bearer token"
+// @Param id path int true "User ID"
+// @Success 204
+// @Failure 400 {object} dtos.ClientError
+// @Failure 401 {object} dtos.ClientError
+// @Failure 403 {object} dtos.ClientError
+// @Failure 404 {object} dtos.ClientError
+// @Failure 409 {object} dtos.ClientError
+// @Failure 500 {object} dtos.ClientError
+// @Router /admin/users/{id}/disable [post]
+func (c *Controller) DisableUser(w http.ResponseWriter, r *http.Request) {
+	c.setUserDisabled(w, r, true)
+}
+
+// @Summary Enable a user
+// @Description Enable a disabled account again. Admins only.
+// @Tags admin
+// @Param Authorization header string true "Bearer token"
+// @Param id path int true "User ID"
+// @Success 204
+// @Failure 400 {object} dtos.ClientError
+// @Failure 401 {object} dtos.ClientError
+// @Failure 403 {object} dtos.ClientError
+// @Failure 404 {object} dtos.ClientError
+// @Failure 500 {object} dtos.ClientError
+// @Router /admin/users/{id}/enable [post]
+func (c *Controller) EnableUser(w http.ResponseWriter, r *http.Request) {
+	c.setUserDisabled(w, r, false)
+}
+
+func (c *Controller) setUserDisabled(w http.ResponseWriter, r *http.Request, disabled bool) {
+	claims := r.Context().Value(constants.UserContextKey).(*models.Claims)
+
+	userID, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := c.service.SetUserDisabled(claims.UserID, userID, disabled); err != nil {
+		writeUserError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// @Summary Change the role of a user
+// @Description Make a user an admin or a regular user. The user is logged out everywhere. Admins only.
+// @Tags admin
+// @Accept json
+// @Param Authorization header string true "Bearer token"
+// @Param id path int true "User ID"
+// @Param request body dtos.SetUserRoleRequest true "Set user role request"
+// @Success 204
+// @Failure 400 {object} dtos.ClientError
+// @Failure 401 {object} dtos.ClientError
+// @Failure 403 {object} dtos.ClientError
+// @Failure 404 {object} dtos.ClientError
+// @Failure 409 {object} dtos.ClientError
+// @Failure 500 {object} dtos.ClientError
+// @Router /admin/users/{id}/role [put]
+func (c *Controller) SetUserRole(w http.ResponseWriter, r *http.Request) {
+	claims := r.Context().Value(constants.UserContextKey).(*models.Claims)
+
+	userID, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	var req dtos.SetUserRoleRequest
+	if err := validation.DecodeJSON(r, &req); err != nil {
+		validation.WriteError(w, err)
+		return
+	}
+
+	if err := c.service.SetUserRole(claims.UserID, userID, req.Role); err != nil {
+		writeUserError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func writeUserError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		http.Error(w, "User not found", http.StatusNotFound)
+	case errors.Is(err, ErrOwnAccount):
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		slog.Error("Could not update user", "error", err)
+		http.Error(w, "Could not update user", http.StatusInternalServerError)
+	}
+}
+
+// @Summary Get system stats
+// @Description Count the users, and the rows of every table. Admins only.
+// @Tags admin
+// @Produce json
+// @Param Authorization header string true "Bearer token"
+// @Success 200 {object} dtos.AdminStatsResponse
+// @Failure 401 {object} dtos.ClientError
+// @Failure 403 {object} dtos.ClientError
+// @Failure 500 {object} dtos.ClientError
+// @Router /admin/stats [get]
+func (c *Controller) GetStats(w http.ResponseWriter, r *http.Request) {
+	stats, err := c.service.GetStats()
+	if err != nil {
+		slog.Error("Could not get system stats", "error", err)
+		http.Error(w, "Could not get system stats", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(stats)
+}
@@ -0,0 +1,76 @@
+package admin
+
+import (
+	"database/sql"
+	"fmt"
+
+	"go-sober/internal/database"
+	"go-sober/internal/dtos"
+	"go-sober/internal/models"
+)
+
+type Repository struct {
+	db *sql.DB
+}
+
+func NewRepository(db *sql.DB) *Repository {
+	return &Repository{db: db}
+}
+
+// ListUsers returns a page of users, oldest first, and the total number of users
+func (r *Repository) ListUsers(page, pageSize int) ([]models.User, int, error) {
+	var total int
+	if err := r.db.QueryRow("SELECT COUNT(*) FROM users").Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("error counting users: %w", err)
+	}
+
+	rows, err := r.db.Query(`
+        SELECT id, email, role, email_verified_at, disabled_at, created_at
+        FROM users
+        ORDER BY id
+        LIMIT ? OFFSET ?
+    `, pageSize, (page-1)*pageSize)
+	if err != nil {
+		return nil, 0, fmt.Errorf("error listing users: %w", err)
+	}
+	defer rows.Close()
+
+	users := []models.User{}
+	for rows.Next() {
+		var user models.User
+		var emailVerifiedAt, disabledAt sql.NullTime
+		if err := rows.Scan(&user.ID, &user.Email, &user.Role, &emailVerifiedAt, &disabledAt, &user.CreatedAt); err != nil {
+			return nil, 0, fmt.Errorf("error scanning user: %w", err)
+		}
+		if emailVerifiedAt.Valid {
+			user.EmailVerifiedAt = &emailVerifiedAt.Time
+		}
+		if disabledAt.Valid {
+			user.DisabledAt = &disabledAt.Time
+		}
+		users = append(users, user)
+	}
+	return users, total, rows.Err()
+}
+
+// GetUserStats counts the users by role and status
+func (r *Repository) GetUserStats() (*dtos.UserStats, error) {
+	stats := &dtos.UserStats{}
+	err := r.db.QueryRow(`
+        SELECT
+            COUNT(*),
+            COALESCE(SUM(role = ?), 0),
+            COALESCE(SUM(disabled_at IS NOT NULL), 0),
+            COALESCE(SUM(email_verified_at IS NOT NULL), 0)
+        FROM users
+    `, models.RoleAdmin).Scan(&stats.Total, &stats.Admins, &stats.Disabled, &stats.Verified)
+	if err != nil {
+		return nil, fmt.Errorf("error counting users: %w", err)
+	}
+	return stats, nil
+}
+
+// GetTableStats returns the row count of every table
+func (r *Repository) GetTableStats() ([]database.TableInfo, error) {
+	return database.ListTables(r.db)
+}
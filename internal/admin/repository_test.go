@@ -0,0 +1,78 @@
+package admin
+
+import (
+	"database/sql"
+	"testing"
+
+	"go-sober/internal/models"
+
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupTestDB(t *testing.T) *Repository {
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("Failed to create database: %v", err)
+	}
+	db.SetMaxOpenConns(1)
+
+	_, err = db.Exec(`
+		CREATE TABLE users (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			email TEXT UNIQUE NOT NULL,
+			password TEXT NOT NULL,
+			email_verified_at DATETIME DEFAULT NULL,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			role TEXT NOT NULL DEFAULT 'user',
+			disabled_at DATETIME DEFAULT NULL
+		);
+
+		INSERT INTO users (email, password, role, email_verified_at, disabled_at) VALUES
+			('admin@example.com', 'hash', 'admin', CURRENT_TIMESTAMP, NULL),
+			('verified@example.com', 'hash', 'user', CURRENT_TIMESTAMP, NULL),
+			('disabled@example.com', 'hash', 'user', NULL, CURRENT_TIMESTAMP);
+	`)
+	if err != nil {
+		t.Fatalf("Failed to create users table: %v", err)
+	}
+
+	return NewRepository(db)
+}
+
+func TestListUsers(t *testing.T) {
+	repo := setupTestDB(t)
+
+	users, total, err := repo.ListUsers(1, 2)
+	require.NoError(t, err)
+	assert.Equal(t, 3, total)
+	require.Len(t, users, 2)
+	assert.Equal(t, "admin@example.com", users[0].Email)
+	assert.Equal(t, models.RoleAdmin, users[0].Role)
+	assert.NotNil(t, users[0].EmailVerifiedAt)
+
+	users, _, err = repo.ListUsers(2, 2)
+	require.NoError(t, err)
+	require.Len(t, users, 1)
+	assert.Equal(t, "disabled@example.com", users[0].Email)
+	assert.NotNil(t, users[0].DisabledAt)
+	assert.Nil(t, users[0].EmailVerifiedAt)
+}
+
+func TestGetStats(t *testing.T) {
+	repo := setupTestDB(t)
+
+	users, err := repo.GetUserStats()
+	require.NoError(t, err)
+	assert.Equal(t, 3, users.Total)
+	assert.Equal(t, 1, users.Admins)
+	assert.Equal(t, 1, users.Disabled)
+	assert.Equal(t, 2, users.Verified)
+
+	tables, err := repo.GetTableStats()
+	require.NoError(t, err)
+	require.Len(t, tables, 1)
+	assert.Equal(t, "users", tables[0].Name)
+	assert.Equal(t, 3, tables[0].RowCount)
+}
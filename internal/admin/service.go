@@ -0,0 +1,63 @@
+package admin
+
+import (
+	"errors"
+
+	"go-sober/internal/auth"
+	"go-sober/internal/dtos"
+	"go-sober/internal/models"
+)
+
+// ErrOwnAccount protects admins from locking themselves out
+var ErrOwnAccount = errors.New("admins cannot disable or demote their own account")
+
+type Service struct {
+	repo *Repository
+	auth *auth.Service
+}
+
+func NewService(repo *Repository, authService *auth.Service) *Service {
+	return &Service{repo: repo, auth: authService}
+}
+
+func (s *Service) ListUsers(page, pageSize int) ([]models.User, int, error) {
+	return s.repo.ListUsers(page, pageSize)
+}
+
+// SetUserDisabled disables or enables an account, sql.ErrNoRows if the user does not exist
+func (s *Service) SetUserDisabled(adminID, userID int64, disabled bool) error {
+	if adminID == userID && disabled {
+		return ErrOwnAccount
+	}
+	return s.auth.SetUserDisabled(adminID, userID, disabled)
+}
+
+// SetUserRole changes the role of a user, sql.ErrNoRows if the user does not exist
+func (s *Service) SetUserRole(adminID, userID int64, role models.Role) error {
+	if adminID == userID && role != models.RoleAdmin {
+		return ErrOwnAccount
+	}
+	return s.auth.SetUserRole(adminID, userID, role)
+}
+
+// GetStats returns the user counts and the row count of every table
+func (s *Service) GetStats() (*dtos.AdminStatsResponse, error) {
+	users, err := s.repo.GetUserStats()
+	if err != nil {
+		return nil, err
+	}
+
+	tables, err := s.repo.GetTableStats()
+	if err != nil {
+		return nil, err
+	}
+
+	stats := &dtos.AdminStatsResponse{
+		Users:  *users,
+		Tables: make([]dtos.TableStats, 0, len(tables)),
+	}
+	for _, table := range tables {
+		stats.Tables = append(stats.Tables, dtos.TableStats{Name: table.Name, RowCount: table.RowCount})
+	}
+	return stats, nil
+}
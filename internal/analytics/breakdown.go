@@ -0,0 +1,114 @@
+package analytics
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"go-sober/internal/dtos"
+	"go-sober/internal/models"
+)
+
+// drinkDetails is what a drink is grouped by in a breakdown
+type drinkDetails struct {
+	name           string
+	drinkType      string
+	abv            float64
+	sizeValue      float64
+	sizeUnit       string
+	standardDrinks float64
+}
+
+// abvBands are the upper bounds, excluded, of the ABV bands. Stronger drinks are in the 25%+ band.
+var abvBands = []struct {
+	max   float64
+	label string
+}{
+	{0.005, "0-0.5%"},
+	{0.05, "0.5-5%"},
+	{0.08, "5-8%"},
+	{0.15, "8-15%"},
+	{0.25, "15-25%"},
+}
+
+func abvBand(abv float64) string {
+	for _, band := range abvBands {
+		if abv < band.max {
+			return band.label
+		}
+	}
+	return "25%+"
+}
+
+// breakdownGroup returns the group of a drink, unknown when the drink has no value for it
+func breakdownGroup(groupBy models.BreakdownGroupBy, drink drinkDetails) string {
+	var group string
+	switch groupBy {
+	case models.BreakdownByType:
+		group = strings.ToLower(strings.TrimSpace(drink.drinkType))
+	case models.BreakdownByName:
+		group = strings.TrimSpace(drink.name)
+	case models.BreakdownByABVBand:
+		group = abvBand(drink.abv)
+	case models.BreakdownBySize:
+		if ml, ok := models.VolumeInMl(drink.sizeValue, drink.sizeUnit); ok {
+			group = fmt.Sprintf("%g ml", math.Round(ml))
+		} else {
+			group = strings.TrimSpace(fmt.Sprintf("%g %s", drink.sizeValue, drink.sizeUnit))
+		}
+	}
+	if group == "" {
+		return "unknown"
+	}
+	return group
+}
+
+// breakdown groups drinks and computes the share of each group
+func breakdown(groupBy models.BreakdownGroupBy, drinks []drinkDetails) *dtos.BreakdownResponse {
+	response := &dtos.BreakdownResponse{GroupBy: groupBy, Groups: []dtos.BreakdownGroup{}}
+
+	indexes := make(map[string]int)
+	for _, drink := range drinks {
+		group := breakdownGroup(groupBy, drink)
+		index, ok := indexes[group]
+		if !ok {
+			index = len(response.Groups)
+			indexes[group] = index
+			response.Groups = append(response.Groups, dtos.BreakdownGroup{Group: group})
+		}
+
+		response.Groups[index].DrinkCount++
+		response.Groups[index].StandardDrinks += drink.standardDrinks
+		response.TotalDrinks++
+		response.TotalStandardDrinks += drink.standardDrinks
+	}
+
+	for i := range response.Groups {
+		group := &response.Groups[i]
+		group.DrinkShare = share(float64(group.DrinkCount), float64(response.TotalDrinks))
+		group.StandardDrinksShare = share(group.StandardDrinks, response.TotalStandardDrinks)
+		group.StandardDrinks = roundStandardDrinks(group.StandardDrinks)
+	}
+	response.TotalStandardDrinks = roundStandardDrinks(response.TotalStandardDrinks)
+
+	sort.SliceStable(response.Groups, func(i, j int) bool {
+		a, b := response.Groups[i], response.Groups[j]
+		if a.StandardDrinks != b.StandardDrinks {
+			return a.StandardDrinks > b.StandardDrinks
+		}
+		if a.DrinkCount != b.DrinkCount {
+			return a.DrinkCount > b.DrinkCount
+		}
+		return a.Group < b.Group
+	})
+	return response
+}
+
+// share returns a part of a total as a percentage, 0 for an empty total
+func share(part, total float64) float64 {
+	if total == 0 {
+		return 0
+	}
+	return math.Round(part*10000/total) / 100
+}
@@ -0,0 +1,70 @@
+package analytics
+
+import (
+	"testing"
+	"time"
+
+	"go-sober/internal/dtos"
+	"go-sober/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetBreakdown(t *testing.T) {
+	repo := setupTestDB(t)
+	march := time.Date(2025, 3, 10, 20, 0, 0, 0, time.UTC)
+	insertDrinkDetails(t, repo, 1, march, drinkDetails{name: "Heineken", drinkType: "beer", sizeValue: 33, sizeUnit: "cl", abv: 0.05, standardDrinks: 1.3})
+	insertDrinkDetails(t, repo, 1, march, drinkDetails{name: "Heineken", drinkType: "Beer", sizeValue: 500, sizeUnit: "ml", abv: 0.05, standardDrinks: 1.97})
+	insertDrinkDetails(t, repo, 1, march, drinkDetails{name: "Bordeaux", drinkType: "wine", sizeValue: 12.5, sizeUnit: "cl", abv: 0.13, standardDrinks: 1.28})
+	insertDrinkDetails(t, repo, 1, march, drinkDetails{name: "Whisky", drinkType: "spirit", sizeValue: 4, sizeUnit: "cl", abv: 0.4, standardDrinks: 1.26})
+	insertDrinkDetails(t, repo, 1, march.AddDate(0, -2, 0), drinkDetails{name: "Old wine", drinkType: "wine", sizeValue: 12.5, sizeUnit: "cl", abv: 0.12, standardDrinks: 1.18})
+	insertDrinkDetails(t, repo, 2, march, drinkDetails{name: "Someone else's", drinkType: "beer", sizeValue: 33, sizeUnit: "cl", abv: 0.05, standardDrinks: 1.3})
+
+	start := march.AddDate(0, 0, -7)
+	end := march.AddDate(0, 0, 1)
+
+	byType, err := repo.GetBreakdown(1, models.BreakdownByType, start, end)
+	require.NoError(t, err)
+	assert.Equal(t, models.BreakdownByType, byType.GroupBy)
+	assert.Equal(t, 4, byType.TotalDrinks)
+	assert.Equal(t, 5.81, byType.TotalStandardDrinks)
+	assert.Equal(t, []dtos.BreakdownGroup{
+		{Group: "beer", DrinkCount: 2, StandardDrinks: 3.27, DrinkShare: 50, StandardDrinksShare: 56.28},
+		{Group: "wine", DrinkCount: 1, StandardDrinks: 1.28, DrinkShare: 25, StandardDrinksShare: 22.03},
+		{Group: "spirit", DrinkCount: 1, StandardDrinks: 1.26, DrinkShare: 25, StandardDrinksShare: 21.69},
+	}, byType.Groups)
+
+	byName, err := repo.GetBreakdown(1, models.BreakdownByName, start, end)
+	require.NoError(t, err)
+	assert.Equal(t, "Heineken", byName.Groups[0].Group)
+	assert.Equal(t, 2, byName.Groups[0].DrinkCount)
+
+	bySize, err := repo.GetBreakdown(1, models.BreakdownBySize, start, end)
+	require.NoError(t, err)
+	var sizes []string
+	for _, group := range bySize.Groups {
+		sizes = append(sizes, group.Group)
+	}
+	assert.Equal(t, []string{"500 ml", "330 ml", "125 ml", "40 ml"}, sizes)
+
+	byABV, err := repo.GetBreakdown(1, models.BreakdownByABVBand, start, end)
+	require.NoError(t, err)
+	assert.Equal(t, "5-8%", byABV.Groups[0].Group)
+	assert.Equal(t, 2, byABV.Groups[0].DrinkCount)
+
+	// No drinks, no groups
+	empty, err := repo.GetBreakdown(3, models.BreakdownByType, start, end)
+	require.NoError(t, err)
+	assert.Empty(t, empty.Groups)
+	assert.Zero(t, empty.TotalDrinks)
+}
+
+func TestABVBand(t *testing.T) {
+	assert.Equal(t, "0-0.5%", abvBand(0))
+	assert.Equal(t, "0.5-5%", abvBand(0.045))
+	assert.Equal(t, "5-8%", abvBand(0.05))
+	assert.Equal(t, "8-15%", abvBand(0.13))
+	assert.Equal(t, "15-25%", abvBand(0.18))
+	assert.Equal(t, "25%+", abvBand(0.4))
+}
@@ -0,0 +1,85 @@
+package analytics
+
+import (
+	"fmt"
+	"time"
+
+	"go-sober/internal/models"
+)
+
+const dateFormat = "2006-01-02"
+
+// Calendar assigns the drinks to the days, weeks, months and years of a user, in their time zone.
+// A day runs from the rollover hour to the rollover hour of the next day, so that the drinks of a
+// night out count for a single day.
+type Calendar struct {
+	location     *time.Location
+	rolloverHour int
+}
+
+func NewCalendar(location *time.Location, rolloverHour int) Calendar {
+	if location == nil {
+		location = time.UTC
+	}
+	return Calendar{location: location, rolloverHour: rolloverHour}
+}
+
+// ProfileCalendar returns the calendar of the settings of a profile
+func ProfileCalendar(profile *models.UserProfile) Calendar {
+	if profile == nil {
+		return NewCalendar(time.UTC, models.DefaultDayRolloverHour)
+	}
+	return NewCalendar(profile.Location(), profile.DayRolloverHour)
+}
+
+// Day returns the day of a time, at midnight in the time zone of the user
+func (c Calendar) Day(t time.Time) time.Time {
+	local := t.In(c.location)
+	day := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, c.location)
+	if local.Before(c.Start(day)) {
+		return day.AddDate(0, 0, -1)
+	}
+	return day
+}
+
+// ParseDay returns the day of a date such as 2025-07-25, in the time zone of the user
+func (c Calendar) ParseDay(date string) (time.Time, error) {
+	return time.ParseInLocation(dateFormat, date, c.location)
+}
+
+// AddDays returns the day a number of days after another, at midnight even across DST changes
+func (c Calendar) AddDays(day time.Time, days int) time.Time {
+	return time.Date(day.Year(), day.Month(), day.Day()+days, 0, 0, 0, 0, c.location)
+}
+
+// MonthStart returns the first day of the month of a day
+func (c Calendar) MonthStart(day time.Time) time.Time {
+	return time.Date(day.Year(), day.Month(), 1, 0, 0, 0, 0, c.location)
+}
+
+// WeekStart returns the Monday of the week of a day
+func (c Calendar) WeekStart(day time.Time) time.Time {
+	return c.AddDays(day, -((int(day.Weekday()) + 6) % 7))
+}
+
+// Start returns the time at which a day begins, its rollover hour
+func (c Calendar) Start(day time.Time) time.Time {
+	return time.Date(day.Year(), day.Month(), day.Day(), c.rolloverHour, 0, 0, 0, c.location)
+}
+
+// periodKey returns the period of a time, in the formats of models.TimePeriodDateFormatter
+func (c Calendar) periodKey(period models.TimePeriod, t time.Time) string {
+	day := c.Day(t)
+	switch period {
+	case models.TimePeriodWeekly:
+		// Week of the year starting on Monday, the days before the first Monday are in week 00
+		mondayBased := (int(day.Weekday()) + 6) % 7
+		return fmt.Sprintf("%d-%02d", day.Year(), (day.YearDay()-1+7-mondayBased)/7)
+	case models.TimePeriodMonthly:
+		return day.Format("2006-01")
+	case models.TimePeriodYearly:
+		return day.Format("2006")
+	default:
+		return day.Format("2006-01-02")
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"go-sober/internal/dtos"
 	"go-sober/internal/models"
 	"go-sober/internal/params"
+	"go-sober/internal/validation"
 	"net/http"
 )
 
@@ -18,11 +19,11 @@ func NewController(service *Service) *Controller {
 }
 
 // @Summary Get drink statistics
-// @Description Get drink statistics for a user
+// @Description Get drink statistics for a user. The periods are in the time zone of the profile, and a day ends at its rollover hour (6am by default).
 // @Tags analytics
 // @Accept json
 // @Produce json
-// @Param Authorization header string true "Bearer token"
+// @Param Authorization header string true "Bearer token, or personal access token with the analytics:read scope"
 // @Param period query string true "Time period" Enums(daily, weekly, monthly, yearly)
 // @Param start_date query string false "Start date"
 // @Param end_date query string false "End date"
@@ -58,6 +59,10 @@ func (c *Controller) GetDrinkStats(w http.ResponseWriter, r *http.Request) {
 		StartDate: startDate,
 		EndDate:   endDate,
 	}
+	if err := validation.Struct(filters); err != nil {
+		validation.WriteError(w, err)
+		return
+	}
 
 	stats, err := c.service.GetDrinkStats(claims.UserID, filters)
 	if err != nil {
@@ -75,10 +80,11 @@ func (c *Controller) GetDrinkStats(w http.ResponseWriter, r *http.Request) {
 }
 
 // @Summary Get monthly BAC statistics
+// @Description Count the days of each month by BAC category. The days are in the time zone of the profile and end at its rollover hour.
 // @Tags analytics
 // @Accept json
 // @Produce json
-// @Param Authorization header string true "Bearer token"
+// @Param Authorization header string true "Bearer token, or personal access token with the analytics:read scope"
 // @Param start_date query string false "Start date"
 // @Param end_date query string false "End date"
 // @Success 200 {object} dtos.MonthlyBACStatsResponse
@@ -116,3 +122,170 @@ func (c *Controller) GetMonthlyBACStats(w http.ResponseWriter, r *http.Request)
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(response)
 }
+
+// @Summary Get the progress against drinking guidelines
+// @Description Compare the drinks with the personal limits of the profile and with public health guidelines, in standard drinks of 10 grams of alcohol. Returns the progress of today and of the current week, and the share of the complete weeks within the limits.
+// @Tags analytics
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Bearer token, or personal access token with the analytics:read scope"
+// @Param start_date query string false "Start of the evaluated weeks, the first drink by default"
+// @Param end_date query string false "End of the evaluated weeks, last week by default"
+// @Success 200 {object} dtos.GuidelinesResponse
+// @Failure 400 {object} dtos.ClientError
+// @Failure 500 {object} dtos.ClientError
+// @Router /analytics/guidelines [get]
+func (c *Controller) GetGuidelines(w http.ResponseWriter, r *http.Request) {
+	claims := r.Context().Value(constants.UserContextKey).(*models.Claims)
+	query := r.URL.Query()
+
+	startDate := params.ParseTimeParam(query.Get("start_date"))
+	endDate := params.ParseTimeParam(query.Get("end_date"))
+
+	if startDate != nil && endDate != nil && endDate.Before(*startDate) {
+		http.Error(w, "End date must be after start date", http.StatusBadRequest)
+		return
+	}
+
+	filters := dtos.GuidelinesFilters{
+		StartDate: startDate,
+		EndDate:   endDate,
+	}
+
+	guidelines, err := c.service.GetGuidelines(claims.UserID, filters)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(dtos.GuidelinesResponse{Guidelines: guidelines})
+}
+
+// @Summary Get the alcohol-free streaks
+// @Description Get the current and longest alcohol-free streaks, the 7, 30 and 100 days milestones, and the alcohol-free days of each week and month. The days are in the time zone of the profile and end at its rollover hour; they are tracked from the first drink logged or the creation of the profile.
+// @Tags analytics
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Bearer token, or personal access token with the analytics:read scope"
+// @Param start_date query string false "Start of the alcohol-free days per week and month, the start of the tracking by default"
+// @Param end_date query string false "End of the alcohol-free days per week and month, today by default"
+// @Success 200 {object} dtos.StreaksResponse
+// @Failure 400 {object} dtos.ClientError
+// @Failure 500 {object} dtos.ClientError
+// @Router /analytics/streaks [get]
+func (c *Controller) GetStreaks(w http.ResponseWriter, r *http.Request) {
+	claims := r.Context().Value(constants.UserContextKey).(*models.Claims)
+	query := r.URL.Query()
+
+	startDate := params.ParseTimeParam(query.Get("start_date"))
+	endDate := params.ParseTimeParam(query.Get("end_date"))
+
+	if startDate != nil && endDate != nil && endDate.Before(*startDate) {
+		http.Error(w, "End date must be after start date", http.StatusBadRequest)
+		return
+	}
+
+	filters := dtos.StreaksFilters{
+		StartDate: startDate,
+		EndDate:   endDate,
+	}
+
+	streaks, err := c.service.GetStreaks(claims.UserID, filters)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(streaks)
+}
+
+// @Summary Get the drinks heatmap
+// @Description Count the drinks and standard drinks by day of the week (rows from Monday) and hour of the day (columns from midnight), in the time zone of the profile. A drink before the rollover hour is in the row of the previous day, so a night out stays on one day.
+// @Tags analytics
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Bearer token, or personal access token with the analytics:read scope"
+// @Param start_date query string false "Start date, the first drink by default"
+// @Param end_date query string false "End date, now by default"
+// @Success 200 {object} dtos.HeatmapResponse
+// @Failure 400 {object} dtos.ClientError
+// @Failure 500 {object} dtos.ClientError
+// @Router /analytics/heatmap [get]
+func (c *Controller) GetHeatmap(w http.ResponseWriter, r *http.Request) {
+	claims := r.Context().Value(constants.UserContextKey).(*models.Claims)
+	query := r.URL.Query()
+
+	startDate := params.ParseTimeParam(query.Get("start_date"))
+	endDate := params.ParseTimeParam(query.Get("end_date"))
+
+	if startDate != nil && endDate != nil && endDate.Before(*startDate) {
+		http.Error(w, "End date must be after start date", http.StatusBadRequest)
+		return
+	}
+
+	filters := dtos.DrinkStatsFilters{
+		StartDate: startDate,
+		EndDate:   endDate,
+	}
+
+	heatmap, err := c.service.GetHeatmap(claims.UserID, filters)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(heatmap)
+}
+
+// @Summary Get the breakdown of the drinks
+// @Description Group the drinks by type, name, ABV band or size, with the drinks, the standard drinks and their share of the total in each group. The groups with the most standard drinks come first.
+// @Tags analytics
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Bearer token, or personal access token with the analytics:read scope"
+// @Param group_by query string true "What to group the drinks by" Enums(type, name, abv_band, size)
+// @Param start_date query string false "Start date, the first drink by default"
+// @Param end_date query string false "End date, now by default"
+// @Success 200 {object} dtos.BreakdownResponse
+// @Failure 400 {object} dtos.ClientError
+// @Failure 500 {object} dtos.ClientError
+// @Router /analytics/breakdown [get]
+func (c *Controller) GetBreakdown(w http.ResponseWriter, r *http.Request) {
+	claims := r.Context().Value(constants.UserContextKey).(*models.Claims)
+	query := r.URL.Query()
+
+	groupBy := models.ToBreakdownGroupBy(query.Get("group_by"))
+	if groupBy == models.BreakdownUnknown {
+		http.Error(w, "Invalid group_by parameter", http.StatusBadRequest)
+		return
+	}
+
+	startDate := params.ParseTimeParam(query.Get("start_date"))
+	endDate := params.ParseTimeParam(query.Get("end_date"))
+
+	if startDate != nil && endDate != nil && endDate.Before(*startDate) {
+		http.Error(w, "End date must be after start date", http.StatusBadRequest)
+		return
+	}
+
+	filters := dtos.DrinkStatsFilters{
+		StartDate: startDate,
+		EndDate:   endDate,
+	}
+
+	breakdown, err := c.service.GetBreakdown(claims.UserID, groupBy, filters)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(breakdown)
+}
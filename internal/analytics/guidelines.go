@@ -0,0 +1,148 @@
+package analytics
+
+import (
+	"math"
+	"time"
+
+	"go-sober/internal/dtos"
+	"go-sober/internal/models"
+)
+
+// guidelines are the public health guidelines the drinks are compared with. Their limits are
+// converted to standard drinks of 10 grams of alcohol.
+var guidelines = []models.Guideline{
+	{
+		Key:         "uk",
+		Name:        "UK Chief Medical Officers",
+		Description: "No more than 14 units (8 g of alcohol) a week, spread over 3 days or more",
+		WeeklyLimit: limit(14 * 0.8),
+	},
+	{
+		Key:            "fr",
+		Name:           "Santé publique France",
+		Description:    "No more than 10 standard drinks a week, 2 a day, and not every day",
+		DailyLimit:     limit(2),
+		WeeklyLimit:    limit(10),
+		DryDaysPerWeek: 1,
+	},
+	{
+		Key:         "au",
+		Name:        "Australian guidelines",
+		Description: "No more than 10 standard drinks a week and 4 on any one day",
+		DailyLimit:  limit(4),
+		WeeklyLimit: limit(10),
+	},
+}
+
+func limit(standardDrinks float64) *float64 {
+	return &standardDrinks
+}
+
+// roundStandardDrinks rounds standard drinks to 2 decimals
+func roundStandardDrinks(standardDrinks float64) float64 {
+	return math.Round(standardDrinks*100) / 100
+}
+
+// evaluatedWeeks returns the Mondays of the complete weeks overlapping the filters, before the
+// current week. Without a start date, they start at the week of the first drink.
+func evaluatedWeeks(calendar Calendar, filters dtos.GuidelinesFilters, standardDrinksByDay map[time.Time]float64, today time.Time) []time.Time {
+	var first time.Time
+	if filters.StartDate != nil {
+		first = calendar.WeekStart(calendar.Day(*filters.StartDate))
+	} else {
+		for day := range standardDrinksByDay {
+			if first.IsZero() || day.Before(first) {
+				first = day
+			}
+		}
+		if first.IsZero() {
+			return nil
+		}
+		first = calendar.WeekStart(first)
+	}
+
+	end := calendar.WeekStart(today)
+	if filters.EndDate != nil {
+		if endWeek := calendar.AddDays(calendar.WeekStart(calendar.Day(*filters.EndDate)), 7); endWeek.Before(end) {
+			end = endWeek
+		}
+	}
+
+	var weeks []time.Time
+	for week := first; week.Before(end); week = calendar.AddDays(week, 7) {
+		weeks = append(weeks, week)
+	}
+	return weeks
+}
+
+// evaluateGuideline compares the drinks of each day with a guideline, for today, the current
+// week and the evaluated weeks
+func evaluateGuideline(guideline models.Guideline, calendar Calendar, standardDrinksByDay map[time.Time]float64, weeks []time.Time, today time.Time) dtos.GuidelineProgress {
+	progress := dtos.GuidelineProgress{
+		Guideline: guideline,
+		Today: dtos.DayProgress{
+			Date:          today.Format("2006-01-02"),
+			LimitProgress: limitProgress(standardDrinksByDay[today], guideline.DailyLimit),
+		},
+	}
+
+	thisWeek := calendar.WeekStart(today)
+	var weekDrinks float64
+	var dryDays int
+	for day := thisWeek; !day.After(today); day = calendar.AddDays(day, 1) {
+		weekDrinks += standardDrinksByDay[day]
+		if standardDrinksByDay[day] == 0 {
+			dryDays++
+		}
+	}
+	progress.ThisWeek = dtos.WeekProgress{
+		StartDate:       thisWeek.Format("2006-01-02"),
+		LimitProgress:   limitProgress(weekDrinks, guideline.WeeklyLimit),
+		DryDays:         dryDays,
+		DryDaysRequired: guideline.DryDaysPerWeek,
+	}
+
+	for _, week := range weeks {
+		var weekDrinks float64
+		var dryDays, daysOver int
+		for i := 0; i < 7; i++ {
+			standardDrinks := standardDrinksByDay[calendar.AddDays(week, i)]
+			weekDrinks += standardDrinks
+			if standardDrinks == 0 {
+				dryDays++
+			}
+			if guideline.DailyLimit != nil && roundStandardDrinks(standardDrinks) > *guideline.DailyLimit {
+				daysOver++
+			}
+		}
+
+		overWeekly := guideline.WeeklyLimit != nil && roundStandardDrinks(weekDrinks) > *guideline.WeeklyLimit
+		if overWeekly {
+			progress.WeeksOverLimit++
+		}
+		progress.DaysOverLimit += daysOver
+		if !overWeekly && daysOver == 0 && dryDays >= guideline.DryDaysPerWeek {
+			progress.WeeksWithin++
+		}
+	}
+
+	progress.WeeksEvaluated = len(weeks)
+	if len(weeks) > 0 {
+		rate := math.Round(float64(progress.WeeksWithin)*10000/float64(len(weeks))) / 100
+		progress.ComplianceRate = &rate
+	}
+	return progress
+}
+
+func limitProgress(standardDrinks float64, limit *float64) dtos.LimitProgress {
+	progress := dtos.LimitProgress{
+		StandardDrinks: roundStandardDrinks(standardDrinks),
+		Limit:          limit,
+	}
+	if limit != nil {
+		remaining := math.Max(0, roundStandardDrinks(*limit-standardDrinks))
+		progress.Remaining = &remaining
+		progress.OverLimit = progress.StandardDrinks > *limit
+	}
+	return progress
+}
@@ -0,0 +1,82 @@
+package analytics
+
+import (
+	"testing"
+	"time"
+
+	"go-sober/internal/dtos"
+	"go-sober/internal/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEvaluateGuideline(t *testing.T) {
+	calendar := NewCalendar(time.UTC, 6)
+	day := func(month time.Month, day int) time.Time {
+		return time.Date(2025, month, day, 0, 0, 0, 0, time.UTC)
+	}
+
+	// Monday the 3rd of March to Wednesday the 19th: two complete weeks and the current one
+	standardDrinksByDay := map[time.Time]float64{
+		// Within every limit, a glass of wine on 5 days
+		day(3, 3): 1, day(3, 4): 1, day(3, 5): 1, day(3, 6): 1, day(3, 7): 1,
+		// A night out over the daily limit, and a drink every day
+		day(3, 10): 1, day(3, 11): 1, day(3, 12): 1, day(3, 13): 1, day(3, 14): 5, day(3, 15): 1, day(3, 16): 1,
+		// Today is the Wednesday
+		day(3, 17): 2, day(3, 19): 1.5,
+	}
+	today := day(3, 19)
+	filters := dtos.GuidelinesFilters{}
+	weeks := evaluatedWeeks(calendar, filters, standardDrinksByDay, today)
+	assert.Equal(t, []time.Time{day(3, 3), day(3, 10)}, weeks)
+
+	fr := evaluateGuideline(guidelines[1], calendar, standardDrinksByDay, weeks, today)
+	assert.Equal(t, "2025-03-19", fr.Today.Date)
+	assert.Equal(t, 1.5, fr.Today.StandardDrinks)
+	assert.Equal(t, 0.5, *fr.Today.Remaining)
+	assert.False(t, fr.Today.OverLimit)
+	assert.Equal(t, "2025-03-17", fr.ThisWeek.StartDate)
+	assert.Equal(t, 3.5, fr.ThisWeek.StandardDrinks)
+	assert.Equal(t, 6.5, *fr.ThisWeek.Remaining)
+	assert.Equal(t, 1, fr.ThisWeek.DryDays)
+	assert.Equal(t, 2, fr.WeeksEvaluated)
+	assert.Equal(t, 1, fr.WeeksWithin)
+	assert.Equal(t, 50.0, *fr.ComplianceRate)
+	assert.Equal(t, 1, fr.WeeksOverLimit)
+	assert.Equal(t, 1, fr.DaysOverLimit)
+
+	// The UK guideline has no daily limit, 11 standard drinks are within 14 units
+	uk := evaluateGuideline(guidelines[0], calendar, standardDrinksByDay, weeks, today)
+	assert.Nil(t, uk.Today.Limit)
+	assert.Nil(t, uk.Today.Remaining)
+	assert.Equal(t, 2, uk.WeeksWithin)
+	assert.Equal(t, 100.0, *uk.ComplianceRate)
+	assert.Equal(t, 0, uk.WeeksOverLimit)
+
+	// Personal limits of 3 dry days a week
+	limits := &models.DrinkLimits{DryDaysPerWeek: 3}
+	custom := evaluateGuideline(limits.Guideline(), calendar, standardDrinksByDay, weeks, today)
+	assert.Equal(t, models.GuidelineCustom, custom.Guideline.Key)
+	assert.Equal(t, 0, custom.WeeksWithin)
+	assert.Equal(t, 0.0, *custom.ComplianceRate)
+}
+
+func TestEvaluatedWeeks(t *testing.T) {
+	calendar := NewCalendar(time.UTC, 0)
+	today := time.Date(2025, 3, 19, 0, 0, 0, 0, time.UTC)
+
+	assert.Empty(t, evaluatedWeeks(calendar, dtos.GuidelinesFilters{}, nil, today))
+
+	start := time.Date(2025, 2, 26, 12, 0, 0, 0, time.UTC)
+	end := time.Date(2025, 3, 4, 12, 0, 0, 0, time.UTC)
+	weeks := evaluatedWeeks(calendar, dtos.GuidelinesFilters{StartDate: &start, EndDate: &end}, nil, today)
+	assert.Equal(t, []time.Time{
+		time.Date(2025, 2, 24, 0, 0, 0, 0, time.UTC),
+		time.Date(2025, 3, 3, 0, 0, 0, 0, time.UTC),
+	}, weeks)
+
+	// The current week is never complete
+	end = today
+	weeks = evaluatedWeeks(calendar, dtos.GuidelinesFilters{StartDate: &start, EndDate: &end}, nil, today)
+	assert.Len(t, weeks, 3)
+}
@@ -5,8 +5,6 @@ import (
 	"fmt"
 	"go-sober/internal/dtos"
 	"go-sober/internal/models"
-	"sort"
-	"strconv"
 	"time"
 )
 
@@ -18,144 +16,180 @@ func NewRepository(db *sql.DB) *Repository {
 	return &Repository{db: db}
 }
 
-func (r *Repository) GetDrinkStats(userID int64, period models.TimePeriod, startDate time.Time, endDate time.Time) ([]models.DrinkStatsPoint, error) {
-	query := `
-        SELECT 
-            strftime(?, dl.logged_at) as time_period,
-            COUNT(*) as drink_count,
-            ROUND(SUM(dld.standard_drinks), 2) as total_standard_drinks
+// loggedDrink is a drink of a user, grouped by the analytics in the local time of the user
+type loggedDrink struct {
+	loggedAt       time.Time
+	standardDrinks float64
+}
+
+// getLoggedDrinks returns the drinks of a user logged between two times, oldest first
+func (r *Repository) getLoggedDrinks(userID int64, startDate, endDate time.Time) ([]loggedDrink, error) {
+	rows, err := r.db.Query(`
+        SELECT dl.logged_at, COALESCE(dld.standard_drinks, 0)
         FROM drink_logs dl
         JOIN drink_log_details dld ON dl.drink_details_id = dld.id
         WHERE dl.user_id = ?
         AND dl.logged_at >= ?
         AND dl.logged_at <= ?
-        GROUP BY strftime(?, dl.logged_at)
-        ORDER BY time_period ASC`
+        ORDER BY dl.logged_at ASC`, userID, startDate.UTC(), endDate.UTC())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
 
-	periodDateFormatter := models.ToTimePeriodDateFormatter(period)
+	var drinks []loggedDrink
+	for rows.Next() {
+		var drink loggedDrink
+		if err := rows.Scan(&drink.loggedAt, &drink.standardDrinks); err != nil {
+			return nil, err
+		}
+		drinks = append(drinks, drink)
+	}
+	return drinks, rows.Err()
+}
 
-	rows, err := r.db.Query(query, periodDateFormatter, userID, startDate, endDate, periodDateFormatter)
+// GetBreakdown groups the drinks of a user logged between two times, with the share of each group
+func (r *Repository) GetBreakdown(userID int64, groupBy models.BreakdownGroupBy, startDate, endDate time.Time) (*dtos.BreakdownResponse, error) {
+	rows, err := r.db.Query(`
+        SELECT COALESCE(dld.name, ''), COALESCE(dld.type, ''), COALESCE(dld.abv, 0),
+            COALESCE(dld.size_value, 0), COALESCE(dld.size_unit, ''), COALESCE(dld.standard_drinks, 0)
+        FROM drink_logs dl
+        JOIN drink_log_details dld ON dl.drink_details_id = dld.id
+        WHERE dl.user_id = ?
+        AND dl.logged_at >= ?
+        AND dl.logged_at <= ?`, userID, startDate.UTC(), endDate.UTC())
 	if err != nil {
-		return nil, fmt.Errorf("failed to get drink stats: %w", err)
+		return nil, fmt.Errorf("failed to get breakdown: %w", err)
 	}
 	defer rows.Close()
 
-	var stats []models.DrinkStatsPoint
+	var drinks []drinkDetails
 	for rows.Next() {
-		var stat models.DrinkStatsPoint
-		var drinkCount int64
-		var totalStandardDrinks float64
-
-		if err := rows.Scan(&stat.TimePeriod, &drinkCount, &totalStandardDrinks); err != nil {
-			return nil, fmt.Errorf("failed to scan drink stats: %w", err)
+		var drink drinkDetails
+		if err := rows.Scan(&drink.name, &drink.drinkType, &drink.abv, &drink.sizeValue, &drink.sizeUnit, &drink.standardDrinks); err != nil {
+			return nil, fmt.Errorf("failed to scan breakdown: %w", err)
 		}
+		drinks = append(drinks, drink)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get breakdown: %w", err)
+	}
+
+	return breakdown(groupBy, drinks), nil
+}
 
-		stat.DrinkCount = int(drinkCount)
-		stat.TotalStandardDrinks = totalStandardDrinks
-		stats = append(stats, stat)
+// GetDrinkStats counts the drinks of a user by period, the periods being those of the calendar
+func (r *Repository) GetDrinkStats(userID int64, period models.TimePeriod, startDate time.Time, endDate time.Time, calendar Calendar) ([]models.DrinkStatsPoint, error) {
+	drinks, err := r.getLoggedDrinks(userID, startDate, endDate)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get drink stats: %w", err)
 	}
 
-	if err = rows.Err(); err != nil {
-		return nil, fmt.Errorf("error iterating drink stats rows: %w", err)
+	// The drinks are sorted, so are their periods
+	var stats []models.DrinkStatsPoint
+	for _, drink := range drinks {
+		key := calendar.periodKey(period, drink.loggedAt)
+		if len(stats) == 0 || stats[len(stats)-1].TimePeriod != key {
+			stats = append(stats, models.DrinkStatsPoint{TimePeriod: key})
+		}
+
+		stat := &stats[len(stats)-1]
+		stat.DrinkCount++
+		stat.TotalStandardDrinks += drink.standardDrinks
 	}
 
+	for i := range stats {
+		stats[i].TotalStandardDrinks = roundStandardDrinks(stats[i].TotalStandardDrinks)
+	}
 	return stats, nil
 }
 
-func (r *Repository) GetMonthlyBACStats(userID int64, startDate, endDate time.Time) ([]dtos.MonthlyBACStats, error) {
-	query := `
-        WITH RECURSIVE 
-        months AS (
-            SELECT date(?) as month
-            UNION ALL
-            SELECT date(month, '+1 month')
-            FROM months
-            WHERE month < date(?)
-        ),
-        numbers(n) AS (
-            SELECT 0 UNION ALL SELECT n+1 FROM numbers WHERE n < 31
-        ),
-        days AS (
-            SELECT date(month, '+' || numbers.n || ' days') as day
-            FROM months
-            CROSS JOIN numbers
-            WHERE 
-                -- Only include dates that are within the same month
-                strftime('%m', date(month, '+' || numbers.n || ' days')) = strftime('%m', month)
-                -- And ensure the date is valid (will exclude invalid dates like Feb 30)
-                AND date(month, '+' || numbers.n || ' days') IS NOT NULL
-                -- Exclude future dates
-                AND date(month, '+' || numbers.n || ' days') <= date('now')
-        )
-        SELECT 
-            strftime('%Y', d.day) as year,
-            strftime('%m', d.day) as month,
-            COALESCE(dl.bac_category, 'sober') as category,
-            COUNT(*) as count
-        FROM days d
-        LEFT JOIN (
-            SELECT 
-                date(dl.logged_at) as log_date,
-                CASE 
-                    WHEN SUM(dld.standard_drinks) = 0 THEN 'sober'
-                    WHEN SUM(dld.standard_drinks) < 4 THEN 'light'
-                    ELSE 'heavy'
-                END as bac_category
-            FROM drink_logs dl
-            JOIN drink_log_details dld ON dl.drink_details_id = dld.id
-            WHERE dl.user_id = ?
-            GROUP BY date(dl.logged_at)
-        ) dl ON d.day = dl.log_date
-        GROUP BY year, month, category
-        ORDER BY year, month, category`
-
-	rows, err := r.db.Query(query, startDate, endDate, userID)
+// GetMonthlyBACStats counts the days of each month by BAC category, from the standard drinks of
+// each day. The days are those of the calendar, and stop at today.
+func (r *Repository) GetMonthlyBACStats(userID int64, startDate, endDate time.Time, calendar Calendar) ([]dtos.MonthlyBACStats, error) {
+	firstDay := calendar.Day(startDate)
+	lastDay := calendar.Day(endDate)
+	if today := calendar.Day(time.Now()); lastDay.After(today) {
+		lastDay = today
+	}
+
+	standardDrinksByDay, err := r.GetStandardDrinksByDay(userID, firstDay, lastDay, calendar)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get monthly BAC stats: %w", err)
 	}
-	defer rows.Close()
 
-	statsMap := make(map[string]dtos.MonthlyBACStats)
+	var result []dtos.MonthlyBACStats
+	for day := firstDay; !day.After(lastDay); day = calendar.AddDays(day, 1) {
+		if len(result) == 0 || result[len(result)-1].Year != day.Year() || result[len(result)-1].Month != int(day.Month()) {
+			result = append(result, dtos.MonthlyBACStats{
+				Year:   day.Year(),
+				Month:  int(day.Month()),
+				Counts: make(map[models.BACCategory]int),
+			})
+		}
 
-	for rows.Next() {
-		var year, month string
-		var category models.BACCategory
-		var count int
+		stats := &result[len(result)-1]
+		stats.Counts[bacCategory(standardDrinksByDay[day])]++
+		stats.Total++
+	}
 
-		if err := rows.Scan(&year, &month, &category, &count); err != nil {
-			return nil, fmt.Errorf("failed to scan monthly BAC stats: %w", err)
-		}
+	return result, nil
+}
 
-		key := fmt.Sprintf("%s-%s", year, month)
-		stats, exists := statsMap[key]
-		if !exists {
-			y, _ := strconv.Atoi(year)
-			m, _ := strconv.Atoi(month)
-			stats = dtos.MonthlyBACStats{
-				Year:   y,
-				Month:  m,
-				Counts: make(map[models.BACCategory]int),
-			}
-		}
+// GetHeatmap counts the drinks of a user by day of the week and local hour, the day being the
+// drinking day of the calendar
+func (r *Repository) GetHeatmap(userID int64, startDate, endDate time.Time, calendar Calendar) (*dtos.HeatmapResponse, error) {
+	drinks, err := r.getLoggedDrinks(userID, startDate, endDate)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get heatmap: %w", err)
+	}
 
-		stats.Counts[category] = count
-		stats.Total += count
-		statsMap[key] = stats
+	heatmap := &dtos.HeatmapResponse{}
+	for weekday := time.Monday; len(heatmap.Weekdays) < 7; weekday = (weekday + 1) % 7 {
+		heatmap.Weekdays = append(heatmap.Weekdays, weekday.String())
 	}
 
-	// Convert map to slice
-	var result []dtos.MonthlyBACStats
-	for _, stats := range statsMap {
-		result = append(result, stats)
+	for _, drink := range drinks {
+		row := (int(calendar.Day(drink.loggedAt).Weekday()) + 6) % 7
+		column := drink.loggedAt.In(calendar.location).Hour()
+		heatmap.DrinkCounts[row][column]++
+		heatmap.StandardDrinks[row][column] += drink.standardDrinks
+		heatmap.TotalDrinks++
+		heatmap.TotalStandardDrinks += drink.standardDrinks
 	}
 
-	// Sort by year and month
-	sort.Slice(result, func(i, j int) bool {
-		if result[i].Year != result[j].Year {
-			return result[i].Year < result[j].Year
+	for row := range heatmap.StandardDrinks {
+		for column := range heatmap.StandardDrinks[row] {
+			heatmap.StandardDrinks[row][column] = roundStandardDrinks(heatmap.StandardDrinks[row][column])
 		}
-		return result[i].Month < result[j].Month
-	})
+	}
+	heatmap.TotalStandardDrinks = roundStandardDrinks(heatmap.TotalStandardDrinks)
+	return heatmap, nil
+}
 
-	return result, nil
+// GetStandardDrinksByDay sums the standard drinks of each day between two days of the calendar,
+// both included. The days without drinks are left out.
+func (r *Repository) GetStandardDrinksByDay(userID int64, firstDay, lastDay time.Time, calendar Calendar) (map[time.Time]float64, error) {
+	drinks, err := r.getLoggedDrinks(userID, calendar.Start(firstDay), calendar.Start(calendar.AddDays(lastDay, 1)).Add(-time.Nanosecond))
+	if err != nil {
+		return nil, err
+	}
+
+	standardDrinksByDay := make(map[time.Time]float64)
+	for _, drink := range drinks {
+		standardDrinksByDay[calendar.Day(drink.loggedAt)] += drink.standardDrinks
+	}
+	return standardDrinksByDay, nil
+}
+
+// bacCategory estimates the BAC category of a day from its standard drinks
+func bacCategory(standardDrinks float64) models.BACCategory {
+	switch {
+	case standardDrinks == 0:
+		return models.BACCategorySober
+	case standardDrinks < 4:
+		return models.BACCategoryLight
+	default:
+		return models.BACCategoryHeavy
+	}
 }
@@ -75,7 +75,7 @@ func TestGetDrinkStats(t *testing.T) {
 		insertTestDrink(t, now, 2.0)
 		insertTestDrink(t, yesterday, 1.0)
 
-		stats, err := repo.GetDrinkStats(userID, models.TimePeriodDaily, yesterday, now)
+		stats, err := repo.GetDrinkStats(userID, models.TimePeriodDaily, yesterday, now, NewCalendar(time.UTC, 0))
 		assert.NoError(t, err)
 		assert.Len(t, stats, 2)
 
@@ -96,7 +96,7 @@ func TestGetDrinkStats(t *testing.T) {
 		insertTestDrink(t, now, 1.5)
 		insertTestDrink(t, lastWeek, 2.0)
 
-		stats, err := repo.GetDrinkStats(userID, models.TimePeriodWeekly, lastWeek, now)
+		stats, err := repo.GetDrinkStats(userID, models.TimePeriodWeekly, lastWeek, now, NewCalendar(time.UTC, 0))
 		assert.NoError(t, err)
 		assert.Len(t, stats, 2)
 	})
@@ -106,7 +106,7 @@ func TestGetDrinkStats(t *testing.T) {
 		startDate := time.Now().AddDate(0, -1, 0) // One month ago
 		endDate := time.Now()
 
-		stats, err := repo.GetDrinkStats(userID, models.TimePeriodDaily, startDate, endDate)
+		stats, err := repo.GetDrinkStats(userID, models.TimePeriodDaily, startDate, endDate, NewCalendar(time.UTC, 0))
 		assert.NoError(t, err)
 		assert.Len(t, stats, 0)
 	})
@@ -137,10 +137,199 @@ func TestGetDrinkStats(t *testing.T) {
 		assert.NoError(t, err)
 
 		// Check stats for first user
-		stats, err := repo.GetDrinkStats(userID, models.TimePeriodDaily, now, now)
+		stats, err := repo.GetDrinkStats(userID, models.TimePeriodDaily, now, now, NewCalendar(time.UTC, 0))
 		assert.NoError(t, err)
 		assert.Len(t, stats, 1)
 		assert.Equal(t, 1, stats[0].DrinkCount)
 		assert.Equal(t, 1.5, stats[0].TotalStandardDrinks)
 	})
 }
+
+func TestGetDrinkStatsInLocalTime(t *testing.T) {
+	repo := setupTestDB(t)
+	paris, err := time.LoadLocation("Europe/Paris")
+	assert.NoError(t, err)
+
+	// Half past midnight on Saturday in Paris, still Friday in UTC
+	saturdayNight := time.Date(2025, 3, 8, 0, 30, 0, 0, paris)
+	insertDrink(t, repo, 1, saturdayNight, 1.5)
+
+	stats, err := repo.GetDrinkStats(1, models.TimePeriodDaily, saturdayNight.AddDate(0, 0, -1), saturdayNight, NewCalendar(paris, 0))
+	assert.NoError(t, err)
+	assert.Len(t, stats, 1)
+	assert.Equal(t, "2025-03-08", stats[0].TimePeriod)
+
+	stats, err = repo.GetDrinkStats(1, models.TimePeriodDaily, saturdayNight.AddDate(0, 0, -1), saturdayNight, NewCalendar(time.UTC, 0))
+	assert.NoError(t, err)
+	assert.Len(t, stats, 1)
+	assert.Equal(t, "2025-03-07", stats[0].TimePeriod)
+
+	// New Year's Eve after midnight belongs to the new year, month and week
+	newYear := time.Date(2025, 1, 1, 0, 30, 0, 0, paris)
+	insertDrink(t, repo, 1, newYear, 1.0)
+	for period, want := range map[models.TimePeriod]string{
+		models.TimePeriodWeekly:  "2025-00",
+		models.TimePeriodMonthly: "2025-01",
+		models.TimePeriodYearly:  "2025",
+	} {
+		stats, err := repo.GetDrinkStats(1, period, newYear.Add(-time.Hour), newYear, NewCalendar(paris, 0))
+		assert.NoError(t, err)
+		assert.Len(t, stats, 1)
+		assert.Equal(t, want, stats[0].TimePeriod, period)
+	}
+}
+
+func TestPeriodKeyMatchesSQLite(t *testing.T) {
+	repo := setupTestDB(t)
+	calendar := NewCalendar(time.UTC, 0)
+
+	// Every day of two years, one starting on a Monday and one not
+	for day := time.Date(2023, 12, 25, 12, 0, 0, 0, time.UTC); day.Year() < 2026; day = day.AddDate(0, 0, 1) {
+		var want string
+		assert.NoError(t, repo.db.QueryRow("SELECT strftime('%Y-%W', ?)", day.Format("2006-01-02")).Scan(&want))
+		assert.Equal(t, want, calendar.periodKey(models.TimePeriodWeekly, day), day)
+	}
+}
+
+func TestGetMonthlyBACStatsInLocalTime(t *testing.T) {
+	repo := setupTestDB(t)
+	tokyo, err := time.LoadLocation("Asia/Tokyo")
+	assert.NoError(t, err)
+
+	// Late on the 31st of January in UTC is the 1st of February in Tokyo
+	insertDrink(t, repo, 1, time.Date(2025, 1, 31, 20, 0, 0, 0, time.UTC), 5)
+
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, tokyo)
+	end := time.Date(2025, 2, 28, 0, 0, 0, 0, tokyo)
+	stats, err := repo.GetMonthlyBACStats(1, start, end, NewCalendar(tokyo, 0))
+	assert.NoError(t, err)
+	assert.Len(t, stats, 2)
+
+	assert.Equal(t, 1, stats[0].Month)
+	assert.Equal(t, 31, stats[0].Total)
+	assert.Equal(t, 31, stats[0].Counts[models.BACCategorySober])
+
+	assert.Equal(t, 2, stats[1].Month)
+	assert.Equal(t, 28, stats[1].Total)
+	assert.Equal(t, 1, stats[1].Counts[models.BACCategoryHeavy])
+}
+
+// insertDrink logs a 330 ml beer of the given standard drinks
+func insertDrink(t *testing.T, repo *Repository, userID int64, loggedAt time.Time, standardDrinks float64) {
+	insertDrinkDetails(t, repo, userID, loggedAt, drinkDetails{
+		name: "Test Beer", drinkType: "Beer", sizeValue: 330, sizeUnit: "ml", abv: 0.05, standardDrinks: standardDrinks,
+	})
+}
+
+func insertDrinkDetails(t *testing.T, repo *Repository, userID int64, loggedAt time.Time, drink drinkDetails) {
+	result, err := repo.db.Exec(`
+        INSERT INTO drink_log_details (name, type, size_value, size_unit, abv, standard_drinks)
+        VALUES (?, ?, ?, ?, ?, ?)`, drink.name, drink.drinkType, drink.sizeValue, drink.sizeUnit, drink.abv, drink.standardDrinks)
+	assert.NoError(t, err)
+	detailsID, err := result.LastInsertId()
+	assert.NoError(t, err)
+
+	_, err = repo.db.Exec("INSERT INTO drink_logs (user_id, drink_details_id, logged_at) VALUES (?, ?, ?)", userID, detailsID, loggedAt.UTC())
+	assert.NoError(t, err)
+}
+
+func TestGetDrinkStatsWithDayRollover(t *testing.T) {
+	repo := setupTestDB(t)
+	paris, err := time.LoadLocation("Europe/Paris")
+	assert.NoError(t, err)
+
+	// A night out from Friday evening to Saturday morning is a single drinking day
+	insertDrink(t, repo, 1, time.Date(2025, 3, 7, 22, 0, 0, 0, paris), 2)
+	insertDrink(t, repo, 1, time.Date(2025, 3, 8, 2, 0, 0, 0, paris), 3)
+	insertDrink(t, repo, 1, time.Date(2025, 3, 8, 18, 0, 0, 0, paris), 1)
+
+	start := time.Date(2025, 3, 7, 0, 0, 0, 0, paris)
+	end := time.Date(2025, 3, 9, 0, 0, 0, 0, paris)
+	stats, err := repo.GetDrinkStats(1, models.TimePeriodDaily, start, end, NewCalendar(paris, 6))
+	assert.NoError(t, err)
+	assert.Equal(t, []models.DrinkStatsPoint{
+		{TimePeriod: "2025-03-07", DrinkCount: 2, TotalStandardDrinks: 5},
+		{TimePeriod: "2025-03-08", DrinkCount: 1, TotalStandardDrinks: 1},
+	}, stats)
+
+	stats, err = repo.GetDrinkStats(1, models.TimePeriodDaily, start, end, NewCalendar(paris, 0))
+	assert.NoError(t, err)
+	assert.Equal(t, []models.DrinkStatsPoint{
+		{TimePeriod: "2025-03-07", DrinkCount: 1, TotalStandardDrinks: 2},
+		{TimePeriod: "2025-03-08", DrinkCount: 2, TotalStandardDrinks: 4},
+	}, stats)
+}
+
+func TestGetMonthlyBACStatsWithDayRollover(t *testing.T) {
+	repo := setupTestDB(t)
+
+	// The drinks after midnight on the 1st of February count for the 31st of January
+	insertDrink(t, repo, 1, time.Date(2025, 1, 31, 23, 0, 0, 0, time.UTC), 2)
+	insertDrink(t, repo, 1, time.Date(2025, 2, 1, 3, 0, 0, 0, time.UTC), 3)
+
+	start := time.Date(2025, 1, 1, 6, 0, 0, 0, time.UTC)
+	end := time.Date(2025, 2, 28, 12, 0, 0, 0, time.UTC)
+	stats, err := repo.GetMonthlyBACStats(1, start, end, NewCalendar(time.UTC, 6))
+	assert.NoError(t, err)
+	assert.Len(t, stats, 2)
+	assert.Equal(t, 1, stats[0].Counts[models.BACCategoryHeavy])
+	assert.Equal(t, 31, stats[0].Total)
+	assert.Equal(t, 28, stats[1].Counts[models.BACCategorySober])
+	assert.Equal(t, 28, stats[1].Total)
+}
+
+func TestCalendarDay(t *testing.T) {
+	paris, err := time.LoadLocation("Europe/Paris")
+	assert.NoError(t, err)
+	calendar := NewCalendar(paris, 6)
+
+	tests := []struct {
+		name string
+		time time.Time
+		want string
+	}{
+		{"evening", time.Date(2025, 3, 29, 23, 0, 0, 0, paris), "2025-03-29"},
+		{"before the rollover", time.Date(2025, 3, 30, 5, 59, 0, 0, paris), "2025-03-29"},
+		{"at the rollover", time.Date(2025, 3, 30, 6, 0, 0, 0, paris), "2025-03-30"},
+		// The clocks go from 2am to 3am that night
+		{"after the change to summer time", time.Date(2025, 3, 30, 3, 30, 0, 0, paris), "2025-03-29"},
+		{"in UTC", time.Date(2025, 3, 30, 4, 30, 0, 0, time.UTC), "2025-03-30"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, calendar.Day(tt.time).Format("2006-01-02"))
+		})
+	}
+}
+
+func TestGetHeatmap(t *testing.T) {
+	repo := setupTestDB(t)
+	paris, err := time.LoadLocation("Europe/Paris")
+	assert.NoError(t, err)
+
+	// Wednesday afternoon, then Friday night until 2am
+	insertDrink(t, repo, 1, time.Date(2025, 3, 5, 15, 20, 0, 0, paris), 1)
+	insertDrink(t, repo, 1, time.Date(2025, 3, 7, 23, 10, 0, 0, paris), 1.5)
+	insertDrink(t, repo, 1, time.Date(2025, 3, 7, 23, 50, 0, 0, paris), 1.25)
+	insertDrink(t, repo, 1, time.Date(2025, 3, 8, 2, 0, 0, 0, paris), 2)
+	insertDrink(t, repo, 2, time.Date(2025, 3, 7, 23, 0, 0, 0, paris), 1)
+
+	start := time.Date(2025, 3, 1, 0, 0, 0, 0, paris)
+	end := time.Date(2025, 3, 10, 0, 0, 0, 0, paris)
+	heatmap, err := repo.GetHeatmap(1, start, end, NewCalendar(paris, 6))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"Monday", "Tuesday", "Wednesday", "Thursday", "Friday", "Saturday", "Sunday"}, heatmap.Weekdays)
+	assert.Equal(t, 1, heatmap.DrinkCounts[2][15])
+	assert.Equal(t, 2, heatmap.DrinkCounts[4][23])
+	assert.Equal(t, 2.75, heatmap.StandardDrinks[4][23])
+	// The drink after midnight stays on Friday night
+	assert.Equal(t, 1, heatmap.DrinkCounts[4][2])
+	assert.Equal(t, 0, heatmap.DrinkCounts[5][2])
+	assert.Equal(t, 4, heatmap.TotalDrinks)
+	assert.Equal(t, 5.75, heatmap.TotalStandardDrinks)
+
+	// Without rollover, it is on Saturday
+	heatmap, err = repo.GetHeatmap(1, start, end, NewCalendar(paris, 0))
+	assert.NoError(t, err)
+	assert.Equal(t, 1, heatmap.DrinkCounts[5][2])
+}
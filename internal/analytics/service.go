@@ -8,16 +8,36 @@ import (
 )
 
 type DrinkStatsRepository interface {
-	GetDrinkStats(userID int64, period models.TimePeriod, startDate time.Time, endDate time.Time) ([]models.DrinkStatsPoint, error)
-	GetMonthlyBACStats(userID int64, startDate, endDate time.Time) ([]dtos.MonthlyBACStats, error)
+	GetDrinkStats(userID int64, period models.TimePeriod, startDate time.Time, endDate time.Time, calendar Calendar) ([]models.DrinkStatsPoint, error)
+	GetMonthlyBACStats(userID int64, startDate, endDate time.Time, calendar Calendar) ([]dtos.MonthlyBACStats, error)
+	GetStandardDrinksByDay(userID int64, firstDay, lastDay time.Time, calendar Calendar) (map[time.Time]float64, error)
+	GetHeatmap(userID int64, startDate, endDate time.Time, calendar Calendar) (*dtos.HeatmapResponse, error)
+	GetBreakdown(userID int64, groupBy models.BreakdownGroupBy, startDate, endDate time.Time) (*dtos.BreakdownResponse, error)
+}
+
+// ProfileRepository gives the profile of a user, for their time zone and drinking days, and
+// their personal limits
+type ProfileRepository interface {
+	GetUserProfile(userID int64) (*models.UserProfile, error)
+	GetDrinkLimits(userID int64) (*models.DrinkLimits, error)
 }
 
 type Service struct {
 	drinkStatsRepo DrinkStatsRepository
+	profileRepo    ProfileRepository
 }
 
-func NewService(drinkStatsRepo DrinkStatsRepository) *Service {
-	return &Service{drinkStatsRepo: drinkStatsRepo}
+func NewService(drinkStatsRepo DrinkStatsRepository, profileRepo ProfileRepository) *Service {
+	return &Service{drinkStatsRepo: drinkStatsRepo, profileRepo: profileRepo}
+}
+
+// calendar returns the calendar the drinks of a user are grouped in
+func (s *Service) calendar(userID int64) (Calendar, error) {
+	profile, err := s.profileRepo.GetUserProfile(userID)
+	if err != nil {
+		return Calendar{}, err
+	}
+	return ProfileCalendar(profile), nil
 }
 
 func (s *Service) GetDrinkStats(userID int64, filters dtos.DrinkStatsFilters) ([]models.DrinkStatsPoint, error) {
@@ -28,18 +48,136 @@ func (s *Service) GetDrinkStats(userID int64, filters dtos.DrinkStatsFilters) ([
 		filters.EndDate = &constants.DefaultEndDate
 	}
 
-	return s.drinkStatsRepo.GetDrinkStats(userID, filters.Period, *filters.StartDate, *filters.EndDate)
+	calendar, err := s.calendar(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.drinkStatsRepo.GetDrinkStats(userID, filters.Period, *filters.StartDate, *filters.EndDate, calendar)
 }
 
 func (s *Service) GetMonthlyBACStats(userID int64, filters dtos.DrinkStatsFilters) ([]dtos.MonthlyBACStats, error) {
+	calendar, err := s.calendar(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if filters.StartDate == nil {
+		// The current month and the 11 before, whole
+		today := calendar.Day(time.Now())
+		firstMonth := calendar.Start(time.Date(today.Year(), today.Month()-11, 1, 0, 0, 0, 0, calendar.location))
+		filters.StartDate = &firstMonth
+	}
+	if filters.EndDate == nil {
+		now := time.Now()
+		filters.EndDate = &now
+	}
+
+	return s.drinkStatsRepo.GetMonthlyBACStats(userID, *filters.StartDate, *filters.EndDate, calendar)
+}
+
+// GetGuidelines compares the drinks of a user with their personal limits, if any, and with
+// each guideline of the catalogue
+func (s *Service) GetGuidelines(userID int64, filters dtos.GuidelinesFilters) ([]dtos.GuidelineProgress, error) {
+	calendar, err := s.calendar(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	limits, err := s.profileRepo.GetDrinkLimits(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	today := calendar.Day(time.Now())
+	firstDay := calendar.Day(constants.DefaultStartDate)
+	if filters.StartDate != nil {
+		firstDay = calendar.WeekStart(calendar.Day(*filters.StartDate))
+	}
+	standardDrinksByDay, err := s.drinkStatsRepo.GetStandardDrinksByDay(userID, firstDay, today, calendar)
+	if err != nil {
+		return nil, err
+	}
+
+	evaluated := guidelines
+	if limits.IsSet() {
+		evaluated = append([]models.Guideline{limits.Guideline()}, guidelines...)
+	}
+
+	weeks := evaluatedWeeks(calendar, filters, standardDrinksByDay, today)
+	progress := make([]dtos.GuidelineProgress, 0, len(evaluated))
+	for _, guideline := range evaluated {
+		progress = append(progress, evaluateGuideline(guideline, calendar, standardDrinksByDay, weeks, today))
+	}
+	return progress, nil
+}
+
+// GetStreaks computes the alcohol-free streaks of a user since the tracking started, with the
+// alcohol-free days of each week and month between the dates of the filters
+func (s *Service) GetStreaks(userID int64, filters dtos.StreaksFilters) (*dtos.StreaksResponse, error) {
+	profile, err := s.profileRepo.GetUserProfile(userID)
+	if err != nil {
+		return nil, err
+	}
+	calendar := ProfileCalendar(profile)
+
+	today := calendar.Day(time.Now())
+	standardDrinksByDay, err := s.drinkStatsRepo.GetStandardDrinksByDay(userID, calendar.Day(constants.DefaultStartDate), today, calendar)
+	if err != nil {
+		return nil, err
+	}
+
+	first := trackingStart(calendar, standardDrinksByDay, profile.CreatedAt)
+	response := &dtos.StreaksResponse{}
+	response.CurrentStreak, response.LongestStreak, response.Milestones = computeStreaks(calendar, standardDrinksByDay, first, today)
+
+	last := today
+	if !first.IsZero() {
+		trackedSince := first.Format(dateFormat)
+		response.TrackedSince = &trackedSince
+	} else {
+		// Nothing is tracked, no period to count
+		first = calendar.AddDays(today, 1)
+	}
+	if filters.StartDate != nil && calendar.Day(*filters.StartDate).After(first) {
+		first = calendar.Day(*filters.StartDate)
+	}
+	if filters.EndDate != nil && calendar.Day(*filters.EndDate).Before(last) {
+		last = calendar.Day(*filters.EndDate)
+	}
+	response.DryDaysPerWeek, response.DryDaysPerMonth = countDryDays(calendar, standardDrinksByDay, first, last)
+
+	return response, nil
+}
+
+// GetHeatmap counts the drinks of a user by day of the week and hour of the day, between the
+// dates of the filters or since the first drink
+func (s *Service) GetHeatmap(userID int64, filters dtos.DrinkStatsFilters) (*dtos.HeatmapResponse, error) {
 	if filters.StartDate == nil {
-		oneYearAgo := time.Now().AddDate(0, -11, 0)
-		filters.StartDate = &oneYearAgo
+		filters.StartDate = &constants.DefaultStartDate
+	}
+	if filters.EndDate == nil {
+		now := time.Now()
+		filters.EndDate = &now
+	}
+
+	calendar, err := s.calendar(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.drinkStatsRepo.GetHeatmap(userID, *filters.StartDate, *filters.EndDate, calendar)
+}
+
+// GetBreakdown groups the drinks of a user between the dates of the filters, all of them by default
+func (s *Service) GetBreakdown(userID int64, groupBy models.BreakdownGroupBy, filters dtos.DrinkStatsFilters) (*dtos.BreakdownResponse, error) {
+	if filters.StartDate == nil {
+		filters.StartDate = &constants.DefaultStartDate
 	}
 	if filters.EndDate == nil {
 		now := time.Now()
 		filters.EndDate = &now
 	}
 
-	return s.drinkStatsRepo.GetMonthlyBACStats(userID, *filters.StartDate, *filters.EndDate)
+	return s.drinkStatsRepo.GetBreakdown(userID, groupBy, *filters.StartDate, *filters.EndDate)
 }
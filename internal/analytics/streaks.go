@@ -0,0 +1,98 @@
+package analytics
+
+import (
+	"time"
+
+	"go-sober/internal/dtos"
+)
+
+// milestones are the lengths of alcohol-free streaks celebrated, in days
+var milestones = []int{7, 30, 100}
+
+// trackingStart returns the day the alcohol-free days are counted from: the first drink logged,
+// or the creation of the profile when it is earlier or there is no drink. It is zero when
+// neither exists.
+func trackingStart(calendar Calendar, standardDrinksByDay map[time.Time]float64, profileCreatedAt time.Time) time.Time {
+	var first time.Time
+	for day := range standardDrinksByDay {
+		if first.IsZero() || day.Before(first) {
+			first = day
+		}
+	}
+	if !profileCreatedAt.IsZero() {
+		if created := calendar.Day(profileCreatedAt); first.IsZero() || created.Before(first) {
+			first = created
+		}
+	}
+	return first
+}
+
+// computeStreaks computes the current and longest alcohol-free streaks and the milestones
+// reached, from the first tracked day to today
+func computeStreaks(calendar Calendar, standardDrinksByDay map[time.Time]float64, first, today time.Time) (current, longest dtos.Streak, reached []dtos.Milestone) {
+	reached = make([]dtos.Milestone, len(milestones))
+	for i, days := range milestones {
+		reached[i] = dtos.Milestone{Days: days}
+	}
+
+	var runStart time.Time
+	var run int
+	for day := first; !first.IsZero() && !day.After(today); day = calendar.AddDays(day, 1) {
+		if standardDrinksByDay[day] > 0 {
+			run = 0
+			continue
+		}
+
+		if run == 0 {
+			runStart = day
+		}
+		run++
+		for i := range reached {
+			if run == reached[i].Days && !reached[i].Reached {
+				reachedOn := day.Format(dateFormat)
+				reached[i].Reached = true
+				reached[i].ReachedOn = &reachedOn
+			}
+		}
+		// The most recent of the longest streaks
+		if run >= longest.Days {
+			longest = streak(runStart, day, run)
+		}
+	}
+
+	if run > 0 {
+		current = streak(runStart, today, run)
+	}
+	for i := range reached {
+		reached[i].DaysToGo = max(0, reached[i].Days-current.Days)
+	}
+	return current, longest, reached
+}
+
+func streak(start, end time.Time, days int) dtos.Streak {
+	return dtos.Streak{Days: days, StartDate: start.Format(dateFormat), EndDate: end.Format(dateFormat)}
+}
+
+// countDryDays counts the alcohol-free days of each week and month between two days, both
+// included. The periods are listed oldest first, even without tracked days.
+func countDryDays(calendar Calendar, standardDrinksByDay map[time.Time]float64, first, last time.Time) (perWeek, perMonth []dtos.DryDaysPoint) {
+	perWeek, perMonth = []dtos.DryDaysPoint{}, []dtos.DryDaysPoint{}
+	for day := first; !day.After(last); day = calendar.AddDays(day, 1) {
+		week := calendar.WeekStart(day).Format(dateFormat)
+		if len(perWeek) == 0 || perWeek[len(perWeek)-1].StartDate != week {
+			perWeek = append(perWeek, dtos.DryDaysPoint{StartDate: week})
+		}
+		month := calendar.MonthStart(day).Format(dateFormat)
+		if len(perMonth) == 0 || perMonth[len(perMonth)-1].StartDate != month {
+			perMonth = append(perMonth, dtos.DryDaysPoint{StartDate: month})
+		}
+
+		for _, point := range []*dtos.DryDaysPoint{&perWeek[len(perWeek)-1], &perMonth[len(perMonth)-1]} {
+			point.Days++
+			if standardDrinksByDay[day] == 0 {
+				point.DryDays++
+			}
+		}
+	}
+	return perWeek, perMonth
+}
@@ -0,0 +1,85 @@
+package analytics
+
+import (
+	"testing"
+	"time"
+
+	"go-sober/internal/dtos"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestComputeStreaks(t *testing.T) {
+	calendar := NewCalendar(time.UTC, 6)
+	day := func(month time.Month, day int) time.Time {
+		return time.Date(2025, month, day, 0, 0, 0, 0, time.UTC)
+	}
+
+	// Drinks on the 1st of January, the 10th of January and the 20th of February
+	standardDrinksByDay := map[time.Time]float64{day(1, 1): 2, day(1, 10): 1, day(2, 20): 3}
+	first := trackingStart(calendar, standardDrinksByDay, time.Time{})
+	assert.Equal(t, day(1, 1), first)
+
+	current, longest, milestones := computeStreaks(calendar, standardDrinksByDay, first, day(3, 1))
+	assert.Equal(t, dtos.Streak{Days: 9, StartDate: "2025-02-21", EndDate: "2025-03-01"}, current)
+	assert.Equal(t, dtos.Streak{Days: 40, StartDate: "2025-01-11", EndDate: "2025-02-19"}, longest)
+
+	assert.Len(t, milestones, 3)
+	assert.True(t, milestones[0].Reached)
+	assert.Equal(t, "2025-01-08", *milestones[0].ReachedOn)
+	assert.Equal(t, 0, milestones[0].DaysToGo)
+	assert.True(t, milestones[1].Reached)
+	assert.Equal(t, "2025-02-09", *milestones[1].ReachedOn)
+	assert.Equal(t, 21, milestones[1].DaysToGo)
+	assert.False(t, milestones[2].Reached)
+	assert.Nil(t, milestones[2].ReachedOn)
+	assert.Equal(t, 91, milestones[2].DaysToGo)
+
+	// A drink today ends the current streak
+	current, _, _ = computeStreaks(calendar, standardDrinksByDay, first, day(2, 20))
+	assert.Equal(t, dtos.Streak{}, current)
+
+	// Nothing tracked
+	current, longest, milestones = computeStreaks(calendar, nil, time.Time{}, day(3, 1))
+	assert.Zero(t, current.Days)
+	assert.Zero(t, longest.Days)
+	assert.False(t, milestones[0].Reached)
+}
+
+func TestTrackingStart(t *testing.T) {
+	paris, err := time.LoadLocation("Europe/Paris")
+	assert.NoError(t, err)
+	calendar := NewCalendar(paris, 6)
+
+	assert.True(t, trackingStart(calendar, nil, time.Time{}).IsZero())
+
+	// A profile created at 3am in Paris belongs to the previous drinking day
+	created := time.Date(2025, 3, 2, 2, 0, 0, 0, time.UTC)
+	assert.Equal(t, time.Date(2025, 3, 1, 0, 0, 0, 0, paris), trackingStart(calendar, nil, created))
+
+	firstDrink := time.Date(2025, 2, 10, 0, 0, 0, 0, paris)
+	assert.Equal(t, firstDrink, trackingStart(calendar, map[time.Time]float64{firstDrink: 1}, created))
+}
+
+func TestCountDryDays(t *testing.T) {
+	calendar := NewCalendar(time.UTC, 0)
+	day := func(month time.Month, day int) time.Time {
+		return time.Date(2025, month, day, 0, 0, 0, 0, time.UTC)
+	}
+	standardDrinksByDay := map[time.Time]float64{day(1, 29): 1, day(2, 1): 1, day(2, 3): 1}
+
+	// From Wednesday the 29th of January to Tuesday the 4th of February
+	perWeek, perMonth := countDryDays(calendar, standardDrinksByDay, day(1, 29), day(2, 4))
+	assert.Equal(t, []dtos.DryDaysPoint{
+		{StartDate: "2025-01-27", DryDays: 3, Days: 5},
+		{StartDate: "2025-02-03", DryDays: 1, Days: 2},
+	}, perWeek)
+	assert.Equal(t, []dtos.DryDaysPoint{
+		{StartDate: "2025-01-01", DryDays: 2, Days: 3},
+		{StartDate: "2025-02-01", DryDays: 2, Days: 4},
+	}, perMonth)
+
+	perWeek, perMonth = countDryDays(calendar, standardDrinksByDay, day(2, 5), day(2, 4))
+	assert.Empty(t, perWeek)
+	assert.Empty(t, perMonth)
+}
@@ -0,0 +1,71 @@
+package audit
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"go-sober/internal/models"
+)
+
+type Repository struct {
+	db *sql.DB
+}
+
+func NewRepository(db *sql.DB) *Repository {
+	return &Repository{db: db}
+}
+
+// Record stores an audit event
+func (r *Repository) Record(event *models.AuditEvent) error {
+	details, err := json.Marshal(event.Details)
+	if err != nil {
+		return fmt.Errorf("error encoding audit details: %w", err)
+	}
+	if event.Details == nil {
+		details = []byte("{}")
+	}
+
+	_, err = r.db.Exec(`
+        INSERT INTO audit_events (user_id, event_type, email, ip_address, user_agent, details, created_at)
+        VALUES (?, ?, ?, ?, ?, ?, ?)
+    `, event.UserID, event.Type, event.Email, event.IPAddress, event.UserAgent, string(details), time.Now().UTC())
+	if err != nil {
+		return fmt.Errorf("error recording audit event: %w", err)
+	}
+	return nil
+}
+
+// GetUserEvents returns the most recent events of a user, newest first
+func (r *Repository) GetUserEvents(userID int64, limit int) ([]models.AuditEvent, error) {
+	rows, err := r.db.Query(`
+        SELECT id, user_id, event_type, email, ip_address, user_agent, details, created_at
+        FROM audit_events
+        WHERE user_id = ?
+        ORDER BY created_at DESC, id DESC
+        LIMIT ?
+    `, userID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("error querying audit events: %w", err)
+	}
+	defer rows.Close()
+
+	var events []models.AuditEvent
+	for rows.Next() {
+		var event models.AuditEvent
+		var eventUserID sql.NullInt64
+		var details string
+		if err := rows.Scan(&event.ID, &eventUserID, &event.Type, &event.Email, &event.IPAddress, &event.UserAgent, &details, &event.CreatedAt); err != nil {
+			return nil, fmt.Errorf("error scanning audit event: %w", err)
+		}
+		if eventUserID.Valid {
+			event.UserID = &eventUserID.Int64
+		}
+		if err := json.Unmarshal([]byte(details), &event.Details); err != nil {
+			return nil, fmt.Errorf("error decoding audit details: %w", err)
+		}
+		events = append(events, event)
+	}
+	return events, rows.Err()
+}
@@ -0,0 +1,167 @@
+package auth
+
+import (
+	"database/sql"
+	"errors"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"go-sober/internal/models"
+)
+
+var (
+	ErrIncorrectPassword = errors.New("current password is incorrect")
+	ErrEmailTaken        = errors.New("email already in use")
+)
+
+// ChangePassword sets a new password for the user of the claims, who must know the current one.
+// Every other device is logged out and pending reset links stop working.
+func (s *Service) ChangePassword(claims *models.Claims, currentPassword, newPassword string) error {
+	user, err := s.repo.GetUserByID(claims.UserID)
+	if err != nil {
+		return err
+	}
+	if err := s.repo.ComparePassword(user.Password, currentPassword); err != nil {
+		return ErrIncorrectPassword
+	}
+	if err := s.CheckPassword(newPassword, user.Email); err != nil {
+		return err
+	}
+
+	if err := s.repo.UpdatePassword(user.ID, newPassword); err != nil {
+		return err
+	}
+	if err := s.repo.InvalidateUserTokens(user.ID, models.PasswordResetToken); err != nil {
+		return err
+	}
+	if err := s.revokeOtherSessions(user.ID, claims.SessionID); err != nil {
+		return err
+	}
+
+	s.recordAuditEvent(models.AuditPasswordChanged, &user.ID, user.Email, "", "", nil)
+	if err := s.mailer.Send(passwordChangedEmail(user.Email, s.config.BaseURL)); err != nil {
+		slog.Error("Could not send the password changed email", "user_id", user.ID, "error", err)
+	}
+	return nil
+}
+
+// ChangeEmail moves the account of a user, who must know their password, to a new address. The
+// new address is unverified until the user opens the link sent to it, and the previous address
+// is told about the change.
+func (s *Service) ChangeEmail(userID int64, password, email string) error {
+	user, err := s.repo.GetUserByID(userID)
+	if err != nil {
+		return err
+	}
+	if err := s.repo.ComparePassword(user.Password, password); err != nil {
+		return ErrIncorrectPassword
+	}
+	if email == user.Email {
+		return nil
+	}
+
+	if _, err := s.repo.GetUserByEmail(email); err == nil {
+		return ErrEmailTaken
+	} else if !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+
+	if err := s.repo.UpdateEmail(user.ID, email); err != nil {
+		return err
+	}
+	// The links sent to the previous address must not work anymore
+	if err := s.repo.InvalidateUserTokens(user.ID, models.EmailVerificationToken); err != nil {
+		return err
+	}
+	if err := s.repo.InvalidateUserTokens(user.ID, models.PasswordResetToken); err != nil {
+		return err
+	}
+
+	s.recordAuditEvent(models.AuditEmailChanged, &user.ID, email, "", "", map[string]string{"previous_email": user.Email})
+	if err := s.mailer.Send(emailChangedEmail(user.Email, email)); err != nil {
+		slog.Error("Could not send the email changed email", "user_id", user.ID, "error", err)
+	}
+
+	user.Email, user.EmailVerifiedAt = email, nil
+	if err := s.SendVerificationEmail(user); err != nil {
+		// The change is done, the user can ask for the link again
+		slog.Error("Could not send verification email", "user_id", user.ID, "error", err)
+	}
+	return nil
+}
+
+// SetUserDisabled disables or enables an account on behalf of an admin. Disabling logs the user
+// out of every device, and personal access tokens stop working until the account is enabled.
+// It returns sql.ErrNoRows if the user does not exist.
+func (s *Service) SetUserDisabled(adminID, userID int64, disabled bool) error {
+	if err := s.repo.SetUserDisabled(userID, disabled); err != nil {
+		return err
+	}
+
+	eventType := models.AuditUserEnabled
+	if disabled {
+		eventType = models.AuditUserDisabled
+		if err := s.revokeAllSessions(userID); err != nil {
+			return err
+		}
+	}
+	s.recordAuditEvent(eventType, &userID, "", "", "", map[string]string{"admin_id": strconv.FormatInt(adminID, 10)})
+	return nil
+}
+
+// SetUserRole changes the role of a user on behalf of an admin. The user is logged out of every
+// device, so that access tokens carrying the previous role stop working right away.
+// It returns sql.ErrNoRows if the user does not exist.
+func (s *Service) SetUserRole(adminID, userID int64, role models.Role) error {
+	if err := s.repo.SetUserRole(userID, role); err != nil {
+		return err
+	}
+	if err := s.revokeAllSessions(userID); err != nil {
+		return err
+	}
+
+	s.recordAuditEvent(models.AuditRoleChanged, &userID, "", "", "", map[string]string{
+		"admin_id": strconv.FormatInt(adminID, 10),
+		"role":     string(role),
+	})
+	return nil
+}
+
+// RequestAccountDeletion schedules the deletion of an account asked by its user, and returns
+// when it will be deleted. The user is logged out of every device and their personal access
+// tokens stop working; logging in again before the date cancels the deletion.
+func (s *Service) RequestAccountDeletion(userID int64) (time.Time, error) {
+	user, err := s.repo.GetUserByID(userID)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	requestedAt := time.Now().UTC()
+	if user.DeletionRequestedAt != nil {
+		requestedAt = *user.DeletionRequestedAt
+	} else if err := s.repo.SetDeletionRequested(userID, &requestedAt); err != nil {
+		return time.Time{}, err
+	}
+	if err := s.revokeAllSessions(userID); err != nil {
+		return time.Time{}, err
+	}
+
+	deleteAt := requestedAt.Add(s.config.Auth.Account.DeletionGracePeriod)
+	s.recordAuditEvent(models.AuditDeletionRequested, &userID, user.Email, "", "", map[string]string{"delete_at": deleteAt.Format(time.RFC3339)})
+	if err := s.mailer.Send(accountDeletionEmail(user.Email, s.config.BaseURL, deleteAt)); err != nil {
+		// The deletion is scheduled anyway, the email is only a courtesy
+		slog.Error("Could not send the account deletion email", "user_id", userID, "error", err)
+	}
+	return deleteAt, nil
+}
+
+// cancelAccountDeletion keeps an account whose user logged in again during the grace period
+func (s *Service) cancelAccountDeletion(user *models.User, ipAddress, userAgent string) error {
+	if err := s.repo.SetDeletionRequested(user.ID, nil); err != nil {
+		return err
+	}
+	user.DeletionRequestedAt = nil
+	s.recordAuditEvent(models.AuditDeletionCancelled, &user.ID, user.Email, ipAddress, userAgent, nil)
+	return nil
+}
@@ -0,0 +1,210 @@
+package auth
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"go-sober/internal/models"
+	"go-sober/internal/validation"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetUserDisabled(t *testing.T) {
+	service := setupTestService(t)
+	user := createTestUser(t, service)
+
+	result, err := service.Login(user.Email, "password123", "10.0.0.1", "phone")
+	require.NoError(t, err)
+	token, _, err := service.CreatePersonalAccessToken(user.ID, "script", []string{models.ScopeBACRead}, 0)
+	require.NoError(t, err)
+
+	require.NoError(t, service.SetUserDisabled(99, user.ID, true))
+	assert.ErrorIs(t, service.SetUserDisabled(99, user.ID+1, true), sql.ErrNoRows)
+
+	// Logged out everywhere, and no new login
+	claims, err := service.ValidateToken(result.Tokens.AccessToken)
+	require.NoError(t, err)
+	revoked, err := service.IsTokenRevoked(claims)
+	require.NoError(t, err)
+	assert.True(t, revoked)
+
+	_, err = service.RefreshSession(result.Tokens.RefreshToken, "phone", "10.0.0.1")
+	assert.ErrorIs(t, err, ErrInvalidRefreshToken)
+
+	_, err = service.Login(user.Email, "password123", "10.0.0.1", "phone")
+	assert.ErrorIs(t, err, ErrAccountDisabled)
+
+	_, err = service.AuthenticatePersonalAccessToken(token)
+	assert.ErrorIs(t, err, ErrInvalidPersonalAccessToken)
+
+	events, err := service.auditLog.GetUserEvents(user.ID, 10)
+	require.NoError(t, err)
+	require.NotEmpty(t, events)
+	assert.Equal(t, models.AuditUserDisabled, events[0].Type)
+
+	// Enabled again, the personal access token works again
+	require.NoError(t, service.SetUserDisabled(99, user.ID, false))
+	_, err = service.Login(user.Email, "password123", "10.0.0.1", "phone")
+	assert.NoError(t, err)
+	_, err = service.AuthenticatePersonalAccessToken(token)
+	assert.NoError(t, err)
+
+	t.Run("refresh racing with the disable", func(t *testing.T) {
+		tokens, err := service.CreateSession(user, "laptop", "10.0.0.1")
+		require.NoError(t, err)
+
+		// The account is disabled but its sessions are not revoked yet
+		require.NoError(t, service.repo.SetUserDisabled(user.ID, true))
+		_, err = service.RefreshSession(tokens.RefreshToken, "laptop", "10.0.0.1")
+		assert.ErrorIs(t, err, ErrAccountDisabled)
+	})
+}
+
+func TestSetUserRole(t *testing.T) {
+	service := setupTestService(t)
+	user := createTestUser(t, service)
+	assert.Equal(t, models.RoleUser, user.Role)
+
+	result, err := service.Login(user.Email, "password123", "10.0.0.1", "phone")
+	require.NoError(t, err)
+
+	require.NoError(t, service.SetUserRole(99, user.ID, models.RoleAdmin))
+
+	// Tokens with the previous role are revoked
+	claims, err := service.ValidateToken(result.Tokens.AccessToken)
+	require.NoError(t, err)
+	assert.Equal(t, models.RoleUser, claims.Role)
+	revoked, err := service.IsTokenRevoked(claims)
+	require.NoError(t, err)
+	assert.True(t, revoked)
+
+	result, err = service.Login(user.Email, "password123", "10.0.0.1", "phone")
+	require.NoError(t, err)
+	claims, err = service.ValidateToken(result.Tokens.AccessToken)
+	require.NoError(t, err)
+	assert.Equal(t, models.RoleAdmin, claims.Role)
+}
+
+func TestRequestAccountDeletion(t *testing.T) {
+	service := setupTestService(t)
+	mail := service.mailer.(*fakeMailer)
+	user := createTestUser(t, service)
+
+	result, err := service.Login(user.Email, "password123", "10.0.0.1", "phone")
+	require.NoError(t, err)
+	token, _, err := service.CreatePersonalAccessToken(user.ID, "script", []string{models.ScopeBACRead}, 0)
+	require.NoError(t, err)
+
+	deleteAt, err := service.RequestAccountDeletion(user.ID)
+	require.NoError(t, err)
+	assert.WithinDuration(t, time.Now().Add(720*time.Hour), deleteAt, time.Minute)
+	require.Len(t, mail.messages, 1)
+	assert.Equal(t, user.Email, mail.messages[0].To)
+
+	// Asking again keeps the first date
+	again, err := service.RequestAccountDeletion(user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, deleteAt, again)
+
+	// Logged out everywhere, and the personal access tokens stop working
+	_, err = service.RefreshSession(result.Tokens.RefreshToken, "phone", "10.0.0.1")
+	assert.ErrorIs(t, err, ErrInvalidRefreshToken)
+	_, err = service.AuthenticatePersonalAccessToken(token)
+	assert.ErrorIs(t, err, ErrInvalidPersonalAccessToken)
+
+	pending, err := service.repo.GetUserByID(user.ID)
+	require.NoError(t, err)
+	assert.NotNil(t, pending.DeletionRequestedAt)
+
+	// Logging in again cancels the deletion
+	_, err = service.Login(user.Email, "password123", "10.0.0.1", "phone")
+	require.NoError(t, err)
+
+	kept, err := service.repo.GetUserByID(user.ID)
+	require.NoError(t, err)
+	assert.Nil(t, kept.DeletionRequestedAt)
+	_, err = service.AuthenticatePersonalAccessToken(token)
+	assert.NoError(t, err)
+
+	events, err := service.auditLog.GetUserEvents(user.ID, 1)
+	require.NoError(t, err)
+	assert.Equal(t, models.AuditDeletionCancelled, events[0].Type)
+
+	_, err = service.RequestAccountDeletion(user.ID + 1)
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+}
+
+func TestChangePassword(t *testing.T) {
+	service := setupTestService(t)
+	mail := service.mailer.(*fakeMailer)
+	user := createTestUser(t, service)
+
+	phone, err := service.Login(user.Email, "password123", "10.0.0.1", "phone")
+	require.NoError(t, err)
+	laptop, err := service.Login(user.Email, "password123", "10.0.0.2", "laptop")
+	require.NoError(t, err)
+	claims, err := service.ValidateToken(phone.Tokens.AccessToken)
+	require.NoError(t, err)
+	require.NoError(t, service.RequestPasswordReset(user.Email))
+	resetToken := mail.lastToken(t)
+
+	assert.ErrorIs(t, service.ChangePassword(claims, "wrong", "new-password"), ErrIncorrectPassword)
+	var validationError *validation.Error
+	assert.ErrorAs(t, service.ChangePassword(claims, "password123", "short"), &validationError)
+
+	require.NoError(t, service.ChangePassword(claims, "password123", "new-password"))
+	_, err = service.AuthenticateUser(user.Email, "new-password")
+	assert.NoError(t, err)
+	assert.Equal(t, "Your password was changed", mail.messages[len(mail.messages)-1].Subject)
+
+	// The device that changed it stays logged in, the others are logged out
+	revoked, err := service.IsTokenRevoked(claims)
+	require.NoError(t, err)
+	assert.False(t, revoked)
+	_, err = service.RefreshSession(laptop.Tokens.RefreshToken, "laptop", "10.0.0.2")
+	assert.ErrorIs(t, err, ErrInvalidRefreshToken)
+
+	assert.ErrorIs(t, service.ResetPassword(resetToken, "another-password"), ErrInvalidUserToken)
+
+	events, err := service.auditLog.GetUserEvents(user.ID, 1)
+	require.NoError(t, err)
+	assert.Equal(t, models.AuditPasswordChanged, events[0].Type)
+}
+
+func TestChangeEmail(t *testing.T) {
+	service := setupTestService(t)
+	mail := service.mailer.(*fakeMailer)
+	user := createTestUser(t, service)
+	require.NoError(t, service.repo.MarkEmailVerified(user.ID))
+	require.NoError(t, service.repo.CreateUser("taken@example.com", "password123"))
+
+	assert.ErrorIs(t, service.ChangeEmail(user.ID, "wrong", "new@example.com"), ErrIncorrectPassword)
+	assert.ErrorIs(t, service.ChangeEmail(user.ID, "password123", "taken@example.com"), ErrEmailTaken)
+	assert.Empty(t, mail.messages)
+
+	require.NoError(t, service.ChangeEmail(user.ID, "password123", "new@example.com"))
+
+	// The previous address is told, the new one gets a verification link
+	require.Len(t, mail.messages, 2)
+	assert.Equal(t, "test@example.com", mail.messages[0].To)
+	assert.Equal(t, "new@example.com", mail.messages[1].To)
+
+	changed, err := service.repo.GetUserByID(user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "new@example.com", changed.Email)
+	assert.Nil(t, changed.EmailVerifiedAt)
+
+	require.NoError(t, service.VerifyEmail(mail.lastToken(t)))
+	_, err = service.AuthenticateUser("new@example.com", "password123")
+	assert.NoError(t, err)
+	_, err = service.AuthenticateUser("test@example.com", "password123")
+	assert.Error(t, err)
+
+	events, err := service.auditLog.GetUserEvents(user.ID, 1)
+	require.NoError(t, err)
+	assert.Equal(t, models.AuditEmailChanged, events[0].Type)
+	assert.Equal(t, "test@example.com", events[0].Details["previous_email"])
+}
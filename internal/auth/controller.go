@@ -1,13 +1,20 @@
 package auth
 
 import (
+	"database/sql"
 	"encoding/json"
+	"errors"
 	"log/slog"
+	"math"
+	"net"
 	"net/http"
+	"strconv"
+	"time"
 
 	"go-sober/internal/constants"
 	"go-sober/internal/dtos"
 	"go-sober/internal/models"
+	"go-sober/internal/validation"
 )
 
 type Controller struct {
@@ -30,14 +37,13 @@ func NewController(service *Service) *Controller {
 // @Router /auth/signup [post]
 func (c *Controller) SignUp(w http.ResponseWriter, r *http.Request) {
 	var user dtos.UserSignupRequest
-	if err := json.NewDecoder(r.Body).Decode(&user); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+	if err := validation.DecodeJSON(r, &user); err != nil {
+		validation.WriteError(w, err)
 		return
 	}
 
-	// Basic validation
-	if user.Email == "" || user.Password == "" {
-		http.Error(w, "Email and password are required", http.StatusBadRequest)
+	if err := c.service.CheckPassword(user.Password, user.Email); err != nil {
+		validation.WriteError(w, err)
 		return
 	}
 
@@ -59,6 +65,13 @@ func (c *Controller) SignUp(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// The account is usable right away, a failed email can be sent again later
+	if createdUser, err := c.service.repo.GetUserByEmail(user.Email); err != nil {
+		slog.Error("Could not get created user", "error", err)
+	} else if err := c.service.SendVerificationEmail(createdUser); err != nil {
+		slog.Error("Could not send verification email", "error", err)
+	}
+
 	response := dtos.UserSignupResponse{
 		Message: "User created successfully",
 	}
@@ -69,7 +82,8 @@ func (c *Controller) SignUp(w http.ResponseWriter, r *http.Request) {
 }
 
 // @Summary Login a user
-// @Description Authenticate a user and generate a JWT token
+// @Description Authenticate a user and generate a JWT token. Repeated failures slow down then temporarily lock the account and the client address.
+// @Description With two-factor authentication, the response has no token but an MFA token to send with a code to /auth/login/mfa.
 // @Tags auth
 // @Accept json
 // @Produce json
@@ -77,30 +91,220 @@ func (c *Controller) SignUp(w http.ResponseWriter, r *http.Request) {
 // @Success 200 {object} dtos.UserLoginResponse
 // @Failure 400 {object} dtos.ClientError
 // @Failure 401 {object} dtos.ClientError
+// @Failure 429 {object} dtos.ClientError
 // @Router /auth/login [post]
 func (c *Controller) Login(w http.ResponseWriter, r *http.Request) {
 	var credentials dtos.UserLoginRequest
-	if err := json.NewDecoder(r.Body).Decode(&credentials); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+	if err := validation.DecodeJSON(r, &credentials); err != nil {
+		validation.WriteError(w, err)
+		return
+	}
+
+	result, err := c.service.Login(credentials.Email, credentials.Password, clientIP(r), r.UserAgent())
+	if err != nil {
+		writeLoginError(w, err)
 		return
 	}
 
-	user, err := c.service.AuthenticateUser(credentials.Email, credentials.Password)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(loginResponse(result))
+}
+
+// JWKS publishes the public keys verifying the access tokens, for other services. It is served
+// at /.well-known/jwks.json, outside of the API base path, so it is not in the Swagger docs.
+func (c *Controller) JWKS(w http.ResponseWriter, r *http.Request) {
+	keys, err := c.service.JWKS()
 	if err != nil {
-		http.Error(w, "Invalid credentials", http.StatusUnauthorized)
+		slog.Error("Could not get signing keys", "error", err)
+		http.Error(w, "Could not get signing keys", http.StatusInternalServerError)
+		return
+	}
+
+	// Verifiers fetch the keys again when a token has an unknown kid
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(dtos.JWKSResponse{Keys: keys})
+}
+
+// loginResponse holds the tokens, or the MFA challenge when a second factor is needed
+func loginResponse(result *models.LoginResult) dtos.UserLoginResponse {
+	if result.Tokens == nil {
+		return dtos.UserLoginResponse{
+			Message:     "MFA code required",
+			MFARequired: true,
+			MFAToken:    result.MFAToken,
+		}
+	}
+
+	return dtos.UserLoginResponse{
+		Message:      "Login successful",
+		Token:        result.Tokens.AccessToken,
+		RefreshToken: result.Tokens.RefreshToken,
+		ExpiresIn:    result.Tokens.ExpiresIn,
+	}
+}
+
+// @Summary Start an OIDC login
+// @Description Get the identity provider URL to send the user to, and a client key to keep on the client. The provider redirects back to the UI with a code and a state, to post to /auth/oidc/callback with the client key.
+// @Tags auth
+// @Produce json
+// @Success 200 {object} dtos.OIDCLoginResponse
+// @Failure 404 {object} dtos.ClientError
+// @Router /auth/oidc/login [get]
+func (c *Controller) StartOIDCLogin(w http.ResponseWriter, r *http.Request) {
+	authURL, clientKey, err := c.service.StartOIDCLogin()
+	if err != nil {
+		writeOIDCError(w, err)
+		return
+	}
+
+	response := dtos.OIDCLoginResponse{
+		AuthorizationURL: authURL,
+		ClientKey:        clientKey,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
+
+// @Summary Complete an OIDC login
+// @Description Exchange the code sent back by the identity provider for the tokens. The client key must be the one given to this client when the login started, so that a code obtained by someone else is rejected. The provider account is linked to the user with the same verified email, or to a new user.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body dtos.OIDCCallbackRequest true "OIDC callback request"
+// @Success 200 {object} dtos.UserLoginResponse
+// @Failure 400 {object} dtos.ClientError
+// @Failure 401 {object} dtos.ClientError
+// @Failure 403 {object} dtos.ClientError
+// @Failure 404 {object} dtos.ClientError
+// @Router /auth/oidc/callback [post]
+func (c *Controller) CompleteOIDCLogin(w http.ResponseWriter, r *http.Request) {
+	var req dtos.OIDCCallbackRequest
+	if err := validation.DecodeJSON(r, &req); err != nil {
+		validation.WriteError(w, err)
+		return
+	}
+
+	result, err := c.service.CompleteOIDCLogin(req.Code, req.State, req.ClientKey, clientIP(r), r.UserAgent())
+	if err != nil {
+		writeOIDCError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(loginResponse(result))
+}
+
+func writeOIDCError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, ErrOIDCDisabled):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, ErrInvalidOIDCState):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, ErrOIDCEmailNotVerified), errors.Is(err, ErrAccountDisabled):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	default:
+		// Provider errors and invalid ID tokens, the details are only logged
+		slog.Error("OIDC login failed", "error", err)
+		http.Error(w, "OIDC login failed", http.StatusUnauthorized)
+	}
+}
+
+// @Summary Complete a login with a second factor
+// @Description Exchange the MFA token returned by the login and a code of the authenticator app (or a recovery code) for the tokens
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body dtos.LoginMFARequest true "Login MFA request"
+// @Success 200 {object} dtos.UserLoginResponse
+// @Failure 400 {object} dtos.ClientError
+// @Failure 401 {object} dtos.ClientError
+// @Failure 429 {object} dtos.ClientError
+// @Router /auth/login/mfa [post]
+func (c *Controller) LoginMFA(w http.ResponseWriter, r *http.Request) {
+	var req dtos.LoginMFARequest
+	if err := validation.DecodeJSON(r, &req); err != nil {
+		validation.WriteError(w, err)
 		return
 	}
 
-	// Generate JWT token
-	token, err := c.service.GenerateToken(user)
+	tokens, err := c.service.CompleteMFALogin(req.MFAToken, req.Code, clientIP(r), r.UserAgent())
 	if err != nil {
-		http.Error(w, "Could not generate token", http.StatusInternalServerError)
+		writeLoginError(w, err)
 		return
 	}
 
 	response := dtos.UserLoginResponse{
-		Message: "Login successful",
-		Token:   token,
+		Message:      "Login successful",
+		Token:        tokens.AccessToken,
+		RefreshToken: tokens.RefreshToken,
+		ExpiresIn:    tokens.ExpiresIn,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
+
+// writeLoginError maps the errors of both login steps to a response
+func writeLoginError(w http.ResponseWriter, err error) {
+	var throttled *LoginThrottledError
+	switch {
+	case errors.As(err, &throttled):
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(throttled.RetryAfter.Seconds()))))
+		http.Error(w, "Too many failed logins", http.StatusTooManyRequests)
+	case errors.Is(err, ErrInvalidCredentials), errors.Is(err, ErrInvalidMFACode), errors.Is(err, ErrInvalidMFAChallenge):
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+	case errors.Is(err, ErrAccountDisabled):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	default:
+		slog.Error("Could not log in", "error", err)
+		http.Error(w, "Could not log in", http.StatusInternalServerError)
+	}
+}
+
+// @Summary Refresh the tokens
+// @Description Exchange a refresh token for a new access token and a new refresh token. A refresh token can only be used once, reusing it revokes the session.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param refresh body dtos.RefreshTokenRequest true "Refresh token request"
+// @Success 200 {object} dtos.RefreshTokenResponse
+// @Failure 400 {object} dtos.ClientError
+// @Failure 401 {object} dtos.ClientError
+// @Failure 403 {object} dtos.ClientError
+// @Router /auth/refresh [post]
+func (c *Controller) Refresh(w http.ResponseWriter, r *http.Request) {
+	var req dtos.RefreshTokenRequest
+	if err := validation.DecodeJSON(r, &req); err != nil {
+		validation.WriteError(w, err)
+		return
+	}
+
+	tokens, err := c.service.RefreshSession(req.RefreshToken, r.UserAgent(), clientIP(r))
+	if err != nil {
+		if errors.Is(err, ErrInvalidRefreshToken) || errors.Is(err, ErrRefreshTokenReused) {
+			http.Error(w, "Invalid refresh token", http.StatusUnauthorized)
+			return
+		}
+		if errors.Is(err, ErrAccountDisabled) {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
+		slog.Error("Could not refresh session", "error", err)
+		http.Error(w, "Could not refresh token", http.StatusInternalServerError)
+		return
+	}
+
+	response := dtos.RefreshTokenResponse{
+		Token:        tokens.AccessToken,
+		RefreshToken: tokens.RefreshToken,
+		ExpiresIn:    tokens.ExpiresIn,
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -122,9 +326,565 @@ func (c *Controller) Me(w http.ResponseWriter, r *http.Request) {
 	response := dtos.UserMeResponse{
 		UserID: claims.UserID,
 		Email:  claims.Email,
+		Role:   claims.Role,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
+
+// @Summary Log out
+// @Description Revoke the access token and the session (refresh token) of the current device
+// @Tags auth
+// @Produce json
+// @Param Authorization header string true "Bearer token"
+// @Success 200 {object} dtos.MessageResponse
+// @Failure 401 {object} dtos.ClientError
+// @Failure 500 {object} dtos.ClientError
+// @Router /auth/logout [post]
+func (c *Controller) Logout(w http.ResponseWriter, r *http.Request) {
+	claims := r.Context().Value(constants.UserContextKey).(*models.Claims)
+
+	if err := c.service.Logout(claims); err != nil {
+		slog.Error("Could not log out", "error", err)
+		http.Error(w, "Could not log out", http.StatusInternalServerError)
+		return
+	}
+
+	response := dtos.MessageResponse{
+		Message: "Logged out",
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
+
+// @Summary Log out all devices
+// @Description Revoke the access token and every session of the current user
+// @Tags auth
+// @Produce json
+// @Param Authorization header string true "Bearer token"
+// @Success 200 {object} dtos.MessageResponse
+// @Failure 401 {object} dtos.ClientError
+// @Failure 500 {object} dtos.ClientError
+// @Router /auth/logout-all [post]
+func (c *Controller) LogoutAll(w http.ResponseWriter, r *http.Request) {
+	claims := r.Context().Value(constants.UserContextKey).(*models.Claims)
+
+	if err := c.service.LogoutAll(claims); err != nil {
+		slog.Error("Could not log out all devices", "error", err)
+		http.Error(w, "Could not log out", http.StatusInternalServerError)
+		return
+	}
+
+	response := dtos.MessageResponse{
+		Message: "Logged out from all devices",
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
+
+// @Summary List the active sessions
+// @Description List the devices the current user is logged in on
+// @Tags auth
+// @Produce json
+// @Param Authorization header string true "Bearer token"
+// @Success 200 {object} dtos.SessionsResponse
+// @Failure 401 {object} dtos.ClientError
+// @Failure 500 {object} dtos.ClientError
+// @Router /auth/sessions [get]
+func (c *Controller) GetSessions(w http.ResponseWriter, r *http.Request) {
+	claims := r.Context().Value(constants.UserContextKey).(*models.Claims)
+
+	sessions, err := c.service.GetSessions(claims.UserID)
+	if err != nil {
+		slog.Error("Could not get sessions", "error", err)
+		http.Error(w, "Could not get sessions", http.StatusInternalServerError)
+		return
+	}
+
+	response := dtos.SessionsResponse{
+		Sessions: make([]dtos.SessionResponse, 0, len(sessions)),
+	}
+	for _, session := range sessions {
+		response.Sessions = append(response.Sessions, dtos.SessionResponse{
+			ID:           session.FamilyID,
+			UserAgent:    session.UserAgent,
+			IPAddress:    session.IPAddress,
+			SignedInAt:   session.SignedInAt,
+			LastActiveAt: session.LastActiveAt,
+			ExpiresAt:    session.ExpiresAt,
+			Current:      session.FamilyID == claims.SessionID,
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
+
+// @Summary Revoke a session
+// @Description Log out one of the devices of the current user
+// @Tags auth
+// @Param Authorization header string true "Bearer token"
+// @Param id path string true "Session ID"
+// @Success 204
+// @Failure 401 {object} dtos.ClientError
+// @Failure 404 {object} dtos.ClientError
+// @Failure 500 {object} dtos.ClientError
+// @Router /auth/sessions/{id} [delete]
+func (c *Controller) RevokeSession(w http.ResponseWriter, r *http.Request) {
+	claims := r.Context().Value(constants.UserContextKey).(*models.Claims)
+
+	if err := c.service.RevokeSession(claims.UserID, r.PathValue("id")); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			http.Error(w, "Session not found", http.StatusNotFound)
+			return
+		}
+		slog.Error("Could not revoke session", "error", err)
+		http.Error(w, "Could not revoke session", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// @Summary Create a personal access token
+// @Description Create a long-lived token for scripts and integrations, limited to some scopes.
+// @Description The token is only shown in this response. It cannot be used to manage the account.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Bearer token"
+// @Param request body dtos.CreatePersonalAccessTokenRequest true "Create personal access token request"
+// @Success 201 {object} dtos.CreatePersonalAccessTokenResponse
+// @Failure 400 {object} dtos.ClientError
+// @Failure 401 {object} dtos.ClientError
+// @Failure 409 {object} dtos.ClientError
+// @Failure 500 {object} dtos.ClientError
+// @Router /auth/tokens [post]
+func (c *Controller) CreatePersonalAccessToken(w http.ResponseWriter, r *http.Request) {
+	claims := r.Context().Value(constants.UserContextKey).(*models.Claims)
+
+	var req dtos.CreatePersonalAccessTokenRequest
+	if err := validation.DecodeJSON(r, &req); err != nil {
+		validation.WriteError(w, err)
+		return
+	}
+
+	expiresIn := time.Duration(req.ExpiresInDays) * 24 * time.Hour
+	token, pat, err := c.service.CreatePersonalAccessToken(claims.UserID, req.Name, req.Scopes, expiresIn)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrInvalidScope):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		case errors.Is(err, ErrTooManyPersonalAccessTokens):
+			http.Error(w, err.Error(), http.StatusConflict)
+		default:
+			slog.Error("Could not create personal access token", "error", err)
+			http.Error(w, "Could not create personal access token", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	response := dtos.CreatePersonalAccessTokenResponse{
+		Token:                       token,
+		PersonalAccessTokenResponse: personalAccessTokenResponse(pat),
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(response)
+}
+
+// @Summary List the personal access tokens
+// @Description List the personal access tokens of the current user that are not revoked
+// @Tags auth
+// @Produce json
+// @Param Authorization header string true "Bearer token"
+// @Success 200 {object} dtos.PersonalAccessTokensResponse
+// @Failure 401 {object} dtos.ClientError
+// @Failure 500 {object} dtos.ClientError
+// @Router /auth/tokens [get]
+func (c *Controller) GetPersonalAccessTokens(w http.ResponseWriter, r *http.Request) {
+	claims := r.Context().Value(constants.UserContextKey).(*models.Claims)
+
+	tokens, err := c.service.GetPersonalAccessTokens(claims.UserID)
+	if err != nil {
+		slog.Error("Could not get personal access tokens", "error", err)
+		http.Error(w, "Could not get personal access tokens", http.StatusInternalServerError)
+		return
+	}
+
+	response := dtos.PersonalAccessTokensResponse{
+		Tokens: make([]dtos.PersonalAccessTokenResponse, 0, len(tokens)),
+	}
+	for _, token := range tokens {
+		response.Tokens = append(response.Tokens, personalAccessTokenResponse(&token))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
+
+// @Summary Revoke a personal access token
+// @Description Revoke one of the personal access tokens of the current user
+// @Tags auth
+// @Param Authorization header string true "Bearer token"
+// @Param id path int true "Personal access token ID"
+// @Success 204
+// @Failure 400 {object} dtos.ClientError
+// @Failure 401 {object} dtos.ClientError
+// @Failure 404 {object} dtos.ClientError
+// @Failure 500 {object} dtos.ClientError
+// @Router /auth/tokens/{id} [delete]
+func (c *Controller) RevokePersonalAccessToken(w http.ResponseWriter, r *http.Request) {
+	claims := r.Context().Value(constants.UserContextKey).(*models.Claims)
+
+	tokenID, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid token ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := c.service.RevokePersonalAccessToken(claims.UserID, tokenID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			http.Error(w, "Personal access token not found", http.StatusNotFound)
+			return
+		}
+		slog.Error("Could not revoke personal access token", "error", err)
+		http.Error(w, "Could not revoke personal access token", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func personalAccessTokenResponse(token *models.PersonalAccessToken) dtos.PersonalAccessTokenResponse {
+	return dtos.PersonalAccessTokenResponse{
+		ID:         token.ID,
+		Name:       token.Name,
+		Prefix:     token.Prefix,
+		Scopes:     token.Scopes,
+		CreatedAt:  token.CreatedAt,
+		ExpiresAt:  token.ExpiresAt,
+		LastUsedAt: token.LastUsedAt,
+	}
+}
+
+// @Summary Verify an email address
+// @Description Confirm the email address of an account with the token sent by email
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body dtos.VerifyEmailRequest true "Verify email request"
+// @Success 200 {object} dtos.MessageResponse
+// @Failure 400 {object} dtos.ClientError
+// @Router /auth/verify-email [post]
+func (c *Controller) VerifyEmail(w http.ResponseWriter, r *http.Request) {
+	var req dtos.VerifyEmailRequest
+	if err := validation.DecodeJSON(r, &req); err != nil {
+		validation.WriteError(w, err)
+		return
+	}
+
+	if err := c.service.VerifyEmail(req.Token); err != nil {
+		if errors.Is(err, ErrInvalidUserToken) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		slog.Error("Could not verify email", "error", err)
+		http.Error(w, "Could not verify email", http.StatusInternalServerError)
+		return
+	}
+
+	response := dtos.MessageResponse{
+		Message: "Email verified",
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
+
+// @Summary Resend the verification email
+// @Description Send a new email verification link to the current user
+// @Tags auth
+// @Produce json
+// @Param Authorization header string true "Bearer token"
+// @Success 202 {object} dtos.MessageResponse
+// @Failure 401 {object} dtos.ClientError
+// @Failure 409 {object} dtos.ClientError
+// @Router /auth/verify-email/resend [post]
+func (c *Controller) ResendVerificationEmail(w http.ResponseWriter, r *http.Request) {
+	claims := r.Context().Value(constants.UserContextKey).(*models.Claims)
+
+	user, err := c.service.repo.GetUserByID(claims.UserID)
+	if err != nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+
+	if err := c.service.SendVerificationEmail(user); err != nil {
+		if errors.Is(err, ErrEmailAlreadyVerified) {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+		slog.Error("Could not send verification email", "error", err)
+		http.Error(w, "Could not send verification email", http.StatusInternalServerError)
+		return
+	}
+
+	response := dtos.MessageResponse{
+		Message: "Verification email sent",
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(response)
+}
+
+// @Summary Request a password reset
+// @Description Email a password reset link. The response is the same whether the account exists or not.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body dtos.ForgotPasswordRequest true "Forgot password request"
+// @Success 202 {object} dtos.MessageResponse
+// @Failure 400 {object} dtos.ClientError
+// @Failure 429 {object} dtos.ClientError
+// @Router /auth/password/forgot [post]
+func (c *Controller) ForgotPassword(w http.ResponseWriter, r *http.Request) {
+	var req dtos.ForgotPasswordRequest
+	if err := validation.DecodeJSON(r, &req); err != nil {
+		validation.WriteError(w, err)
+		return
+	}
+
+	if err := c.service.RequestPasswordReset(req.Email); err != nil {
+		slog.Error("Could not send password reset email", "error", err)
+	}
+
+	response := dtos.MessageResponse{
+		Message: "If an account exists for this email, a reset link has been sent",
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(response)
+}
+
+// @Summary Reset the password
+// @Description Set a new password with the token sent by email. Every device is logged out.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body dtos.ResetPasswordRequest true "Reset password request"
+// @Success 200 {object} dtos.MessageResponse
+// @Failure 400 {object} dtos.ClientError
+// @Router /auth/password/reset [post]
+func (c *Controller) ResetPassword(w http.ResponseWriter, r *http.Request) {
+	var req dtos.ResetPasswordRequest
+	if err := validation.DecodeJSON(r, &req); err != nil {
+		validation.WriteError(w, err)
+		return
+	}
+
+	if err := c.service.ResetPassword(req.Token, req.Password); err != nil {
+		var validationError *validation.Error
+		if errors.As(err, &validationError) {
+			validation.WriteError(w, err)
+			return
+		}
+		if errors.Is(err, ErrInvalidUserToken) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		slog.Error("Could not reset password", "error", err)
+		http.Error(w, "Could not reset password", http.StatusInternalServerError)
+		return
+	}
+
+	response := dtos.MessageResponse{
+		Message: "Password updated",
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
+
+// @Summary Start the TOTP enrolment
+// @Description Generate a TOTP secret for an authenticator app. Two-factor authentication is enabled once a first code is confirmed.
+// @Tags auth
+// @Produce json
+// @Param Authorization header string true "Bearer token"
+// @Success 200 {object} dtos.TOTPEnrolmentResponse
+// @Failure 401 {object} dtos.ClientError
+// @Failure 409 {object} dtos.ClientError
+// @Router /auth/mfa/totp [post]
+func (c *Controller) EnrolTOTP(w http.ResponseWriter, r *http.Request) {
+	user, ok := c.currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	enrolment, err := c.service.EnrolTOTP(user)
+	if err != nil {
+		writeMFAError(w, err)
+		return
+	}
+
+	response := dtos.TOTPEnrolmentResponse{
+		Secret:          enrolment.Secret,
+		ProvisioningURI: enrolment.ProvisioningURI,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
+
+// @Summary Enable two-factor authentication
+// @Description Confirm the TOTP enrolment with a first code, and get the recovery codes
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Bearer token"
+// @Param request body dtos.MFACodeRequest true "Code of the authenticator app"
+// @Success 200 {object} dtos.RecoveryCodesResponse
+// @Failure 400 {object} dtos.ClientError
+// @Failure 401 {object} dtos.ClientError
+// @Failure 409 {object} dtos.ClientError
+// @Router /auth/mfa/totp/confirm [post]
+func (c *Controller) ConfirmTOTP(w http.ResponseWriter, r *http.Request) {
+	user, ok := c.currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	var req dtos.MFACodeRequest
+	if err := validation.DecodeJSON(r, &req); err != nil {
+		validation.WriteError(w, err)
+		return
+	}
+
+	codes, err := c.service.ConfirmTOTP(user, req.Code)
+	if err != nil {
+		writeMFAError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(dtos.RecoveryCodesResponse{RecoveryCodes: codes})
+}
+
+// @Summary Disable two-factor authentication
+// @Description Remove the authenticator app and the recovery codes, with a current code or a recovery code
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Bearer token"
+// @Param request body dtos.MFACodeRequest true "Code of the authenticator app or recovery code"
+// @Success 200 {object} dtos.MessageResponse
+// @Failure 400 {object} dtos.ClientError
+// @Failure 401 {object} dtos.ClientError
+// @Router /auth/mfa/totp/disable [post]
+func (c *Controller) DisableTOTP(w http.ResponseWriter, r *http.Request) {
+	user, ok := c.currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	var req dtos.MFACodeRequest
+	if err := validation.DecodeJSON(r, &req); err != nil {
+		validation.WriteError(w, err)
+		return
+	}
+
+	if err := c.service.DisableTOTP(user, req.Code); err != nil {
+		writeMFAError(w, err)
+		return
+	}
+
+	response := dtos.MessageResponse{
+		Message: "Two-factor authentication disabled",
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(response)
 }
+
+// @Summary Regenerate the recovery codes
+// @Description Replace the recovery codes, with a current code or a recovery code. The previous codes stop working.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Bearer token"
+// @Param request body dtos.MFACodeRequest true "Code of the authenticator app or recovery code"
+// @Success 200 {object} dtos.RecoveryCodesResponse
+// @Failure 400 {object} dtos.ClientError
+// @Failure 401 {object} dtos.ClientError
+// @Router /auth/mfa/recovery-codes [post]
+func (c *Controller) RegenerateRecoveryCodes(w http.ResponseWriter, r *http.Request) {
+	user, ok := c.currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	var req dtos.MFACodeRequest
+	if err := validation.DecodeJSON(r, &req); err != nil {
+		validation.WriteError(w, err)
+		return
+	}
+
+	codes, err := c.service.RegenerateRecoveryCodes(user, req.Code)
+	if err != nil {
+		writeMFAError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(dtos.RecoveryCodesResponse{RecoveryCodes: codes})
+}
+
+// currentUser loads the user of the access token, it writes the response when it fails
+func (c *Controller) currentUser(w http.ResponseWriter, r *http.Request) (*models.User, bool) {
+	claims := r.Context().Value(constants.UserContextKey).(*models.Claims)
+
+	user, err := c.service.repo.GetUserByID(claims.UserID)
+	if err != nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return nil, false
+	}
+	return user, true
+}
+
+func writeMFAError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, ErrMFAAlreadyEnabled):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, ErrMFANotEnabled), errors.Is(err, ErrInvalidMFACode):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		slog.Error("Could not manage two-factor authentication", "error", err)
+		http.Error(w, "Could not manage two-factor authentication", http.StatusInternalServerError)
+	}
+}
+
+// clientIP returns the address of the client, without its port
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
@@ -0,0 +1,84 @@
+package auth
+
+import (
+	"fmt"
+	"net/url"
+	"time"
+
+	"go-sober/internal/mailer"
+)
+
+// Lifetimes of the tokens sent by email
+const (
+	emailVerificationTTL = 48 * time.Hour
+	passwordResetTTL     = time.Hour
+)
+
+func verificationEmail(to, baseURL, token string) mailer.Message {
+	link := fmt.Sprintf("%s/verify-email?token=%s", baseURL, url.QueryEscape(token))
+	return mailer.Message{
+		To:      to,
+		Subject: "Confirm your email address",
+		Body: fmt.Sprintf(`Welcome to Sober!
+
+Please confirm your email address by opening the link below:
+
+%s
+
+The link expires in %d hours. If you did not create an account, you can ignore this email.
+`, link, int(emailVerificationTTL.Hours())),
+	}
+}
+
+func passwordResetEmail(to, baseURL, token string) mailer.Message {
+	link := fmt.Sprintf("%s/reset-password?token=%s", baseURL, url.QueryEscape(token))
+	return mailer.Message{
+		To:      to,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf(`Someone asked to reset the password of your Sober account.
+
+Open the link below to choose a new password:
+
+%s
+
+The link expires in %d minutes and can be used once. If you did not ask for it, you can ignore this email.
+`, link, int(passwordResetTTL.Minutes())),
+	}
+}
+
+func accountDeletionEmail(to, baseURL string, deleteAt time.Time) mailer.Message {
+	return mailer.Message{
+		To:      to,
+		Subject: "Your account will be deleted",
+		Body: fmt.Sprintf(`You asked to delete your Sober account.
+
+Your account and all your data will be deleted for good on %s.
+
+Changed your mind? Log in before that date at %s to keep your account. If you did not ask for it,
+log in and change your password.
+`, deleteAt.Format("January 2, 2006 at 15:04 MST"), baseURL),
+	}
+}
+
+func passwordChangedEmail(to, baseURL string) mailer.Message {
+	return mailer.Message{
+		To:      to,
+		Subject: "Your password was changed",
+		Body: fmt.Sprintf(`The password of your Sober account was just changed, and your other devices were logged out.
+
+If you did not change it, reset your password right away from the login page at %s.
+`, baseURL),
+	}
+}
+
+func emailChangedEmail(to, newEmail string) mailer.Message {
+	return mailer.Message{
+		To:      to,
+		Subject: "Your email address was changed",
+		Body: fmt.Sprintf(`The email address of your Sober account was changed to %s.
+
+This address will not receive emails about your account anymore. If you did not make this
+change, contact us right away.
+`, newEmail),
+	}
+}
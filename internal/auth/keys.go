@@ -0,0 +1,325 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+
+	"go-sober/internal/dtos"
+	"go-sober/internal/models"
+	"go-sober/platform"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Algorithms signing the access tokens
+const (
+	AlgorithmHS256 = "HS256" // Shared secret, only this API can verify the tokens
+	AlgorithmRS256 = "RS256"
+	AlgorithmEdDSA = "EdDSA"
+)
+
+const (
+	rsaKeyBits = 2048
+	// Unknown kids reload the keys at most this often, so that forged tokens do not hammer the database
+	keyMissRefreshInterval = 5 * time.Second
+	// Keys a rotation leaves active: the new one, and the previous one for the tokens it signed
+	minActiveSigningKeys = 2
+)
+
+var errUnknownSigningKey = errors.New("unknown signing key")
+
+// NewSigningKey generates a key pair for an asymmetric algorithm
+func NewSigningKey(algorithm string) (*models.SigningKey, error) {
+	var private crypto.Signer
+	var err error
+	switch algorithm {
+	case AlgorithmRS256:
+		private, err = rsa.GenerateKey(rand.Reader, rsaKeyBits)
+	case AlgorithmEdDSA:
+		_, private, err = ed25519.GenerateKey(rand.Reader)
+	default:
+		return nil, fmt.Errorf("unsupported signing algorithm %q", algorithm)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not generate signing key: %w", err)
+	}
+
+	der, err := x509.MarshalPKCS8PrivateKey(private)
+	if err != nil {
+		return nil, fmt.Errorf("could not encode signing key: %w", err)
+	}
+
+	id := make([]byte, 12)
+	if _, err := rand.Read(id); err != nil {
+		return nil, fmt.Errorf("could not generate key ID: %w", err)
+	}
+
+	return &models.SigningKey{
+		ID:         base64.RawURLEncoding.EncodeToString(id),
+		Algorithm:  algorithm,
+		PrivateKey: string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})),
+		CreatedAt:  time.Now().UTC(),
+	}, nil
+}
+
+// RotateSigningKeys adds a key, which signs the new tokens once the API reloads its keys, and
+// retires the oldest keys so that at most keep keys stay active. It returns the new key and the
+// retired key IDs.
+func RotateSigningKeys(repo *Repository, algorithm string, keep int) (*models.SigningKey, []string, error) {
+	if keep < minActiveSigningKeys {
+		return nil, nil, fmt.Errorf("at least %d keys must stay active, for the tokens signed with the previous key", minActiveSigningKeys)
+	}
+
+	key, err := NewSigningKey(algorithm)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := repo.CreateSigningKey(key); err != nil {
+		return nil, nil, err
+	}
+
+	active, err := repo.GetSigningKeys(false)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var retired []string
+	for i := keep; i < len(active); i++ {
+		if err := repo.RetireSigningKey(active[i].ID); err != nil {
+			return nil, nil, err
+		}
+		retired = append(retired, active[i].ID)
+	}
+	return key, retired, nil
+}
+
+// signingKey is a parsed key pair of the database
+type signingKey struct {
+	id      string
+	method  jwt.SigningMethod
+	private crypto.Signer
+}
+
+func parseSigningKey(key models.SigningKey) (*signingKey, error) {
+	block, _ := pem.Decode([]byte(key.PrivateKey))
+	if block == nil {
+		return nil, fmt.Errorf("signing key %s is not PEM encoded", key.ID)
+	}
+	private, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse signing key %s: %w", key.ID, err)
+	}
+
+	switch private := private.(type) {
+	case *rsa.PrivateKey:
+		if key.Algorithm == AlgorithmRS256 {
+			return &signingKey{id: key.ID, method: jwt.SigningMethodRS256, private: private}, nil
+		}
+	case ed25519.PrivateKey:
+		if key.Algorithm == AlgorithmEdDSA {
+			return &signingKey{id: key.ID, method: jwt.SigningMethodEdDSA, private: private}, nil
+		}
+	}
+	return nil, fmt.Errorf("signing key %s does not match its algorithm %s", key.ID, key.Algorithm)
+}
+
+// jwk is the public half of the key, as published in the JWKS
+func (k *signingKey) jwk() dtos.JSONWebKey {
+	key := dtos.JSONWebKey{Kid: k.id, Use: "sig", Alg: k.method.Alg()}
+	switch public := k.private.Public().(type) {
+	case *rsa.PublicKey:
+		key.Kty = "RSA"
+		key.N = base64.RawURLEncoding.EncodeToString(public.N.Bytes())
+		key.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes())
+	case ed25519.PublicKey:
+		key.Kty = "OKP"
+		key.Crv = "Ed25519"
+		key.X = base64.RawURLEncoding.EncodeToString(public)
+	}
+	return key
+}
+
+// keySet signs and verifies the tokens of the API. With HS256 it uses the secret, otherwise the
+// newest active key of the algorithm signs and every active key verifies, so that rotating keys
+// does not log anyone out. Keys are reloaded from the database now and then, to pick up rotations.
+type keySet struct {
+	repo      *Repository
+	algorithm string
+	secret    []byte // Verifies HS256 tokens when set, also after switching algorithm
+	refresh   time.Duration
+
+	mu       sync.Mutex
+	keys     map[string]*signingKey
+	ordered  []*signingKey // Newest first
+	current  *signingKey   // nil with HS256
+	loadedAt time.Time
+}
+
+func newKeySet(repo *Repository, config platform.AuthConfig) (*keySet, error) {
+	k := &keySet{
+		repo:      repo,
+		algorithm: config.JWT.Algorithm,
+		secret:    []byte(config.JWT.Secret),
+		refresh:   config.JWT.KeyRefreshInterval,
+		keys:      map[string]*signingKey{},
+	}
+
+	switch k.algorithm {
+	case AlgorithmHS256:
+		if len(k.secret) == 0 {
+			return nil, errors.New("JWT_SECRET is required with the HS256 algorithm")
+		}
+		return k, nil
+	case AlgorithmRS256, AlgorithmEdDSA:
+	default:
+		return nil, fmt.Errorf("unsupported JWT algorithm %q", k.algorithm)
+	}
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if err := k.load(); err != nil {
+		return nil, err
+	}
+	if k.current == nil {
+		// First start with this algorithm
+		key, err := NewSigningKey(k.algorithm)
+		if err != nil {
+			return nil, err
+		}
+		if err := repo.CreateSigningKey(key); err != nil {
+			return nil, err
+		}
+		if err := k.load(); err != nil {
+			return nil, err
+		}
+	}
+	return k, nil
+}
+
+// load reads the active keys, the caller holds the lock
+func (k *keySet) load() error {
+	stored, err := k.repo.GetSigningKeys(false)
+	if err != nil {
+		return err
+	}
+
+	keys := make(map[string]*signingKey, len(stored))
+	ordered := make([]*signingKey, 0, len(stored))
+	var current *signingKey
+	for _, key := range stored {
+		parsed, err := parseSigningKey(key)
+		if err != nil {
+			return err
+		}
+		keys[parsed.id] = parsed
+		ordered = append(ordered, parsed)
+		if current == nil && key.Algorithm == k.algorithm {
+			current = parsed
+		}
+	}
+
+	k.keys = keys
+	k.ordered = ordered
+	k.current = current
+	k.loadedAt = time.Now()
+	return nil
+}
+
+// reloadIfStale reloads the keys when they are older than maxAge, the caller holds the lock
+func (k *keySet) reloadIfStale(maxAge time.Duration) error {
+	if time.Since(k.loadedAt) < maxAge {
+		return nil
+	}
+	return k.load()
+}
+
+// sign signs claims with the current key
+func (k *keySet) sign(claims jwt.Claims) (string, error) {
+	if k.algorithm == AlgorithmHS256 {
+		return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(k.secret)
+	}
+
+	k.mu.Lock()
+	if err := k.reloadIfStale(k.refresh); err != nil {
+		k.mu.Unlock()
+		return "", err
+	}
+	current := k.current
+	k.mu.Unlock()
+
+	if current == nil {
+		return "", fmt.Errorf("no active %s signing key", k.algorithm)
+	}
+
+	token := jwt.NewWithClaims(current.method, claims)
+	token.Header["kid"] = current.id
+	return token.SignedString(current.private)
+}
+
+// parse verifies a token signed by the API
+func (k *keySet) parse(tokenString string, claims jwt.Claims, options ...jwt.ParserOption) (*jwt.Token, error) {
+	methods := []string{jwt.SigningMethodRS256.Alg(), jwt.SigningMethodEdDSA.Alg()}
+	if len(k.secret) > 0 {
+		methods = append(methods, jwt.SigningMethodHS256.Alg())
+	}
+	options = append(options, jwt.WithValidMethods(methods))
+	return jwt.ParseWithClaims(tokenString, claims, k.verificationKey, options...)
+}
+
+func (k *keySet) verificationKey(token *jwt.Token) (interface{}, error) {
+	if _, ok := token.Method.(*jwt.SigningMethodHMAC); ok {
+		return k.secret, nil
+	}
+
+	kid, _ := token.Header["kid"].(string)
+	if kid == "" {
+		return nil, errUnknownSigningKey
+	}
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if err := k.reloadIfStale(k.refresh); err != nil {
+		return nil, err
+	}
+	key, ok := k.keys[kid]
+	if !ok {
+		// Maybe rotated since the last load
+		if err := k.reloadIfStale(keyMissRefreshInterval); err != nil {
+			return nil, err
+		}
+		if key, ok = k.keys[kid]; !ok {
+			return nil, errUnknownSigningKey
+		}
+	}
+
+	// The algorithm of the token must be the one of the key
+	if key.method.Alg() != token.Method.Alg() {
+		return nil, errUnknownSigningKey
+	}
+	return key.private.Public(), nil
+}
+
+// publicKeys returns the active public keys, to publish as a JWKS
+func (k *keySet) publicKeys() ([]dtos.JSONWebKey, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if err := k.reloadIfStale(k.refresh); err != nil {
+		return nil, err
+	}
+
+	keys := make([]dtos.JSONWebKey, 0, len(k.ordered))
+	for _, key := range k.ordered {
+		keys = append(keys, key.jwk())
+	}
+	return keys, nil
+}
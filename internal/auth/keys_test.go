@@ -0,0 +1,142 @@
+package auth
+
+import (
+	"testing"
+	"time"
+
+	"go-sober/internal/models"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupKeyService(t *testing.T, algorithm string) *Service {
+	config := testConfig()
+	config.Auth.JWT.Algorithm = algorithm
+	config.Auth.JWT.Secret = ""
+	return setupTestServiceWithConfig(t, config)
+}
+
+func TestAsymmetricTokens(t *testing.T) {
+	for _, algorithm := range []string{AlgorithmRS256, AlgorithmEdDSA} {
+		t.Run(algorithm, func(t *testing.T) {
+			service := setupKeyService(t, algorithm)
+			user := &models.User{ID: 1, Email: "test@example.com"}
+
+			token, err := service.GenerateToken(user, "family")
+			require.NoError(t, err)
+
+			parsed, _, err := jwt.NewParser().ParseUnverified(token, &models.Claims{})
+			require.NoError(t, err)
+			assert.Equal(t, algorithm, parsed.Method.Alg())
+
+			keys, err := service.JWKS()
+			require.NoError(t, err)
+			require.Len(t, keys, 1)
+			assert.Equal(t, keys[0].Kid, parsed.Header["kid"])
+			assert.Equal(t, algorithm, keys[0].Alg)
+
+			claims, err := service.ValidateToken(token)
+			require.NoError(t, err)
+			assert.Equal(t, user.ID, claims.UserID)
+		})
+	}
+}
+
+func TestSigningKeyRotation(t *testing.T) {
+	service := setupKeyService(t, AlgorithmRS256)
+	user := &models.User{ID: 1, Email: "test@example.com"}
+
+	oldToken, err := service.GenerateToken(user, "family")
+	require.NoError(t, err)
+
+	_, _, err = RotateSigningKeys(service.repo, AlgorithmRS256, 1)
+	assert.Error(t, err)
+
+	newKey, retired, err := RotateSigningKeys(service.repo, AlgorithmRS256, 2)
+	require.NoError(t, err)
+	assert.Empty(t, retired)
+
+	// A token of an API instance that already uses the new key reloads the keys
+	service.keys.loadedAt = time.Now().Add(-keyMissRefreshInterval)
+	rotated := jwt.NewWithClaims(jwt.SigningMethodRS256, &models.Claims{UserID: 1, SessionID: "family", RegisteredClaims: jwt.RegisteredClaims{ID: "jti"}})
+	rotated.Header["kid"] = newKey.ID
+	signingKey, err := parseSigningKey(*newKey)
+	require.NoError(t, err)
+	rotatedToken, err := rotated.SignedString(signingKey.private)
+	require.NoError(t, err)
+	_, err = service.ValidateToken(rotatedToken)
+	require.NoError(t, err)
+
+	// The new key signs once the keys are reloaded, and the old tokens stay valid
+	service.keys.loadedAt = time.Time{}
+	newToken, err := service.GenerateToken(user, "family")
+	require.NoError(t, err)
+	parsed, _, err := jwt.NewParser().ParseUnverified(newToken, &models.Claims{})
+	require.NoError(t, err)
+	assert.Equal(t, newKey.ID, parsed.Header["kid"])
+
+	_, err = service.ValidateToken(oldToken)
+	require.NoError(t, err)
+
+	keys, err := service.JWKS()
+	require.NoError(t, err)
+	assert.Len(t, keys, 2)
+	assert.Equal(t, newKey.ID, keys[0].Kid)
+
+	// The next rotation retires the first key, and its tokens
+	_, retired, err = RotateSigningKeys(service.repo, AlgorithmRS256, 2)
+	require.NoError(t, err)
+	assert.Len(t, retired, 1)
+
+	service.keys.loadedAt = time.Time{}
+	_, err = service.ValidateToken(oldToken)
+	assert.Error(t, err)
+	_, err = service.ValidateToken(newToken)
+	assert.NoError(t, err)
+}
+
+func TestLegacySecretAfterSwitch(t *testing.T) {
+	legacy := setupTestService(t)
+	user := &models.User{ID: 1, Email: "test@example.com"}
+	hmacToken, err := legacy.GenerateToken(user, "family")
+	require.NoError(t, err)
+
+	config := testConfig()
+	config.Auth.JWT.Algorithm = AlgorithmEdDSA
+	service := setupTestServiceWithConfig(t, config)
+
+	// Tokens of the secret stay valid while it is configured
+	_, err = service.ValidateToken(hmacToken)
+	assert.NoError(t, err)
+
+	withoutSecret := setupKeyService(t, AlgorithmEdDSA)
+	_, err = withoutSecret.ValidateToken(hmacToken)
+	assert.Error(t, err)
+
+	// An RSA token cannot pass as an EdDSA one with the same kid
+	rsaKey, err := NewSigningKey(AlgorithmRS256)
+	require.NoError(t, err)
+	parsedKey, err := parseSigningKey(*rsaKey)
+	require.NoError(t, err)
+	keys, err := withoutSecret.JWKS()
+	require.NoError(t, err)
+	confused := jwt.NewWithClaims(jwt.SigningMethodRS256, &models.Claims{UserID: 1, SessionID: "family", RegisteredClaims: jwt.RegisteredClaims{ID: "jti"}})
+	confused.Header["kid"] = keys[0].Kid
+	confusedToken, err := confused.SignedString(parsedKey.private)
+	require.NoError(t, err)
+	_, err = withoutSecret.ValidateToken(confusedToken)
+	assert.Error(t, err)
+}
+
+func TestNewKeySetConfig(t *testing.T) {
+	config := testConfig()
+	config.Auth.JWT.Secret = ""
+	_, err := newKeySet(setupTestDB(t), config.Auth)
+	assert.Error(t, err, "HS256 needs a secret")
+
+	config.Auth.JWT.Algorithm = "none"
+	_, err = newKeySet(setupTestDB(t), config.Auth)
+	assert.Error(t, err)
+}
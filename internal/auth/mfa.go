@@ -0,0 +1,285 @@
+package auth
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"go-sober/internal/models"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+var (
+	ErrMFAAlreadyEnabled   = errors.New("two-factor authentication already enabled")
+	ErrMFANotEnabled       = errors.New("two-factor authentication not enabled")
+	ErrInvalidMFACode      = errors.New("invalid two-factor code")
+	ErrInvalidMFAChallenge = errors.New("invalid or expired MFA challenge")
+)
+
+const (
+	recoveryCodeCount = 10
+	// Audience of MFA challenge tokens, so that they cannot be mistaken for access tokens
+	mfaChallengeAudience = "mfa-challenge"
+)
+
+// Recovery codes avoid characters that are easy to confuse (0/o, 1/l/i)
+var recoveryCodeAlphabet = []byte("abcdefghjkmnpqrstuvwxyz23456789")
+
+// mfaChallengeClaims identify a user who passed the password step of a login
+type mfaChallengeClaims struct {
+	UserID int64  `json:"user_id"`
+	Email  string `json:"email"`
+	jwt.RegisteredClaims
+}
+
+// EnrolTOTP starts the enrolment of an authenticator app. The factor is enabled once
+// ConfirmTOTP receives a first valid code.
+func (s *Service) EnrolTOTP(user *models.User) (*models.TOTPEnrolment, error) {
+	enabled, err := s.mfaEnabled(user.ID)
+	if err != nil {
+		return nil, err
+	}
+	if enabled {
+		return nil, ErrMFAAlreadyEnabled
+	}
+
+	secret, err := newTOTPSecret()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.SaveTOTPSecret(user.ID, secret); err != nil {
+		return nil, err
+	}
+
+	return &models.TOTPEnrolment{
+		Secret:          secret,
+		ProvisioningURI: totpProvisioningURI(s.config.Auth.MFA.Issuer, user.Email, secret),
+	}, nil
+}
+
+// ConfirmTOTP enables a pending factor and returns its recovery codes, which are not stored in clear
+func (s *Service) ConfirmTOTP(user *models.User, code string) ([]string, error) {
+	factor, err := s.repo.GetTOTPFactor(user.ID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrMFANotEnabled
+	}
+	if err != nil {
+		return nil, err
+	}
+	if factor.EnabledAt != nil {
+		return nil, ErrMFAAlreadyEnabled
+	}
+
+	step, ok := validateTOTP(factor.Secret, code, time.Now())
+	if !ok {
+		return nil, ErrInvalidMFACode
+	}
+
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.EnableTOTP(user.ID, step, hashes); err != nil {
+		return nil, err
+	}
+
+	s.recordAuditEvent(models.AuditMFAEnabled, &user.ID, user.Email, "", "", nil)
+	return codes, nil
+}
+
+// DisableTOTP removes the factor, with a current code or a recovery code
+func (s *Service) DisableTOTP(user *models.User, code string) error {
+	factor, err := s.enabledTOTPFactor(user.ID)
+	if err != nil {
+		return err
+	}
+	if err := s.verifySecondFactor(factor, code); err != nil {
+		return err
+	}
+
+	if err := s.repo.DeleteTOTP(user.ID); err != nil {
+		return err
+	}
+
+	s.recordAuditEvent(models.AuditMFADisabled, &user.ID, user.Email, "", "", nil)
+	return nil
+}
+
+// RegenerateRecoveryCodes replaces the recovery codes, with a current code or a recovery code
+func (s *Service) RegenerateRecoveryCodes(user *models.User, code string) ([]string, error) {
+	factor, err := s.enabledTOTPFactor(user.ID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.verifySecondFactor(factor, code); err != nil {
+		return nil, err
+	}
+
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.ReplaceRecoveryCodes(user.ID, hashes); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// CompleteMFALogin is the second step of a login: it checks the code for an MFA challenge
+// token returned by Login, then starts a session. Failures count as failed logins.
+func (s *Service) CompleteMFALogin(mfaToken, code, ipAddress, userAgent string) (*models.TokenPair, error) {
+	challenge, err := s.parseMFAChallenge(mfaToken)
+	if err != nil {
+		return nil, ErrInvalidMFAChallenge
+	}
+
+	accountKey := loginAccountKey(challenge.Email)
+	accountLimits, _ := s.loginLimits()
+	if wait := s.logins.wait(accountKey, accountLimits); wait > 0 {
+		s.recordAuditEvent(models.AuditMFAFailed, &challenge.UserID, challenge.Email, ipAddress, userAgent, map[string]string{"reason": "throttled"})
+		return nil, &LoginThrottledError{RetryAfter: wait}
+	}
+
+	factor, err := s.enabledTOTPFactor(challenge.UserID)
+	if errors.Is(err, ErrMFANotEnabled) {
+		return nil, ErrInvalidMFAChallenge
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.verifySecondFactor(factor, code); err != nil {
+		if !errors.Is(err, ErrInvalidMFACode) {
+			return nil, err
+		}
+
+		accountFailures := s.logins.fail(accountKey)
+		s.recordAuditEvent(models.AuditMFAFailed, &challenge.UserID, challenge.Email, ipAddress, userAgent, map[string]string{
+			"reason":           "invalid_code",
+			"account_failures": strconv.Itoa(accountFailures),
+		})
+		s.recordLockout(accountFailures, &challenge.UserID, challenge.Email, ipAddress, userAgent)
+		return nil, err
+	}
+
+	s.logins.reset(accountKey)
+
+	user, err := s.repo.GetUserByID(challenge.UserID)
+	if err != nil {
+		return nil, err
+	}
+	return s.CreateSession(user, userAgent, ipAddress)
+}
+
+func (s *Service) mfaEnabled(userID int64) (bool, error) {
+	_, err := s.enabledTOTPFactor(userID)
+	if errors.Is(err, ErrMFANotEnabled) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// enabledTOTPFactor returns ErrMFANotEnabled when the user has no factor or a pending one
+func (s *Service) enabledTOTPFactor(userID int64) (*models.TOTPFactor, error) {
+	factor, err := s.repo.GetTOTPFactor(userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrMFANotEnabled
+	}
+	if err != nil {
+		return nil, err
+	}
+	if factor.EnabledAt == nil {
+		return nil, ErrMFANotEnabled
+	}
+	return factor, nil
+}
+
+// verifySecondFactor accepts a TOTP code or a recovery code, each one once
+func (s *Service) verifySecondFactor(factor *models.TOTPFactor, code string) error {
+	if step, ok := validateTOTP(factor.Secret, code, time.Now()); ok {
+		err := s.repo.UseTOTPStep(factor.UserID, step)
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrInvalidMFACode
+		}
+		return err
+	}
+
+	err := s.repo.UseRecoveryCode(factor.UserID, hashToken(normalizeRecoveryCode(code)))
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrInvalidMFACode
+	}
+	return err
+}
+
+func (s *Service) newMFAChallenge(user *models.User) (string, error) {
+	claims := &mfaChallengeClaims{
+		UserID: user.ID,
+		Email:  user.Email,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        uuid.NewString(),
+			Audience:  jwt.ClaimStrings{mfaChallengeAudience},
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(s.config.Auth.MFA.ChallengeTTL)),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+		},
+	}
+
+	return s.keys.sign(claims)
+}
+
+func (s *Service) parseMFAChallenge(tokenString string) (*mfaChallengeClaims, error) {
+	token, err := s.keys.parse(tokenString, &mfaChallengeClaims{}, jwt.WithAudience(mfaChallengeAudience))
+	if err != nil {
+		return nil, err
+	}
+
+	claims, ok := token.Claims.(*mfaChallengeClaims)
+	if !ok || !token.Valid || claims.UserID == 0 {
+		return nil, errors.New("invalid MFA challenge")
+	}
+	return claims, nil
+}
+
+// newRecoveryCodes returns recovery codes such as "k7qmz-3hxp2" and their hashes
+func newRecoveryCodes() (codes []string, hashes []string, err error) {
+	for i := 0; i < recoveryCodeCount; i++ {
+		b, err := randomRecoveryCharacters(10)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		code := string(b[:5]) + "-" + string(b[5:])
+		codes = append(codes, code)
+		hashes = append(hashes, hashToken(normalizeRecoveryCode(code)))
+	}
+	return codes, hashes, nil
+}
+
+// randomRecoveryCharacters draws characters uniformly from the recovery code alphabet
+func randomRecoveryCharacters(n int) ([]byte, error) {
+	// Bytes above the largest multiple of the alphabet size would favour the first characters
+	limit := 256 - 256%len(recoveryCodeAlphabet)
+
+	characters := make([]byte, 0, n)
+	b := make([]byte, 1)
+	for len(characters) < n {
+		if _, err := rand.Read(b); err != nil {
+			return nil, fmt.Errorf("could not generate recovery code: %w", err)
+		}
+		if int(b[0]) < limit {
+			characters = append(characters, recoveryCodeAlphabet[int(b[0])%len(recoveryCodeAlphabet)])
+		}
+	}
+	return characters, nil
+}
+
+// normalizeRecoveryCode lets users type a recovery code without the dash or in upper case
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(strings.TrimSpace(code))
+	return strings.NewReplacer("-", "", " ", "").Replace(code)
+}
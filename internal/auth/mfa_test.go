@@ -0,0 +1,92 @@
+package auth
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// totpCodeAt is what the authenticator app shows at a step
+func totpCodeAt(t *testing.T, secret string, step int64) string {
+	t.Helper()
+	rawSecret, err := totpEncoding.DecodeString(secret)
+	require.NoError(t, err)
+	return totpCode(rawSecret, step, totpDigits)
+}
+
+func TestMFALogin(t *testing.T) {
+	service := setupTestService(t)
+	user := createTestUser(t, service)
+
+	enrolment, err := service.EnrolTOTP(user)
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(enrolment.ProvisioningURI, "otpauth://totp/Sober:test@example.com?"))
+
+	// A pending enrolment does not change the login
+	result, err := service.Login(user.Email, "password123", "10.0.0.1", "phone")
+	require.NoError(t, err)
+	assert.NotNil(t, result.Tokens)
+
+	_, err = service.ConfirmTOTP(user, "000000")
+	assert.ErrorIs(t, err, ErrInvalidMFACode)
+
+	confirmationCode := totpCodeAt(t, enrolment.Secret, totpStep(time.Now())-1)
+	recoveryCodes, err := service.ConfirmTOTP(user, confirmationCode)
+	require.NoError(t, err)
+	assert.Len(t, recoveryCodes, recoveryCodeCount)
+
+	_, err = service.EnrolTOTP(user)
+	assert.ErrorIs(t, err, ErrMFAAlreadyEnabled)
+
+	// The password step now returns a challenge instead of tokens
+	result, err = service.Login(user.Email, "password123", "10.0.0.1", "phone")
+	require.NoError(t, err)
+	assert.Nil(t, result.Tokens)
+	require.NotEmpty(t, result.MFAToken)
+
+	// The challenge is not an access token
+	_, err = service.ValidateToken(result.MFAToken)
+	assert.Error(t, err)
+
+	// The code used for the confirmation cannot be replayed
+	_, err = service.CompleteMFALogin(result.MFAToken, confirmationCode, "10.0.0.1", "phone")
+	assert.ErrorIs(t, err, ErrInvalidMFACode)
+
+	tokens, err := service.CompleteMFALogin(result.MFAToken, totpCodeAt(t, enrolment.Secret, totpStep(time.Now())), "10.0.0.1", "phone")
+	require.NoError(t, err)
+	claims, err := service.ValidateToken(tokens.AccessToken)
+	require.NoError(t, err)
+	assert.Equal(t, user.ID, claims.UserID)
+
+	// Recovery codes work once, with or without the dash
+	recoveryCode := strings.ToUpper(strings.ReplaceAll(recoveryCodes[0], "-", ""))
+	_, err = service.CompleteMFALogin(result.MFAToken, recoveryCode, "10.0.0.1", "phone")
+	require.NoError(t, err)
+	_, err = service.CompleteMFALogin(result.MFAToken, recoveryCodes[0], "10.0.0.1", "phone")
+	assert.ErrorIs(t, err, ErrInvalidMFACode)
+
+	_, err = service.CompleteMFALogin("not-a-token", recoveryCodes[1], "10.0.0.1", "phone")
+	assert.ErrorIs(t, err, ErrInvalidMFAChallenge)
+
+	require.NoError(t, service.DisableTOTP(user, recoveryCodes[1]))
+	result, err = service.Login(user.Email, "password123", "10.0.0.1", "phone")
+	require.NoError(t, err)
+	assert.NotNil(t, result.Tokens)
+}
+
+func TestNewRecoveryCodes(t *testing.T) {
+	codes, hashes, err := newRecoveryCodes()
+	require.NoError(t, err)
+	require.Len(t, codes, recoveryCodeCount)
+
+	seen := make(map[string]bool)
+	for i, code := range codes {
+		assert.Regexp(t, `^[a-z2-9]{5}-[a-z2-9]{5}$`, code)
+		assert.Equal(t, hashToken(normalizeRecoveryCode(code)), hashes[i])
+		assert.False(t, seen[code])
+		seen[code] = true
+	}
+}
@@ -0,0 +1,145 @@
+package auth
+
+import (
+	"crypto/subtle"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"go-sober/internal/models"
+)
+
+var (
+	ErrOIDCDisabled         = errors.New("OIDC login is not configured")
+	ErrInvalidOIDCState     = errors.New("invalid or expired OIDC login")
+	ErrOIDCEmailNotVerified = errors.New("the identity provider did not verify the email address")
+)
+
+// StartOIDCLogin returns the provider URL where the user logs in, and a client key for the
+// client to keep and send back with the code. The key ties the login to the client that
+// started it: a code and state obtained by someone else cannot log the user into their
+// account. The state, nonce and PKCE verifier are kept until the provider redirects back.
+func (s *Service) StartOIDCLogin() (authURL string, clientKey string, err error) {
+	if s.oidc == nil {
+		return "", "", ErrOIDCDisabled
+	}
+
+	state, err := newOpaqueToken()
+	if err != nil {
+		return "", "", err
+	}
+	clientKey, err = newOpaqueToken()
+	if err != nil {
+		return "", "", err
+	}
+	nonce, err := newOpaqueToken()
+	if err != nil {
+		return "", "", err
+	}
+	codeVerifier, err := newOpaqueToken()
+	if err != nil {
+		return "", "", err
+	}
+
+	authURL, err = s.oidc.authCodeURL(state, nonce, codeVerifier)
+	if err != nil {
+		return "", "", err
+	}
+
+	expiresAt := time.Now().Add(s.config.Auth.OIDC.LoginTTL)
+	if err := s.repo.SaveOIDCLoginState(hashToken(state), hashToken(clientKey), nonce, codeVerifier, expiresAt); err != nil {
+		return "", "", err
+	}
+	return authURL, clientKey, nil
+}
+
+// CompleteOIDCLogin exchanges the code the provider sent back for an ID token, then logs
+// in the user linked to the provider account. Unknown accounts are linked to the user
+// with the same email, or to a new user, when the provider verified the email.
+// The client key must be the one StartOIDCLogin gave for this state.
+func (s *Service) CompleteOIDCLogin(code, state, clientKey, ipAddress, userAgent string) (*models.LoginResult, error) {
+	if s.oidc == nil {
+		return nil, ErrOIDCDisabled
+	}
+
+	clientKeyHash, nonce, codeVerifier, err := s.repo.ConsumeOIDCLoginState(hashToken(state))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrInvalidOIDCState
+	}
+	if err != nil {
+		return nil, err
+	}
+	if subtle.ConstantTimeCompare([]byte(hashToken(clientKey)), []byte(clientKeyHash)) != 1 {
+		return nil, ErrInvalidOIDCState
+	}
+
+	rawIDToken, err := s.oidc.exchange(code, codeVerifier)
+	if err != nil {
+		return nil, err
+	}
+	claims, err := s.oidc.verifyIDToken(rawIDToken, nonce)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.oidcUser(claims)
+	if err != nil {
+		return nil, err
+	}
+	return s.startLogin(user, ipAddress, userAgent)
+}
+
+// oidcUser finds or creates the user of a provider account
+func (s *Service) oidcUser(claims *oidcIDClaims) (*models.User, error) {
+	identity, err := s.repo.GetUserIdentity(claims.Issuer, claims.Subject)
+	if err == nil {
+		if err := s.repo.TouchUserIdentity(identity.ID, claims.Email); err != nil {
+			return nil, err
+		}
+		return s.repo.GetUserByID(identity.UserID)
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+
+	// Linking on an unverified email would let anyone take over the account with that email
+	email := strings.TrimSpace(claims.Email)
+	if email == "" || !claims.EmailVerified {
+		return nil, ErrOIDCEmailNotVerified
+	}
+
+	user, err := s.repo.GetUserByEmail(email)
+	if errors.Is(err, sql.ErrNoRows) {
+		user, err = s.createOIDCUser(email)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.CreateUserIdentity(user.ID, claims.Issuer, claims.Subject, email); err != nil {
+		return nil, err
+	}
+	s.recordAuditEvent(models.AuditIdentityLinked, &user.ID, email, "", "", map[string]string{"issuer": claims.Issuer})
+	return user, nil
+}
+
+// createOIDCUser creates a user without a usable password, it can be set with a password reset
+func (s *Service) createOIDCUser(email string) (*models.User, error) {
+	password, err := newOpaqueToken()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.CreateUser(email, password); err != nil {
+		return nil, err
+	}
+
+	user, err := s.repo.GetUserByEmail(email)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.MarkEmailVerified(user.ID); err != nil {
+		return nil, err
+	}
+	return s.repo.GetUserByID(user.ID)
+}
@@ -0,0 +1,252 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"go-sober/platform"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// mockOIDCServer is a minimal OpenID provider: discovery, keys and an authorization code
+// token endpoint checking PKCE. The user login at the provider is simulated by authorize.
+type mockOIDCServer struct {
+	*httptest.Server
+	t        *testing.T
+	key      *rsa.PrivateKey
+	clientID string
+	secret   string
+
+	mu    sync.Mutex
+	codes map[string]mockAuthorization
+}
+
+type mockAuthorization struct {
+	nonce         string
+	codeChallenge string
+	redirectURI   string
+	claims        jwt.MapClaims
+}
+
+func newMockOIDCServer(t *testing.T) *mockOIDCServer {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	server := &mockOIDCServer{t: t, key: key, clientID: "sober", secret: "client-secret", codes: make(map[string]mockAuthorization)}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 server.URL,
+			"authorization_endpoint": server.URL + "/authorize",
+			"token_endpoint":         server.URL + "/token",
+			"jwks_uri":               server.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("GET /jwks", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"keys": []map[string]string{{
+				"kty": "RSA",
+				"kid": "test-key",
+				"use": "sig",
+				"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			}},
+		})
+	})
+	mux.HandleFunc("POST /token", server.token)
+
+	server.Server = httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+// authorize logs a provider account in at an authorization URL and returns the code and state sent back
+func (m *mockOIDCServer) authorize(authURL string, claims jwt.MapClaims) (code string, state string) {
+	parsed, err := url.Parse(authURL)
+	require.NoError(m.t, err)
+	query := parsed.Query()
+	require.Equal(m.t, "S256", query.Get("code_challenge_method"))
+	require.Equal(m.t, m.clientID, query.Get("client_id"))
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	code = "code-" + claims["sub"].(string) + "-" + query.Get("nonce")[:8]
+	m.codes[code] = mockAuthorization{
+		nonce:         query.Get("nonce"),
+		codeChallenge: query.Get("code_challenge"),
+		redirectURI:   query.Get("redirect_uri"),
+		claims:        claims,
+	}
+	return code, query.Get("state")
+}
+
+func (m *mockOIDCServer) token(w http.ResponseWriter, r *http.Request) {
+	clientID, secret, ok := r.BasicAuth()
+	if !ok || clientID != m.clientID || secret != m.secret {
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(map[string]string{"error": "invalid_client"})
+		return
+	}
+
+	m.mu.Lock()
+	authorization, ok := m.codes[r.FormValue("code")]
+	delete(m.codes, r.FormValue("code"))
+	m.mu.Unlock()
+
+	if !ok || r.FormValue("grant_type") != "authorization_code" || r.FormValue("redirect_uri") != authorization.redirectURI ||
+		pkceChallenge(r.FormValue("code_verifier")) != authorization.codeChallenge {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+		return
+	}
+
+	claims := jwt.MapClaims{
+		"iss":   m.URL,
+		"aud":   m.clientID,
+		"iat":   time.Now().Unix(),
+		"exp":   time.Now().Add(time.Hour).Unix(),
+		"nonce": authorization.nonce,
+	}
+	for name, value := range authorization.claims {
+		claims[name] = value
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = "test-key"
+	idToken, err := token.SignedString(m.key)
+	require.NoError(m.t, err)
+
+	json.NewEncoder(w).Encode(map[string]string{"id_token": idToken, "token_type": "Bearer"})
+}
+
+func setupOIDCTestService(t *testing.T) (*Service, *mockOIDCServer) {
+	server := newMockOIDCServer(t)
+	service := setupTestService(t)
+
+	service.config.Auth.OIDC = platform.OIDCConfig{
+		Issuer:       server.URL,
+		ClientID:     server.clientID,
+		ClientSecret: server.secret,
+		RedirectURL:  "http://localhost:3000/login/oidc",
+		Scopes:       []string{"openid", "email"},
+		LoginTTL:     10 * time.Minute,
+	}
+	service.oidc = newOIDCProvider(service.config.Auth.OIDC, server.Client())
+	return service, server
+}
+
+func TestOIDCLogin(t *testing.T) {
+	service, server := setupOIDCTestService(t)
+	account := jwt.MapClaims{"sub": "alice", "email": "alice@example.com", "email_verified": true}
+
+	authURL, clientKey, err := service.StartOIDCLogin()
+	require.NoError(t, err)
+	code, state := server.authorize(authURL, account)
+
+	result, err := service.CompleteOIDCLogin(code, state, clientKey, "10.0.0.1", "browser")
+	require.NoError(t, err)
+	require.NotNil(t, result.Tokens)
+	claims, err := service.ValidateToken(result.Tokens.AccessToken)
+	require.NoError(t, err)
+	assert.Equal(t, "alice@example.com", claims.Email)
+
+	user, err := service.repo.GetUserByEmail("alice@example.com")
+	require.NoError(t, err)
+	assert.NotNil(t, user.EmailVerifiedAt, "the provider verified the email")
+
+	// A state can only be used once
+	_, err = service.CompleteOIDCLogin(code, state, clientKey, "10.0.0.1", "browser")
+	assert.ErrorIs(t, err, ErrInvalidOIDCState)
+
+	// The next login finds the linked identity, even when the email changed at the provider
+	authURL, clientKey, err = service.StartOIDCLogin()
+	require.NoError(t, err)
+	code, state = server.authorize(authURL, jwt.MapClaims{"sub": "alice", "email": "alice@new.example.com"})
+	result, err = service.CompleteOIDCLogin(code, state, clientKey, "10.0.0.1", "browser")
+	require.NoError(t, err)
+	claims, err = service.ValidateToken(result.Tokens.AccessToken)
+	require.NoError(t, err)
+	assert.Equal(t, user.ID, claims.UserID)
+}
+
+func TestOIDCLoginLinksExistingUser(t *testing.T) {
+	service, server := setupOIDCTestService(t)
+	user := createTestUser(t, service)
+
+	// An unverified email is not enough to link an existing account
+	authURL, clientKey, err := service.StartOIDCLogin()
+	require.NoError(t, err)
+	code, state := server.authorize(authURL, jwt.MapClaims{"sub": "bob", "email": user.Email, "email_verified": false})
+	_, err = service.CompleteOIDCLogin(code, state, clientKey, "10.0.0.1", "browser")
+	assert.ErrorIs(t, err, ErrOIDCEmailNotVerified)
+
+	authURL, clientKey, err = service.StartOIDCLogin()
+	require.NoError(t, err)
+	code, state = server.authorize(authURL, jwt.MapClaims{"sub": "bob", "email": user.Email, "email_verified": true})
+	result, err := service.CompleteOIDCLogin(code, state, clientKey, "10.0.0.1", "browser")
+	require.NoError(t, err)
+
+	claims, err := service.ValidateToken(result.Tokens.AccessToken)
+	require.NoError(t, err)
+	assert.Equal(t, user.ID, claims.UserID)
+
+	identity, err := service.repo.GetUserIdentity(server.URL, "bob")
+	require.NoError(t, err)
+	assert.Equal(t, user.ID, identity.UserID)
+}
+
+func TestOIDCLoginBoundToTheClient(t *testing.T) {
+	service, server := setupOIDCTestService(t)
+
+	// An attacker starts a login with their account and stops at the callback
+	authURL, _, err := service.StartOIDCLogin()
+	require.NoError(t, err)
+	code, state := server.authorize(authURL, jwt.MapClaims{"sub": "mallory", "email": "mallory@example.com", "email_verified": true})
+
+	// The victim's browser posting that code has another client key, or none
+	_, victimKey, err := service.StartOIDCLogin()
+	require.NoError(t, err)
+	_, err = service.CompleteOIDCLogin(code, state, victimKey, "10.0.0.2", "victim")
+	assert.ErrorIs(t, err, ErrInvalidOIDCState)
+
+	_, err = service.repo.GetUserByEmail("mallory@example.com")
+	assert.Error(t, err, "no user is logged in")
+}
+
+func TestOIDCLoginRejectsForgedTokens(t *testing.T) {
+	service, server := setupOIDCTestService(t)
+
+	// Signed by another key than the provider ones
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	server.key = otherKey
+
+	authURL, clientKey, err := service.StartOIDCLogin()
+	require.NoError(t, err)
+	code, state := server.authorize(authURL, jwt.MapClaims{"sub": "mallory", "email": "mallory@example.com", "email_verified": true})
+	_, err = service.CompleteOIDCLogin(code, state, clientKey, "10.0.0.1", "browser")
+	assert.Error(t, err)
+
+	_, err = service.repo.GetUserByEmail("mallory@example.com")
+	assert.Error(t, err, "no user is created")
+}
+
+func TestOIDCDisabled(t *testing.T) {
+	service := setupTestService(t)
+
+	_, _, err := service.StartOIDCLogin()
+	assert.ErrorIs(t, err, ErrOIDCDisabled)
+}
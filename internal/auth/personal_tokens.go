@@ -0,0 +1,133 @@
+package auth
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"go-sober/internal/models"
+)
+
+// personalAccessTokenPrefix starts every personal access token, to tell them apart from JWTs
+// and to make them easy to spot by secret scanners
+const personalAccessTokenPrefix = "sober_pat_"
+
+const (
+	maxPersonalAccessTokens = 50
+	// How stale last_used_at may get, to avoid a write on each request
+	personalAccessTokenTouchInterval = time.Minute
+)
+
+var (
+	ErrInvalidPersonalAccessToken  = errors.New("invalid, expired or revoked personal access token")
+	ErrInvalidScope                = errors.New("invalid scope")
+	ErrTooManyPersonalAccessTokens = fmt.Errorf("a user can have at most %d personal access tokens", maxPersonalAccessTokens)
+)
+
+// IsPersonalAccessToken tells whether a bearer token is a personal access token rather than a JWT
+func IsPersonalAccessToken(token string) bool {
+	return strings.HasPrefix(token, personalAccessTokenPrefix)
+}
+
+// CreatePersonalAccessToken creates a token limited to some scopes, that never expires when
+// expiresIn is zero. The token is only returned here, it is stored hashed.
+func (s *Service) CreatePersonalAccessToken(userID int64, name string, scopes []string, expiresIn time.Duration) (string, *models.PersonalAccessToken, error) {
+	for _, scope := range scopes {
+		if !slices.Contains(models.PersonalAccessTokenScopes, scope) {
+			return "", nil, fmt.Errorf("%w: %s", ErrInvalidScope, scope)
+		}
+	}
+
+	existing, err := s.repo.GetPersonalAccessTokens(userID)
+	if err != nil {
+		return "", nil, err
+	}
+	if len(existing) >= maxPersonalAccessTokens {
+		return "", nil, ErrTooManyPersonalAccessTokens
+	}
+
+	secret, err := newOpaqueToken()
+	if err != nil {
+		return "", nil, err
+	}
+	token := personalAccessTokenPrefix + secret
+
+	scopes = slices.Clone(scopes)
+	slices.Sort(scopes)
+	pat := &models.PersonalAccessToken{
+		UserID:    userID,
+		Name:      name,
+		Prefix:    token[:len(personalAccessTokenPrefix)+4],
+		Scopes:    slices.Compact(scopes),
+		CreatedAt: time.Now().UTC(),
+	}
+	if expiresIn > 0 {
+		expiresAt := pat.CreatedAt.Add(expiresIn)
+		pat.ExpiresAt = &expiresAt
+	}
+
+	if err := s.repo.CreatePersonalAccessToken(pat, hashToken(token)); err != nil {
+		return "", nil, err
+	}
+	return token, pat, nil
+}
+
+// GetPersonalAccessTokens lists the tokens of a user that are not revoked
+func (s *Service) GetPersonalAccessTokens(userID int64) ([]models.PersonalAccessToken, error) {
+	return s.repo.GetPersonalAccessTokens(userID)
+}
+
+// RevokePersonalAccessToken revokes a token, sql.ErrNoRows if it is not an active token of the user
+func (s *Service) RevokePersonalAccessToken(userID, tokenID int64) error {
+	return s.repo.RevokePersonalAccessToken(userID, tokenID)
+}
+
+// AuthenticatePersonalAccessToken returns the claims of a request made with a personal access
+// token. They have no session and are limited to the scopes of the token.
+func (s *Service) AuthenticatePersonalAccessToken(token string) (*models.Claims, error) {
+	if !IsPersonalAccessToken(token) {
+		return nil, ErrInvalidPersonalAccessToken
+	}
+
+	pat, err := s.repo.GetPersonalAccessTokenByHash(hashToken(token))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrInvalidPersonalAccessToken
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	if pat.RevokedAt != nil || (pat.ExpiresAt != nil && !now.Before(*pat.ExpiresAt)) {
+		return nil, ErrInvalidPersonalAccessToken
+	}
+
+	user, err := s.repo.GetUserByID(pat.UserID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrInvalidPersonalAccessToken
+	}
+	if err != nil {
+		return nil, err
+	}
+	// The tokens of an account being deleted work again if the user logs in to cancel it
+	if user.DisabledAt != nil || user.DeletionRequestedAt != nil {
+		return nil, ErrInvalidPersonalAccessToken
+	}
+
+	if pat.LastUsedAt == nil || now.Sub(*pat.LastUsedAt) >= personalAccessTokenTouchInterval {
+		if err := s.repo.TouchPersonalAccessToken(pat.ID, now); err != nil {
+			return nil, err
+		}
+	}
+
+	return &models.Claims{
+		UserID:                user.ID,
+		Email:                 user.Email,
+		Role:                  user.Role,
+		PersonalAccessTokenID: pat.ID,
+		Scopes:                pat.Scopes,
+	}, nil
+}
@@ -0,0 +1,89 @@
+package auth
+
+import (
+	"database/sql"
+	"strings"
+	"testing"
+	"time"
+
+	"go-sober/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPersonalAccessToken(t *testing.T) {
+	service := setupTestService(t)
+	user := createTestUser(t, service)
+
+	_, _, err := service.CreatePersonalAccessToken(user.ID, "script", []string{"drinks:delete"}, 0)
+	assert.ErrorIs(t, err, ErrInvalidScope)
+
+	token, pat, err := service.CreatePersonalAccessToken(user.ID, "Home Assistant", []string{models.ScopeDrinksWrite, models.ScopeBACRead, models.ScopeDrinksWrite}, 0)
+	require.NoError(t, err)
+	assert.True(t, IsPersonalAccessToken(token))
+	assert.True(t, strings.HasPrefix(token, pat.Prefix))
+	assert.Equal(t, []string{models.ScopeBACRead, models.ScopeDrinksWrite}, pat.Scopes)
+	assert.Nil(t, pat.ExpiresAt)
+
+	// It is not a JWT
+	_, err = service.ValidateToken(token)
+	assert.Error(t, err)
+
+	claims, err := service.AuthenticatePersonalAccessToken(token)
+	require.NoError(t, err)
+	assert.Equal(t, user.ID, claims.UserID)
+	assert.Equal(t, user.Email, claims.Email)
+	assert.Equal(t, pat.ID, claims.PersonalAccessTokenID)
+	assert.True(t, claims.HasScope(models.ScopeBACRead))
+	assert.False(t, claims.HasScope(models.ScopeAnalyticsRead))
+
+	_, err = service.AuthenticatePersonalAccessToken(token + "x")
+	assert.ErrorIs(t, err, ErrInvalidPersonalAccessToken)
+
+	tokens, err := service.GetPersonalAccessTokens(user.ID)
+	require.NoError(t, err)
+	require.Len(t, tokens, 1)
+	assert.Equal(t, "Home Assistant", tokens[0].Name)
+	assert.NotNil(t, tokens[0].LastUsedAt)
+
+	// Only the owner can revoke it
+	assert.ErrorIs(t, service.RevokePersonalAccessToken(user.ID+1, pat.ID), sql.ErrNoRows)
+	require.NoError(t, service.RevokePersonalAccessToken(user.ID, pat.ID))
+	assert.ErrorIs(t, service.RevokePersonalAccessToken(user.ID, pat.ID), sql.ErrNoRows)
+
+	_, err = service.AuthenticatePersonalAccessToken(token)
+	assert.ErrorIs(t, err, ErrInvalidPersonalAccessToken)
+
+	tokens, err = service.GetPersonalAccessTokens(user.ID)
+	require.NoError(t, err)
+	assert.Empty(t, tokens)
+}
+
+func TestPersonalAccessTokenExpiry(t *testing.T) {
+	service := setupTestService(t)
+	user := createTestUser(t, service)
+
+	token, pat, err := service.CreatePersonalAccessToken(user.ID, "script", []string{models.ScopeAnalyticsRead}, time.Hour)
+	require.NoError(t, err)
+	require.NotNil(t, pat.ExpiresAt)
+
+	_, err = service.AuthenticatePersonalAccessToken(token)
+	require.NoError(t, err)
+
+	_, err = service.repo.db.Exec("UPDATE personal_access_tokens SET expires_at = ? WHERE id = ?", time.Now().UTC().Add(-time.Minute), pat.ID)
+	require.NoError(t, err)
+
+	_, err = service.AuthenticatePersonalAccessToken(token)
+	assert.ErrorIs(t, err, ErrInvalidPersonalAccessToken)
+}
+
+func TestClaimsHasScope(t *testing.T) {
+	// Session tokens are not limited by scopes
+	session := &models.Claims{UserID: 1, SessionID: "family"}
+	assert.True(t, session.HasScope(models.ScopeDrinksWrite))
+
+	pat := &models.Claims{UserID: 1, PersonalAccessTokenID: 3, Scopes: []string{models.ScopeBACRead}}
+	assert.True(t, pat.HasScope(models.ScopeBACRead))
+	assert.False(t, pat.HasScope(models.ScopeDrinksWrite))
+}
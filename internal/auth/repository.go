@@ -2,7 +2,11 @@ package auth
 
 import (
 	"database/sql"
+	"errors"
+	"fmt"
 	"log/slog"
+	"strings"
+	"time"
 
 	"go-sober/internal/models"
 
@@ -34,26 +38,864 @@ func (r *Repository) CreateUser(email, password string) error {
 }
 
 func (r *Repository) GetUserByEmail(email string) (*models.User, error) {
-	user := &models.User{}
 	query := `
-        SELECT id, email, password, created_at
+        SELECT id, email, password, role, email_verified_at, disabled_at, deletion_requested_at, created_at
         FROM users
         WHERE email = ?
     `
-	err := r.db.QueryRow(query, email).Scan(
+	return scanUser(r.db.QueryRow(query, email))
+}
+
+func (r *Repository) GetUserByID(id int64) (*models.User, error) {
+	query := `
+        SELECT id, email, password, role, email_verified_at, disabled_at, deletion_requested_at, created_at
+        FROM users
+        WHERE id = ?
+    `
+	return scanUser(r.db.QueryRow(query, id))
+}
+
+func scanUser(row *sql.Row) (*models.User, error) {
+	user := &models.User{}
+	var emailVerifiedAt, disabledAt, deletionRequestedAt sql.NullTime
+	err := row.Scan(
 		&user.ID,
 		&user.Email,
 		&user.Password,
+		&user.Role,
+		&emailVerifiedAt,
+		&disabledAt,
+		&deletionRequestedAt,
 		&user.CreatedAt,
 	)
 	if err != nil {
-		slog.Error("Could not get user by email", "error", err)
 		return nil, err
 	}
+
+	if emailVerifiedAt.Valid {
+		user.EmailVerifiedAt = &emailVerifiedAt.Time
+	}
+	if disabledAt.Valid {
+		user.DisabledAt = &disabledAt.Time
+	}
+	if deletionRequestedAt.Valid {
+		user.DeletionRequestedAt = &deletionRequestedAt.Time
+	}
 	return user, nil
 }
 
+func (r *Repository) UpdatePassword(userID int64, password string) error {
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return fmt.Errorf("could not hash password: %w", err)
+	}
+
+	if _, err := r.db.Exec("UPDATE users SET password = ? WHERE id = ?", string(hashedPassword), userID); err != nil {
+		return fmt.Errorf("error updating password: %w", err)
+	}
+	return nil
+}
+
+// UpdateEmail changes the email of a user, which has to be verified again
+func (r *Repository) UpdateEmail(userID int64, email string) error {
+	result, err := r.db.Exec("UPDATE users SET email = ?, email_verified_at = NULL WHERE id = ?", email, userID)
+	if err != nil {
+		return fmt.Errorf("error updating email: %w", err)
+	}
+	if rows, err := result.RowsAffected(); err != nil || rows == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+func (r *Repository) MarkEmailVerified(userID int64) error {
+	_, err := r.db.Exec(`
+        UPDATE users
+        SET email_verified_at = ?
+        WHERE id = ? AND email_verified_at IS NULL
+    `, time.Now().UTC(), userID)
+	if err != nil {
+		return fmt.Errorf("error marking email as verified: %w", err)
+	}
+	return nil
+}
+
+// SetUserDisabled disables or enables an account, sql.ErrNoRows if the user does not exist
+func (r *Repository) SetUserDisabled(userID int64, disabled bool) error {
+	var disabledAt any
+	if disabled {
+		disabledAt = time.Now().UTC()
+	}
+
+	result, err := r.db.Exec("UPDATE users SET disabled_at = ? WHERE id = ?", disabledAt, userID)
+	if err != nil {
+		return fmt.Errorf("error updating user: %w", err)
+	}
+	if rows, err := result.RowsAffected(); err != nil || rows == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// SetDeletionRequested records or clears the deletion request of a user, sql.ErrNoRows if the user does not exist
+func (r *Repository) SetDeletionRequested(userID int64, requestedAt *time.Time) error {
+	var value any
+	if requestedAt != nil {
+		value = requestedAt.UTC()
+	}
+
+	result, err := r.db.Exec("UPDATE users SET deletion_requested_at = ? WHERE id = ?", value, userID)
+	if err != nil {
+		return fmt.Errorf("error updating user deletion: %w", err)
+	}
+	if rows, err := result.RowsAffected(); err != nil || rows == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// SetUserRole changes the role of a user, sql.ErrNoRows if the user does not exist
+func (r *Repository) SetUserRole(userID int64, role models.Role) error {
+	result, err := r.db.Exec("UPDATE users SET role = ? WHERE id = ?", role, userID)
+	if err != nil {
+		return fmt.Errorf("error updating user role: %w", err)
+	}
+	if rows, err := result.RowsAffected(); err != nil || rows == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
 // ComparePassword compares a hashed password with a plain text password
 func (r *Repository) ComparePassword(hashedPassword, password string) error {
 	return bcrypt.CompareHashAndPassword([]byte(hashedPassword), []byte(password))
 }
+
+// ErrSessionAlreadyRotated is returned when a refresh token was exchanged concurrently
+var ErrSessionAlreadyRotated = errors.New("session already rotated")
+
+func (r *Repository) CreateSession(session *models.Session) error {
+	query := `
+        INSERT INTO sessions (user_id, family_id, token_hash, user_agent, ip_address, created_at, expires_at)
+        VALUES (?, ?, ?, ?, ?, ?, ?)
+    `
+	result, err := r.db.Exec(query, session.UserID, session.FamilyID, session.TokenHash,
+		session.UserAgent, session.IPAddress, session.CreatedAt, session.ExpiresAt)
+	if err != nil {
+		return fmt.Errorf("error creating session: %w", err)
+	}
+
+	session.ID, err = result.LastInsertId()
+	return err
+}
+
+func (r *Repository) GetSessionByTokenHash(tokenHash string) (*models.Session, error) {
+	query := `
+        SELECT id, user_id, family_id, token_hash, user_agent, ip_address, created_at, expires_at, rotated_at, revoked_at
+        FROM sessions
+        WHERE token_hash = ?
+    `
+	return scanSession(r.db.QueryRow(query, tokenHash))
+}
+
+// RotateSession marks a session as exchanged and stores the next session of its family
+func (r *Repository) RotateSession(sessionID int64, next *models.Session) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`
+        UPDATE sessions
+        SET rotated_at = ?
+        WHERE id = ? AND rotated_at IS NULL AND revoked_at IS NULL
+    `, next.CreatedAt, sessionID)
+	if err != nil {
+		return fmt.Errorf("error rotating session: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("error getting rows affected: %w", err)
+	}
+	if rows == 0 {
+		return ErrSessionAlreadyRotated
+	}
+
+	insert, err := tx.Exec(`
+        INSERT INTO sessions (user_id, family_id, token_hash, user_agent, ip_address, created_at, expires_at)
+        VALUES (?, ?, ?, ?, ?, ?, ?)
+    `, next.UserID, next.FamilyID, next.TokenHash, next.UserAgent, next.IPAddress, next.CreatedAt, next.ExpiresAt)
+	if err != nil {
+		return fmt.Errorf("error creating session: %w", err)
+	}
+
+	if next.ID, err = insert.LastInsertId(); err != nil {
+		return fmt.Errorf("error getting session ID: %w", err)
+	}
+
+	return tx.Commit()
+}
+
+// RevokeSessionFamily revokes every refresh token of a device
+func (r *Repository) RevokeSessionFamily(familyID string) error {
+	_, err := r.db.Exec(`
+        UPDATE sessions
+        SET revoked_at = ?
+        WHERE family_id = ? AND revoked_at IS NULL
+    `, time.Now().UTC(), familyID)
+	if err != nil {
+		return fmt.Errorf("error revoking session family: %w", err)
+	}
+	return nil
+}
+
+func scanSession(row *sql.Row) (*models.Session, error) {
+	session := &models.Session{}
+	var rotatedAt, revokedAt sql.NullTime
+	err := row.Scan(
+		&session.ID,
+		&session.UserID,
+		&session.FamilyID,
+		&session.TokenHash,
+		&session.UserAgent,
+		&session.IPAddress,
+		&session.CreatedAt,
+		&session.ExpiresAt,
+		&rotatedAt,
+		&revokedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if rotatedAt.Valid {
+		session.RotatedAt = &rotatedAt.Time
+	}
+	if revokedAt.Valid {
+		session.RevokedAt = &revokedAt.Time
+	}
+	return session, nil
+}
+
+// RevokeUserSessions revokes every refresh token of a user
+func (r *Repository) RevokeUserSessions(userID int64) error {
+	_, err := r.db.Exec(`
+        UPDATE sessions
+        SET revoked_at = ?
+        WHERE user_id = ? AND revoked_at IS NULL
+    `, time.Now().UTC(), userID)
+	if err != nil {
+		return fmt.Errorf("error revoking user sessions: %w", err)
+	}
+	return nil
+}
+
+// RevokeOtherUserSessions revokes every session of a user but the ones of a family
+func (r *Repository) RevokeOtherUserSessions(userID int64, keepFamilyID string) error {
+	_, err := r.db.Exec(`
+        UPDATE sessions
+        SET revoked_at = ?
+        WHERE user_id = ? AND family_id != ? AND revoked_at IS NULL
+    `, time.Now().UTC(), userID, keepFamilyID)
+	if err != nil {
+		return fmt.Errorf("error revoking user sessions: %w", err)
+	}
+	return nil
+}
+
+// RevokeUserSessionFamily revokes a device of a user, sql.ErrNoRows if the user has no such active device
+func (r *Repository) RevokeUserSessionFamily(userID int64, familyID string) error {
+	result, err := r.db.Exec(`
+        UPDATE sessions
+        SET revoked_at = ?
+        WHERE user_id = ? AND family_id = ? AND revoked_at IS NULL
+    `, time.Now().UTC(), userID, familyID)
+	if err != nil {
+		return fmt.Errorf("error revoking session family: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("error getting rows affected: %w", err)
+	}
+	if rows == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+func (r *Repository) IsSessionFamilyRevoked(familyID string) (bool, error) {
+	var revoked bool
+	err := r.db.QueryRow(`
+        SELECT EXISTS (SELECT 1 FROM sessions WHERE family_id = ? AND revoked_at IS NOT NULL)
+    `, familyID).Scan(&revoked)
+	if err != nil {
+		return false, fmt.Errorf("error checking session family: %w", err)
+	}
+	return revoked, nil
+}
+
+// GetActiveSessions lists the logged in devices of a user, most recently active first
+func (r *Repository) GetActiveSessions(userID int64) ([]models.DeviceSession, error) {
+	rows, err := r.db.Query(`
+        SELECT s.family_id, s.user_agent, s.ip_address,
+            (SELECT MIN(f.created_at) FROM sessions f WHERE f.family_id = s.family_id) AS signed_in_at,
+            s.created_at, s.expires_at
+        FROM sessions s
+        WHERE s.user_id = ? AND s.rotated_at IS NULL AND s.revoked_at IS NULL AND s.expires_at > ?
+        ORDER BY s.created_at DESC
+    `, userID, time.Now().UTC())
+	if err != nil {
+		return nil, fmt.Errorf("error querying sessions: %w", err)
+	}
+	defer rows.Close()
+
+	var sessions []models.DeviceSession
+	for rows.Next() {
+		var session models.DeviceSession
+		var signedInAt string
+		if err := rows.Scan(
+			&session.FamilyID,
+			&session.UserAgent,
+			&session.IPAddress,
+			&signedInAt,
+			&session.LastActiveAt,
+			&session.ExpiresAt,
+		); err != nil {
+			return nil, fmt.Errorf("error scanning session: %w", err)
+		}
+
+		// Aggregates lose the column type, so the driver returns the stored text
+		if session.SignedInAt, err = parseSQLiteTime(signedInAt); err != nil {
+			return nil, fmt.Errorf("error parsing session date: %w", err)
+		}
+		sessions = append(sessions, session)
+	}
+	return sessions, rows.Err()
+}
+
+// RevokeToken adds an access token to the denylist until it expires
+func (r *Repository) RevokeToken(jti string, userID int64, expiresAt time.Time) error {
+	now := time.Now().UTC()
+	if _, err := r.db.Exec(`
+        INSERT OR IGNORE INTO revoked_tokens (jti, user_id, expires_at, revoked_at)
+        VALUES (?, ?, ?, ?)
+    `, jti, userID, expiresAt.UTC(), now); err != nil {
+		return fmt.Errorf("error revoking token: %w", err)
+	}
+
+	// Expired tokens are rejected anyway, no need to remember them
+	if _, err := r.db.Exec("DELETE FROM revoked_tokens WHERE expires_at <= ?", now); err != nil {
+		return fmt.Errorf("error purging revoked tokens: %w", err)
+	}
+	return nil
+}
+
+func (r *Repository) IsTokenRevoked(jti string) (bool, error) {
+	var revoked bool
+	err := r.db.QueryRow("SELECT EXISTS (SELECT 1 FROM revoked_tokens WHERE jti = ?)", jti).Scan(&revoked)
+	if err != nil {
+		return false, fmt.Errorf("error checking revoked token: %w", err)
+	}
+	return revoked, nil
+}
+
+// Layouts the SQLite driver writes times with
+var sqliteTimeLayouts = []string{
+	"2006-01-02 15:04:05.999999999-07:00",
+	"2006-01-02T15:04:05.999999999-07:00",
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02 15:04:05",
+	time.RFC3339Nano,
+}
+
+func parseSQLiteTime(value string) (time.Time, error) {
+	for _, layout := range sqliteTimeLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("unknown time format %q", value)
+}
+
+func (r *Repository) CreateUserToken(userID int64, purpose models.UserTokenPurpose, tokenHash string, expiresAt time.Time) error {
+	_, err := r.db.Exec(`
+        INSERT INTO user_tokens (user_id, purpose, token_hash, created_at, expires_at)
+        VALUES (?, ?, ?, ?, ?)
+    `, userID, purpose, tokenHash, time.Now().UTC(), expiresAt.UTC())
+	if err != nil {
+		return fmt.Errorf("error creating user token: %w", err)
+	}
+	return nil
+}
+
+// FindUserToken returns the user of a valid token without using it, sql.ErrNoRows when the token is invalid
+func (r *Repository) FindUserToken(tokenHash string, purpose models.UserTokenPurpose) (int64, error) {
+	var userID int64
+	err := r.db.QueryRow(`
+        SELECT user_id
+        FROM user_tokens
+        WHERE token_hash = ? AND purpose = ? AND used_at IS NULL AND expires_at > ?
+    `, tokenHash, purpose, time.Now().UTC()).Scan(&userID)
+	if err != nil {
+		return 0, err
+	}
+	return userID, nil
+}
+
+// ConsumeUserToken marks a token as used and returns its user.
+// It returns sql.ErrNoRows if the token is unknown, used or expired.
+func (r *Repository) ConsumeUserToken(tokenHash string, purpose models.UserTokenPurpose) (int64, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	now := time.Now().UTC()
+	var tokenID, userID int64
+	err = tx.QueryRow(`
+        SELECT id, user_id
+        FROM user_tokens
+        WHERE token_hash = ? AND purpose = ? AND used_at IS NULL AND expires_at > ?
+    `, tokenHash, purpose, now).Scan(&tokenID, &userID)
+	if err != nil {
+		return 0, err
+	}
+
+	result, err := tx.Exec("UPDATE user_tokens SET used_at = ? WHERE id = ? AND used_at IS NULL", now, tokenID)
+	if err != nil {
+		return 0, fmt.Errorf("error using user token: %w", err)
+	}
+	if rows, err := result.RowsAffected(); err != nil || rows == 0 {
+		return 0, sql.ErrNoRows
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("error committing transaction: %w", err)
+	}
+	return userID, nil
+}
+
+// InvalidateUserTokens marks the unused tokens of a user as used
+func (r *Repository) InvalidateUserTokens(userID int64, purpose models.UserTokenPurpose) error {
+	_, err := r.db.Exec(`
+        UPDATE user_tokens
+        SET used_at = ?
+        WHERE user_id = ? AND purpose = ? AND used_at IS NULL
+    `, time.Now().UTC(), userID, purpose)
+	if err != nil {
+		return fmt.Errorf("error invalidating user tokens: %w", err)
+	}
+	return nil
+}
+
+// GetTOTPFactor returns the TOTP factor of a user, sql.ErrNoRows when there is none
+func (r *Repository) GetTOTPFactor(userID int64) (*models.TOTPFactor, error) {
+	factor := &models.TOTPFactor{}
+	var enabledAt sql.NullTime
+	err := r.db.QueryRow(`
+        SELECT user_id, totp_secret, last_used_step, created_at, enabled_at
+        FROM user_mfa
+        WHERE user_id = ?
+    `, userID).Scan(&factor.UserID, &factor.Secret, &factor.LastUsedStep, &factor.CreatedAt, &enabledAt)
+	if err != nil {
+		return nil, err
+	}
+
+	if enabledAt.Valid {
+		factor.EnabledAt = &enabledAt.Time
+	}
+	return factor, nil
+}
+
+// SaveTOTPSecret stores the secret of a pending enrolment, replacing a previous pending one.
+// An enabled factor is left untouched.
+func (r *Repository) SaveTOTPSecret(userID int64, secret string) error {
+	_, err := r.db.Exec(`
+        INSERT INTO user_mfa (user_id, totp_secret, created_at)
+        VALUES (?, ?, ?)
+        ON CONFLICT (user_id) DO UPDATE
+        SET totp_secret = excluded.totp_secret, created_at = excluded.created_at, last_used_step = 0
+        WHERE user_mfa.enabled_at IS NULL
+    `, userID, secret, time.Now().UTC())
+	if err != nil {
+		return fmt.Errorf("error saving TOTP secret: %w", err)
+	}
+	return nil
+}
+
+// EnableTOTP enables a pending factor with the step of the code that confirmed it, and stores
+// its recovery codes. It returns sql.ErrNoRows when there is no pending factor.
+func (r *Repository) EnableTOTP(userID int64, step int64, recoveryCodeHashes []string) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`
+        UPDATE user_mfa
+        SET enabled_at = ?, last_used_step = ?
+        WHERE user_id = ? AND enabled_at IS NULL
+    `, time.Now().UTC(), step, userID)
+	if err != nil {
+		return fmt.Errorf("error enabling TOTP: %w", err)
+	}
+	if rows, err := result.RowsAffected(); err != nil || rows == 0 {
+		return sql.ErrNoRows
+	}
+
+	if err := replaceRecoveryCodes(tx, userID, recoveryCodeHashes); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error committing transaction: %w", err)
+	}
+	return nil
+}
+
+// ReplaceRecoveryCodes invalidates the recovery codes of a user and stores new ones
+func (r *Repository) ReplaceRecoveryCodes(userID int64, recoveryCodeHashes []string) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := replaceRecoveryCodes(tx, userID, recoveryCodeHashes); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error committing transaction: %w", err)
+	}
+	return nil
+}
+
+func replaceRecoveryCodes(tx *sql.Tx, userID int64, recoveryCodeHashes []string) error {
+	if _, err := tx.Exec("DELETE FROM mfa_recovery_codes WHERE user_id = ?", userID); err != nil {
+		return fmt.Errorf("error deleting recovery codes: %w", err)
+	}
+
+	now := time.Now().UTC()
+	for _, codeHash := range recoveryCodeHashes {
+		_, err := tx.Exec(`
+            INSERT INTO mfa_recovery_codes (user_id, code_hash, created_at)
+            VALUES (?, ?, ?)
+        `, userID, codeHash, now)
+		if err != nil {
+			return fmt.Errorf("error creating recovery code: %w", err)
+		}
+	}
+	return nil
+}
+
+// UseTOTPStep records the step of an accepted code. It returns sql.ErrNoRows when
+// this step or a later one was already used, so that a code cannot be replayed.
+func (r *Repository) UseTOTPStep(userID int64, step int64) error {
+	result, err := r.db.Exec(`
+        UPDATE user_mfa
+        SET last_used_step = ?
+        WHERE user_id = ? AND last_used_step < ?
+    `, step, userID, step)
+	if err != nil {
+		return fmt.Errorf("error using TOTP step: %w", err)
+	}
+	if rows, err := result.RowsAffected(); err != nil || rows == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// UseRecoveryCode marks a recovery code as used, sql.ErrNoRows when it is unknown or already used
+func (r *Repository) UseRecoveryCode(userID int64, codeHash string) error {
+	result, err := r.db.Exec(`
+        UPDATE mfa_recovery_codes
+        SET used_at = ?
+        WHERE user_id = ? AND code_hash = ? AND used_at IS NULL
+    `, time.Now().UTC(), userID, codeHash)
+	if err != nil {
+		return fmt.Errorf("error using recovery code: %w", err)
+	}
+	if rows, err := result.RowsAffected(); err != nil || rows == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// DeleteTOTP removes the TOTP factor of a user and its recovery codes
+func (r *Repository) DeleteTOTP(userID int64) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("DELETE FROM mfa_recovery_codes WHERE user_id = ?", userID); err != nil {
+		return fmt.Errorf("error deleting recovery codes: %w", err)
+	}
+	if _, err := tx.Exec("DELETE FROM user_mfa WHERE user_id = ?", userID); err != nil {
+		return fmt.Errorf("error deleting TOTP factor: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error committing transaction: %w", err)
+	}
+	return nil
+}
+
+// SaveOIDCLoginState stores a pending login at the identity provider, and purges the expired ones
+func (r *Repository) SaveOIDCLoginState(stateHash, clientKeyHash, nonce, codeVerifier string, expiresAt time.Time) error {
+	now := time.Now().UTC()
+	if _, err := r.db.Exec("DELETE FROM oidc_login_states WHERE expires_at <= ?", now); err != nil {
+		return fmt.Errorf("error purging OIDC login states: %w", err)
+	}
+
+	_, err := r.db.Exec(`
+        INSERT INTO oidc_login_states (state_hash, client_key_hash, nonce, code_verifier, created_at, expires_at)
+        VALUES (?, ?, ?, ?, ?, ?)
+    `, stateHash, clientKeyHash, nonce, codeVerifier, now, expiresAt.UTC())
+	if err != nil {
+		return fmt.Errorf("error saving OIDC login state: %w", err)
+	}
+	return nil
+}
+
+// ConsumeOIDCLoginState deletes a pending login and returns the client key hash, nonce and
+// PKCE verifier. It returns sql.ErrNoRows when the state is unknown, expired or already used.
+func (r *Repository) ConsumeOIDCLoginState(stateHash string) (clientKeyHash, nonce, codeVerifier string, err error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return "", "", "", fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRow(`
+        SELECT client_key_hash, nonce, code_verifier
+        FROM oidc_login_states
+        WHERE state_hash = ? AND expires_at > ?
+    `, stateHash, time.Now().UTC()).Scan(&clientKeyHash, &nonce, &codeVerifier)
+	if err != nil {
+		return "", "", "", err
+	}
+
+	if _, err := tx.Exec("DELETE FROM oidc_login_states WHERE state_hash = ?", stateHash); err != nil {
+		return "", "", "", fmt.Errorf("error deleting OIDC login state: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return "", "", "", fmt.Errorf("error committing transaction: %w", err)
+	}
+	return clientKeyHash, nonce, codeVerifier, nil
+}
+
+// GetUserIdentity returns the identity of a provider account, sql.ErrNoRows when it is not linked
+func (r *Repository) GetUserIdentity(issuer, subject string) (*models.UserIdentity, error) {
+	identity := &models.UserIdentity{}
+	var lastLoginAt sql.NullTime
+	err := r.db.QueryRow(`
+        SELECT id, user_id, issuer, subject, email, created_at, last_login_at
+        FROM user_identities
+        WHERE issuer = ? AND subject = ?
+    `, issuer, subject).Scan(&identity.ID, &identity.UserID, &identity.Issuer, &identity.Subject, &identity.Email, &identity.CreatedAt, &lastLoginAt)
+	if err != nil {
+		return nil, err
+	}
+
+	if lastLoginAt.Valid {
+		identity.LastLoginAt = &lastLoginAt.Time
+	}
+	return identity, nil
+}
+
+// CreateUserIdentity links a provider account to a user
+func (r *Repository) CreateUserIdentity(userID int64, issuer, subject, email string) error {
+	now := time.Now().UTC()
+	_, err := r.db.Exec(`
+        INSERT INTO user_identities (user_id, issuer, subject, email, created_at, last_login_at)
+        VALUES (?, ?, ?, ?, ?, ?)
+    `, userID, issuer, subject, email, now, now)
+	if err != nil {
+		return fmt.Errorf("error creating user identity: %w", err)
+	}
+	return nil
+}
+
+// TouchUserIdentity records a login with a provider account
+func (r *Repository) TouchUserIdentity(identityID int64, email string) error {
+	_, err := r.db.Exec(`
+        UPDATE user_identities
+        SET email = ?, last_login_at = ?
+        WHERE id = ?
+    `, email, time.Now().UTC(), identityID)
+	if err != nil {
+		return fmt.Errorf("error updating user identity: %w", err)
+	}
+	return nil
+}
+
+// CreatePersonalAccessToken stores a new personal access token and sets its ID
+func (r *Repository) CreatePersonalAccessToken(token *models.PersonalAccessToken, tokenHash string) error {
+	var expiresAt any
+	if token.ExpiresAt != nil {
+		expiresAt = token.ExpiresAt.UTC()
+	}
+
+	result, err := r.db.Exec(`
+        INSERT INTO personal_access_tokens (user_id, name, token_hash, token_prefix, scopes, created_at, expires_at)
+        VALUES (?, ?, ?, ?, ?, ?, ?)
+    `, token.UserID, token.Name, tokenHash, token.Prefix, strings.Join(token.Scopes, " "), token.CreatedAt.UTC(), expiresAt)
+	if err != nil {
+		return fmt.Errorf("error creating personal access token: %w", err)
+	}
+
+	token.ID, err = result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("error getting personal access token ID: %w", err)
+	}
+	return nil
+}
+
+const personalAccessTokenColumns = `id, user_id, name, token_prefix, scopes, created_at, expires_at, last_used_at, revoked_at`
+
+// GetPersonalAccessTokenByHash returns a token whether it is valid or not, sql.ErrNoRows when it does not exist
+func (r *Repository) GetPersonalAccessTokenByHash(tokenHash string) (*models.PersonalAccessToken, error) {
+	row := r.db.QueryRow(`SELECT `+personalAccessTokenColumns+` FROM personal_access_tokens WHERE token_hash = ?`, tokenHash)
+	return scanPersonalAccessToken(row)
+}
+
+// GetPersonalAccessTokens lists the tokens of a user that are not revoked, newest first
+func (r *Repository) GetPersonalAccessTokens(userID int64) ([]models.PersonalAccessToken, error) {
+	rows, err := r.db.Query(`
+        SELECT `+personalAccessTokenColumns+`
+        FROM personal_access_tokens
+        WHERE user_id = ? AND revoked_at IS NULL
+        ORDER BY created_at DESC, id DESC
+    `, userID)
+	if err != nil {
+		return nil, fmt.Errorf("error getting personal access tokens: %w", err)
+	}
+	defer rows.Close()
+
+	tokens := []models.PersonalAccessToken{}
+	for rows.Next() {
+		token, err := scanPersonalAccessToken(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning personal access token: %w", err)
+		}
+		tokens = append(tokens, *token)
+	}
+	return tokens, rows.Err()
+}
+
+func scanPersonalAccessToken(row interface{ Scan(dest ...any) error }) (*models.PersonalAccessToken, error) {
+	token := &models.PersonalAccessToken{}
+	var scopes string
+	var expiresAt, lastUsedAt, revokedAt sql.NullTime
+	err := row.Scan(&token.ID, &token.UserID, &token.Name, &token.Prefix, &scopes, &token.CreatedAt, &expiresAt, &lastUsedAt, &revokedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	token.Scopes = strings.Fields(scopes)
+	if expiresAt.Valid {
+		token.ExpiresAt = &expiresAt.Time
+	}
+	if lastUsedAt.Valid {
+		token.LastUsedAt = &lastUsedAt.Time
+	}
+	if revokedAt.Valid {
+		token.RevokedAt = &revokedAt.Time
+	}
+	return token, nil
+}
+
+// TouchPersonalAccessToken records the use of a token
+func (r *Repository) TouchPersonalAccessToken(tokenID int64, usedAt time.Time) error {
+	_, err := r.db.Exec("UPDATE personal_access_tokens SET last_used_at = ? WHERE id = ?", usedAt.UTC(), tokenID)
+	if err != nil {
+		return fmt.Errorf("error updating personal access token: %w", err)
+	}
+	return nil
+}
+
+// RevokePersonalAccessToken revokes a token of a user, sql.ErrNoRows if it is not an active token of the user
+func (r *Repository) RevokePersonalAccessToken(userID, tokenID int64) error {
+	result, err := r.db.Exec(`
+        UPDATE personal_access_tokens
+        SET revoked_at = ?
+        WHERE id = ? AND user_id = ? AND revoked_at IS NULL
+    `, time.Now().UTC(), tokenID, userID)
+	if err != nil {
+		return fmt.Errorf("error revoking personal access token: %w", err)
+	}
+	if rows, err := result.RowsAffected(); err != nil || rows == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// CreateSigningKey stores a new key pair for access tokens
+func (r *Repository) CreateSigningKey(key *models.SigningKey) error {
+	_, err := r.db.Exec(`
+        INSERT INTO jwt_signing_keys (id, algorithm, private_key, created_at)
+        VALUES (?, ?, ?, ?)
+    `, key.ID, key.Algorithm, key.PrivateKey, key.CreatedAt.UTC())
+	if err != nil {
+		return fmt.Errorf("error creating signing key: %w", err)
+	}
+	return nil
+}
+
+// GetSigningKeys lists the signing keys, newest first, with the retired ones or not
+func (r *Repository) GetSigningKeys(includeRetired bool) ([]models.SigningKey, error) {
+	query := `
+        SELECT id, algorithm, private_key, created_at, retired_at
+        FROM jwt_signing_keys
+    `
+	if !includeRetired {
+		query += " WHERE retired_at IS NULL"
+	}
+	query += " ORDER BY created_at DESC, rowid DESC"
+
+	rows, err := r.db.Query(query)
+	if err != nil {
+		return nil, fmt.Errorf("error getting signing keys: %w", err)
+	}
+	defer rows.Close()
+
+	keys := []models.SigningKey{}
+	for rows.Next() {
+		var key models.SigningKey
+		var retiredAt sql.NullTime
+		if err := rows.Scan(&key.ID, &key.Algorithm, &key.PrivateKey, &key.CreatedAt, &retiredAt); err != nil {
+			return nil, fmt.Errorf("error scanning signing key: %w", err)
+		}
+		if retiredAt.Valid {
+			key.RetiredAt = &retiredAt.Time
+		}
+		keys = append(keys, key)
+	}
+	return keys, rows.Err()
+}
+
+// RetireSigningKey stops a key from verifying tokens, sql.ErrNoRows if it is not an active key
+func (r *Repository) RetireSigningKey(id string) error {
+	result, err := r.db.Exec(`
+        UPDATE jwt_signing_keys
+        SET retired_at = ?
+        WHERE id = ? AND retired_at IS NULL
+    `, time.Now().UTC(), id)
+	if err != nil {
+		return fmt.Errorf("error retiring signing key: %w", err)
+	}
+	if rows, err := result.RowsAffected(); err != nil || rows == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
@@ -3,6 +3,9 @@ package auth
 import (
 	"database/sql"
 	"testing"
+	"time"
+
+	"go-sober/internal/models"
 
 	_ "github.com/mattn/go-sqlite3"
 	"github.com/stretchr/testify/assert"
@@ -21,13 +24,159 @@ func setupTestDB(t *testing.T) *Repository {
 			id INTEGER PRIMARY KEY AUTOINCREMENT,
 			email TEXT UNIQUE NOT NULL,
 			password TEXT NOT NULL,
-			created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+			email_verified_at DATETIME DEFAULT NULL,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			role TEXT NOT NULL DEFAULT 'user',
+			disabled_at DATETIME DEFAULT NULL,
+			deletion_requested_at DATETIME DEFAULT NULL
 		)
 	`)
 	if err != nil {
 		t.Fatalf("Failed to create users table: %v", err)
 	}
 
+	// Create sessions table
+	_, err = db.Exec(`
+		CREATE TABLE sessions (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			user_id INTEGER NOT NULL,
+			family_id TEXT NOT NULL,
+			token_hash TEXT UNIQUE NOT NULL,
+			user_agent TEXT NOT NULL DEFAULT '',
+			ip_address TEXT NOT NULL DEFAULT '',
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			expires_at DATETIME NOT NULL,
+			rotated_at DATETIME DEFAULT NULL,
+			revoked_at DATETIME DEFAULT NULL
+		)
+	`)
+	if err != nil {
+		t.Fatalf("Failed to create sessions table: %v", err)
+	}
+
+	// Create revoked tokens table
+	_, err = db.Exec(`
+		CREATE TABLE revoked_tokens (
+			jti TEXT PRIMARY KEY,
+			user_id INTEGER NOT NULL,
+			expires_at DATETIME NOT NULL,
+			revoked_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		)
+	`)
+	if err != nil {
+		t.Fatalf("Failed to create revoked_tokens table: %v", err)
+	}
+
+	// Create user tokens table
+	_, err = db.Exec(`
+		CREATE TABLE user_tokens (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			user_id INTEGER NOT NULL,
+			purpose TEXT NOT NULL,
+			token_hash TEXT UNIQUE NOT NULL,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			expires_at DATETIME NOT NULL,
+			used_at DATETIME DEFAULT NULL
+		)
+	`)
+	if err != nil {
+		t.Fatalf("Failed to create user_tokens table: %v", err)
+	}
+
+	// Create audit events table
+	_, err = db.Exec(`
+		CREATE TABLE audit_events (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			user_id INTEGER DEFAULT NULL,
+			event_type TEXT NOT NULL,
+			email TEXT NOT NULL DEFAULT '',
+			ip_address TEXT NOT NULL DEFAULT '',
+			user_agent TEXT NOT NULL DEFAULT '',
+			details TEXT NOT NULL DEFAULT '{}',
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		)
+	`)
+	if err != nil {
+		t.Fatalf("Failed to create audit_events table: %v", err)
+	}
+
+	// Create MFA tables
+	_, err = db.Exec(`
+		CREATE TABLE user_mfa (
+			user_id INTEGER PRIMARY KEY,
+			totp_secret TEXT NOT NULL,
+			last_used_step INTEGER NOT NULL DEFAULT 0,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			enabled_at DATETIME DEFAULT NULL
+		);
+		CREATE TABLE mfa_recovery_codes (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			user_id INTEGER NOT NULL,
+			code_hash TEXT UNIQUE NOT NULL,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			used_at DATETIME DEFAULT NULL
+		)
+	`)
+	if err != nil {
+		t.Fatalf("Failed to create MFA tables: %v", err)
+	}
+
+	// Create OIDC tables
+	_, err = db.Exec(`
+		CREATE TABLE user_identities (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			user_id INTEGER NOT NULL,
+			issuer TEXT NOT NULL,
+			subject TEXT NOT NULL,
+			email TEXT NOT NULL DEFAULT '',
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			last_login_at DATETIME DEFAULT NULL,
+			UNIQUE (issuer, subject)
+		);
+		CREATE TABLE oidc_login_states (
+			state_hash TEXT PRIMARY KEY,
+			client_key_hash TEXT NOT NULL DEFAULT '',
+			nonce TEXT NOT NULL,
+			code_verifier TEXT NOT NULL,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			expires_at DATETIME NOT NULL
+		)
+	`)
+	if err != nil {
+		t.Fatalf("Failed to create OIDC tables: %v", err)
+	}
+
+	_, err = db.Exec(`
+		CREATE TABLE personal_access_tokens (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			user_id INTEGER NOT NULL,
+			name TEXT NOT NULL,
+			token_hash TEXT UNIQUE NOT NULL,
+			token_prefix TEXT NOT NULL,
+			scopes TEXT NOT NULL,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			expires_at DATETIME DEFAULT NULL,
+			last_used_at DATETIME DEFAULT NULL,
+			revoked_at DATETIME DEFAULT NULL
+		)
+	`)
+	if err != nil {
+		t.Fatalf("Failed to create personal access tokens table: %v", err)
+	}
+
+	_, err = db.Exec(`
+		CREATE TABLE jwt_signing_keys (
+			id TEXT PRIMARY KEY,
+			algorithm TEXT NOT NULL,
+			private_key TEXT NOT NULL,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			retired_at DATETIME DEFAULT NULL
+		)
+	`)
+	if err != nil {
+		t.Fatalf("Failed to create signing keys table: %v", err)
+	}
+
 	return NewRepository(db)
 }
 
@@ -123,3 +272,106 @@ func TestComparePassword(t *testing.T) {
 		assert.Error(t, err)
 	})
 }
+
+func TestRotateSession(t *testing.T) {
+	repo := setupTestDB(t)
+
+	now := time.Now().UTC()
+	session := &models.Session{UserID: 1, FamilyID: "family", TokenHash: "first", CreatedAt: now, ExpiresAt: now.Add(time.Hour)}
+	assert.NoError(t, repo.CreateSession(session))
+
+	next := &models.Session{UserID: 1, FamilyID: "family", TokenHash: "second", CreatedAt: now, ExpiresAt: now.Add(time.Hour)}
+	assert.NoError(t, repo.RotateSession(session.ID, next))
+	assert.NotZero(t, next.ID)
+
+	rotated, err := repo.GetSessionByTokenHash("first")
+	assert.NoError(t, err)
+	assert.NotNil(t, rotated.RotatedAt)
+
+	t.Run("already rotated", func(t *testing.T) {
+		other := &models.Session{UserID: 1, FamilyID: "family", TokenHash: "third", CreatedAt: now, ExpiresAt: now.Add(time.Hour)}
+		assert.ErrorIs(t, repo.RotateSession(session.ID, other), ErrSessionAlreadyRotated)
+
+		_, err := repo.GetSessionByTokenHash("third")
+		assert.Equal(t, sql.ErrNoRows, err)
+	})
+
+	t.Run("revoke family", func(t *testing.T) {
+		assert.NoError(t, repo.RevokeSessionFamily("family"))
+
+		for _, hash := range []string{"first", "second"} {
+			revoked, err := repo.GetSessionByTokenHash(hash)
+			assert.NoError(t, err)
+			assert.NotNil(t, revoked.RevokedAt)
+		}
+	})
+}
+
+func TestGetActiveSessions(t *testing.T) {
+	repo := setupTestDB(t)
+
+	now := time.Now().UTC()
+	first := &models.Session{UserID: 1, FamilyID: "phone", TokenHash: "a", UserAgent: "phone", CreatedAt: now.Add(-time.Hour), ExpiresAt: now.Add(time.Hour)}
+	assert.NoError(t, repo.CreateSession(first))
+	rotated := &models.Session{UserID: 1, FamilyID: "phone", TokenHash: "b", UserAgent: "phone", CreatedAt: now, ExpiresAt: now.Add(time.Hour)}
+	assert.NoError(t, repo.RotateSession(first.ID, rotated))
+	assert.NoError(t, repo.CreateSession(&models.Session{UserID: 1, FamilyID: "laptop", TokenHash: "c", CreatedAt: now, ExpiresAt: now.Add(-time.Minute)}))
+	assert.NoError(t, repo.CreateSession(&models.Session{UserID: 2, FamilyID: "other", TokenHash: "d", CreatedAt: now, ExpiresAt: now.Add(time.Hour)}))
+
+	sessions, err := repo.GetActiveSessions(1)
+	assert.NoError(t, err)
+	if assert.Len(t, sessions, 1) {
+		assert.Equal(t, "phone", sessions[0].FamilyID)
+		assert.WithinDuration(t, now.Add(-time.Hour), sessions[0].SignedInAt, time.Second)
+		assert.WithinDuration(t, now, sessions[0].LastActiveAt, time.Second)
+	}
+
+	assert.Equal(t, sql.ErrNoRows, repo.RevokeUserSessionFamily(2, "phone"))
+	assert.NoError(t, repo.RevokeUserSessionFamily(1, "phone"))
+
+	revoked, err := repo.IsSessionFamilyRevoked("phone")
+	assert.NoError(t, err)
+	assert.True(t, revoked)
+}
+
+func TestRevokeToken(t *testing.T) {
+	repo := setupTestDB(t)
+
+	assert.NoError(t, repo.RevokeToken("expired", 1, time.Now().Add(-time.Minute)))
+	assert.NoError(t, repo.RevokeToken("active", 1, time.Now().Add(time.Minute)))
+
+	revoked, err := repo.IsTokenRevoked("active")
+	assert.NoError(t, err)
+	assert.True(t, revoked)
+
+	// Expired tokens are purged from the denylist
+	revoked, err = repo.IsTokenRevoked("expired")
+	assert.NoError(t, err)
+	assert.False(t, revoked)
+}
+
+func TestConsumeUserToken(t *testing.T) {
+	repo := setupTestDB(t)
+
+	assert.NoError(t, repo.CreateUserToken(1, models.PasswordResetToken, "valid", time.Now().Add(time.Hour)))
+	assert.NoError(t, repo.CreateUserToken(1, models.PasswordResetToken, "expired", time.Now().Add(-time.Minute)))
+
+	t.Run("wrong purpose", func(t *testing.T) {
+		_, err := repo.ConsumeUserToken("valid", models.EmailVerificationToken)
+		assert.Equal(t, sql.ErrNoRows, err)
+	})
+
+	t.Run("single use", func(t *testing.T) {
+		userID, err := repo.ConsumeUserToken("valid", models.PasswordResetToken)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(1), userID)
+
+		_, err = repo.ConsumeUserToken("valid", models.PasswordResetToken)
+		assert.Equal(t, sql.ErrNoRows, err)
+	})
+
+	t.Run("expired", func(t *testing.T) {
+		_, err := repo.ConsumeUserToken("expired", models.PasswordResetToken)
+		assert.Equal(t, sql.ErrNoRows, err)
+	})
+}
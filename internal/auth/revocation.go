@@ -0,0 +1,76 @@
+package auth
+
+import (
+	"sync"
+	"time"
+)
+
+// How long a "not revoked" answer is trusted. Revocations made by this instance
+// are seen at once, the ones made by another instance within this delay.
+const revocationNegativeTTL = 30 * time.Second
+
+// Size above which expired entries are swept
+const revocationCacheSweepSize = 10000
+
+type revocationEntry struct {
+	revoked   bool
+	expiresAt time.Time
+}
+
+// revocationCache remembers the revocation status of tokens and sessions,
+// so that authenticated requests do not all hit the database
+type revocationCache struct {
+	mu      sync.Mutex
+	entries map[string]revocationEntry
+	now     func() time.Time
+}
+
+func newRevocationCache() *revocationCache {
+	return &revocationCache{
+		entries: make(map[string]revocationEntry),
+		now:     time.Now,
+	}
+}
+
+func (c *revocationCache) get(key string) (revoked bool, ok bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[key]
+	if !ok || !c.now().Before(entry.expiresAt) {
+		return false, false
+	}
+	return entry.revoked, true
+}
+
+// setRevoked remembers a revocation until the given time, when the tokens it applies to have expired
+func (c *revocationCache) setRevoked(key string, until time.Time) {
+	c.set(key, revocationEntry{revoked: true, expiresAt: until})
+}
+
+func (c *revocationCache) setNotRevoked(key string) {
+	c.set(key, revocationEntry{revoked: false, expiresAt: c.now().Add(revocationNegativeTTL)})
+}
+
+func (c *revocationCache) set(key string, entry revocationEntry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if len(c.entries) >= revocationCacheSweepSize {
+		now := c.now()
+		for key, existing := range c.entries {
+			if !now.Before(existing.expiresAt) {
+				delete(c.entries, key)
+			}
+		}
+	}
+	c.entries[key] = entry
+}
+
+func tokenCacheKey(jti string) string {
+	return "jti:" + jti
+}
+
+func sessionCacheKey(sessionID string) string {
+	return "sid:" + sessionID
+}
@@ -1,70 +1,596 @@
 package auth
 
 import (
+	"database/sql"
 	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
+	"go-sober/internal/audit"
+	"go-sober/internal/dtos"
+	"go-sober/internal/mailer"
 	"go-sober/internal/models"
+	"go-sober/internal/validation"
 	"go-sober/platform"
 
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
 )
 
+var (
+	ErrInvalidRefreshToken  = errors.New("invalid refresh token")
+	ErrRefreshTokenReused   = errors.New("refresh token reused")
+	ErrInvalidUserToken     = errors.New("invalid or expired token")
+	ErrEmailAlreadyVerified = errors.New("email already verified")
+	ErrInvalidCredentials   = errors.New("invalid credentials")
+	ErrAccountDisabled      = errors.New("account disabled")
+
+	errUnknownEmail  = fmt.Errorf("%w: unknown email", ErrInvalidCredentials)
+	errWrongPassword = fmt.Errorf("%w: wrong password", ErrInvalidCredentials)
+)
+
+// LoginThrottledError rejects a login attempt made too soon after failed ones
+type LoginThrottledError struct {
+	RetryAfter time.Duration
+}
+
+func (e *LoginThrottledError) Error() string {
+	return fmt.Sprintf("too many failed logins, retry in %s", e.RetryAfter.Round(time.Second))
+}
+
 type Service struct {
-	repo   *Repository
-	config *platform.Config
+	repo        *Repository
+	config      *platform.Config
+	mailer      mailer.Mailer
+	passwords   *validation.PasswordPolicy
+	auditLog    *audit.Repository
+	keys        *keySet
+	revocations *revocationCache
+	logins      *loginThrottle
+	oidc        *oidcProvider // nil when OIDC is disabled
+	dummyHash   string        // Compared for unknown emails, so that they take as long as known ones
 }
 
-func NewService(repo *Repository, config *platform.Config) *Service {
-	return &Service{
-		repo:   repo,
-		config: config,
+func NewService(repo *Repository, config *platform.Config, mailer mailer.Mailer, passwords *validation.PasswordPolicy, auditLog *audit.Repository) (*Service, error) {
+	dummyHash, err := bcrypt.GenerateFromPassword([]byte(uuid.NewString()), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, err
+	}
+
+	keys, err := newKeySet(repo, config.Auth)
+	if err != nil {
+		return nil, err
 	}
+
+	var oidc *oidcProvider
+	if config.Auth.OIDC.Issuer != "" {
+		oidc = newOIDCProvider(config.Auth.OIDC, &http.Client{Timeout: 10 * time.Second})
+	}
+
+	return &Service{
+		repo:        repo,
+		config:      config,
+		mailer:      mailer,
+		passwords:   passwords,
+		auditLog:    auditLog,
+		keys:        keys,
+		revocations: newRevocationCache(),
+		logins:      newLoginThrottle(config.Auth.Login),
+		oidc:        oidc,
+		dummyHash:   string(dummyHash),
+	}, nil
 }
 
-func (s *Service) GenerateToken(user *models.User) (string, error) {
+// CheckPassword returns a *validation.Error when a password breaks the password policy
+func (s *Service) CheckPassword(password, email string) error {
+	return s.passwords.Check(password, email)
+}
+
+// GenerateToken issues an access token for a session (a refresh token family)
+func (s *Service) GenerateToken(user *models.User, sessionID string) (string, error) {
 	claims := &models.Claims{
-		UserID: user.ID,
-		Email:  user.Email,
+		UserID:    user.ID,
+		Email:     user.Email,
+		SessionID: sessionID,
+		Role:      user.Role,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(24 * time.Hour)),
+			ID:        uuid.NewString(),
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(s.config.Auth.JWT.AccessTokenTTL)),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 			NotBefore: jwt.NewNumericDate(time.Now()),
 		},
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(s.config.Auth.JWT.Secret))
+	return s.keys.sign(claims)
 }
 
 func (s *Service) ValidateToken(tokenString string) (*models.Claims, error) {
-	token, err := jwt.ParseWithClaims(tokenString, &models.Claims{}, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, errors.New("unexpected signing method")
-		}
-		return []byte(s.config.Auth.JWT.Secret), nil
-	})
-
+	token, err := s.keys.parse(tokenString, &models.Claims{})
 	if err != nil {
 		return nil, err
 	}
 
-	if claims, ok := token.Claims.(*models.Claims); ok && token.Valid {
+	// Tokens without an ID or a session cannot be revoked
+	if claims, ok := token.Claims.(*models.Claims); ok && token.Valid && claims.ID != "" && claims.SessionID != "" {
 		return claims, nil
 	}
 
 	return nil, errors.New("invalid token")
 }
 
+// JWKS returns the public keys verifying the access tokens, there are none with HS256 alone
+func (s *Service) JWKS() ([]dtos.JSONWebKey, error) {
+	return s.keys.publicKeys()
+}
+
+// AuthenticateUser checks credentials, wrong ones return an error wrapping ErrInvalidCredentials.
+// Unknown emails cost a bcrypt comparison too, the response time does not tell whether an account exists.
 func (s *Service) AuthenticateUser(email, password string) (*models.User, error) {
 	user, err := s.repo.GetUserByEmail(email)
+	if errors.Is(err, sql.ErrNoRows) {
+		s.repo.ComparePassword(s.dummyHash, password)
+		return nil, errUnknownEmail
+	}
 	if err != nil {
 		return nil, err
 	}
 
 	if err := s.repo.ComparePassword(user.Password, password); err != nil {
-		return nil, err
+		return user, errWrongPassword
 	}
 
 	return user, nil
 }
+
+// Login is the password step of a login, with brute-force protection: failed attempts slow down
+// then lock the account and the client address, and every failure is recorded in the audit log.
+// Throttled attempts return a *LoginThrottledError. Users with two-factor authentication get
+// an MFA challenge token instead of the tokens, to answer with CompleteMFALogin.
+func (s *Service) Login(email, password, ipAddress, userAgent string) (*models.LoginResult, error) {
+	user, err := s.checkPassword(email, password, ipAddress, userAgent)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := s.startLogin(user, ipAddress, userAgent)
+	if err != nil {
+		return nil, err
+	}
+
+	// With MFA, the failures are only forgotten once the second factor is checked too
+	if result.Tokens != nil {
+		s.logins.reset(loginAccountKey(email))
+	}
+	return result, nil
+}
+
+// startLogin creates a session for an authenticated user, or an MFA challenge when the user has MFA
+func (s *Service) startLogin(user *models.User, ipAddress, userAgent string) (*models.LoginResult, error) {
+	if user.DisabledAt != nil {
+		return nil, ErrAccountDisabled
+	}
+
+	mfaEnabled, err := s.mfaEnabled(user.ID)
+	if err != nil {
+		return nil, err
+	}
+	if mfaEnabled {
+		mfaToken, err := s.newMFAChallenge(user)
+		if err != nil {
+			return nil, err
+		}
+		return &models.LoginResult{MFAToken: mfaToken}, nil
+	}
+
+	tokens, err := s.CreateSession(user, userAgent, ipAddress)
+	if err != nil {
+		return nil, err
+	}
+	return &models.LoginResult{Tokens: tokens}, nil
+}
+
+// checkPassword authenticates a user unless the account or the address is throttled
+func (s *Service) checkPassword(email, password, ipAddress, userAgent string) (*models.User, error) {
+	accountKey := loginAccountKey(email)
+	ipKey := "ip:" + ipAddress
+	accountLimits, ipLimits := s.loginLimits()
+
+	wait := max(s.logins.wait(accountKey, accountLimits), s.logins.wait(ipKey, ipLimits))
+	if wait > 0 {
+		s.recordAuditEvent(models.AuditLoginFailed, nil, email, ipAddress, userAgent, map[string]string{"reason": "throttled"})
+		return nil, &LoginThrottledError{RetryAfter: wait}
+	}
+
+	user, err := s.AuthenticateUser(email, password)
+	if err == nil || !errors.Is(err, ErrInvalidCredentials) {
+		return user, err
+	}
+
+	// Unknown emails are throttled like accounts, so they cannot be told apart
+	accountFailures := s.logins.fail(accountKey)
+	ipFailures := s.logins.fail(ipKey)
+
+	var userID *int64
+	reason := "unknown_email"
+	if errors.Is(err, errWrongPassword) {
+		userID = &user.ID
+		reason = "wrong_password"
+	}
+	s.recordAuditEvent(models.AuditLoginFailed, userID, email, ipAddress, userAgent, map[string]string{
+		"reason":           reason,
+		"account_failures": strconv.Itoa(accountFailures),
+		"ip_failures":      strconv.Itoa(ipFailures),
+	})
+	s.recordLockout(accountFailures, userID, email, ipAddress, userAgent)
+
+	return nil, ErrInvalidCredentials
+}
+
+func (s *Service) loginLimits() (account loginLimits, ip loginLimits) {
+	loginConfig := s.config.Auth.Login
+	account = loginLimits{freeAttempts: loginConfig.FreeAttempts, lockoutThreshold: loginConfig.LockoutThreshold}
+	ip = loginLimits{freeAttempts: loginConfig.IPFreeAttempts, lockoutThreshold: loginConfig.IPLockoutThreshold}
+	return account, ip
+}
+
+// recordLockout audits the failure that locks an account
+func (s *Service) recordLockout(accountFailures int, userID *int64, email, ipAddress, userAgent string) {
+	if accountFailures != s.config.Auth.Login.LockoutThreshold {
+		return
+	}
+	s.recordAuditEvent(models.AuditAccountLocked, userID, email, ipAddress, userAgent, map[string]string{
+		"duration": s.config.Auth.Login.LockoutDuration.String(),
+	})
+}
+
+func loginAccountKey(email string) string {
+	return "account:" + strings.ToLower(strings.TrimSpace(email))
+}
+
+// recordAuditEvent writes to the audit log, a failure to do so must not change the outcome of the action
+func (s *Service) recordAuditEvent(eventType models.AuditEventType, userID *int64, email, ipAddress, userAgent string, details map[string]string) {
+	event := &models.AuditEvent{
+		UserID:    userID,
+		Type:      eventType,
+		Email:     email,
+		IPAddress: ipAddress,
+		UserAgent: userAgent,
+		Details:   details,
+	}
+	if err := s.auditLog.Record(event); err != nil {
+		slog.Error("Could not record audit event", "type", eventType, "error", err)
+	}
+}
+
+// CreateSession starts a new token family for a device and returns its first tokens
+func (s *Service) CreateSession(user *models.User, userAgent, ipAddress string) (*models.TokenPair, error) {
+	if user.DisabledAt != nil {
+		return nil, ErrAccountDisabled
+	}
+	if user.DeletionRequestedAt != nil {
+		if err := s.cancelAccountDeletion(user, ipAddress, userAgent); err != nil {
+			return nil, err
+		}
+	}
+
+	refreshToken, session, err := s.newSession(user.ID, uuid.NewString(), userAgent, ipAddress)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.CreateSession(session); err != nil {
+		return nil, err
+	}
+
+	return s.tokenPair(user, session.FamilyID, refreshToken)
+}
+
+// RefreshSession exchanges a refresh token for new tokens. A refresh token can be used once:
+// presenting it again means it leaked, so the whole family is revoked.
+func (s *Service) RefreshSession(refreshToken, userAgent, ipAddress string) (*models.TokenPair, error) {
+	session, err := s.repo.GetSessionByTokenHash(hashToken(refreshToken))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrInvalidRefreshToken
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if session.RevokedAt != nil || !time.Now().Before(session.ExpiresAt) {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	if session.RotatedAt != nil {
+		return nil, s.revokeReusedFamily(session)
+	}
+
+	// Checked before rotating, so that a refresh racing with the account being disabled gets nothing
+	user, err := s.repo.GetUserByID(session.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if user.DisabledAt != nil {
+		return nil, ErrAccountDisabled
+	}
+
+	nextToken, next, err := s.newSession(session.UserID, session.FamilyID, userAgent, ipAddress)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.RotateSession(session.ID, next); err != nil {
+		if errors.Is(err, ErrSessionAlreadyRotated) {
+			return nil, s.revokeReusedFamily(session)
+		}
+		return nil, err
+	}
+
+	return s.tokenPair(user, next.FamilyID, nextToken)
+}
+
+func (s *Service) revokeReusedFamily(session *models.Session) error {
+	slog.Warn("Refresh token reused, revoking the session family", "user_id", session.UserID, "family_id", session.FamilyID)
+	if err := s.repo.RevokeSessionFamily(session.FamilyID); err != nil {
+		return err
+	}
+	s.cacheRevocation(sessionCacheKey(session.FamilyID), true)
+	return ErrRefreshTokenReused
+}
+
+func (s *Service) newSession(userID int64, familyID, userAgent, ipAddress string) (string, *models.Session, error) {
+	refreshToken, err := newOpaqueToken()
+	if err != nil {
+		return "", nil, err
+	}
+
+	now := time.Now().UTC()
+	session := &models.Session{
+		UserID:    userID,
+		FamilyID:  familyID,
+		TokenHash: hashToken(refreshToken),
+		UserAgent: userAgent,
+		IPAddress: ipAddress,
+		CreatedAt: now,
+		ExpiresAt: now.Add(s.config.Auth.JWT.RefreshTokenTTL),
+	}
+	return refreshToken, session, nil
+}
+
+func (s *Service) tokenPair(user *models.User, sessionID, refreshToken string) (*models.TokenPair, error) {
+	accessToken, err := s.GenerateToken(user, sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.TokenPair{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int64(s.config.Auth.JWT.AccessTokenTTL.Seconds()),
+	}, nil
+}
+
+// IsTokenRevoked reports whether an access token, or the session it belongs to, was revoked
+func (s *Service) IsTokenRevoked(claims *models.Claims) (bool, error) {
+	tokenKey := tokenCacheKey(claims.ID)
+	revoked, ok := s.revocations.get(tokenKey)
+	if !ok {
+		var err error
+		if revoked, err = s.repo.IsTokenRevoked(claims.ID); err != nil {
+			return false, err
+		}
+		s.cacheRevocation(tokenKey, revoked)
+	}
+	if revoked {
+		return true, nil
+	}
+
+	sessionKey := sessionCacheKey(claims.SessionID)
+	revoked, ok = s.revocations.get(sessionKey)
+	if !ok {
+		var err error
+		if revoked, err = s.repo.IsSessionFamilyRevoked(claims.SessionID); err != nil {
+			return false, err
+		}
+		s.cacheRevocation(sessionKey, revoked)
+	}
+	return revoked, nil
+}
+
+// Logout revokes the access token and the session it belongs to
+func (s *Service) Logout(claims *models.Claims) error {
+	if err := s.repo.RevokeSessionFamily(claims.SessionID); err != nil {
+		return err
+	}
+	s.cacheRevocation(sessionCacheKey(claims.SessionID), true)
+
+	return s.revokeToken(claims)
+}
+
+// LogoutAll revokes the access token and every session of the user
+func (s *Service) LogoutAll(claims *models.Claims) error {
+	if err := s.revokeAllSessions(claims.UserID); err != nil {
+		return err
+	}
+	s.cacheRevocation(sessionCacheKey(claims.SessionID), true)
+
+	return s.revokeToken(claims)
+}
+
+// revokeAllSessions logs every device of a user out
+func (s *Service) revokeAllSessions(userID int64) error {
+	sessions, err := s.repo.GetActiveSessions(userID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.repo.RevokeUserSessions(userID); err != nil {
+		return err
+	}
+	for _, session := range sessions {
+		s.cacheRevocation(sessionCacheKey(session.FamilyID), true)
+	}
+	return nil
+}
+
+// revokeOtherSessions logs every device of a user out, but the one of a session
+func (s *Service) revokeOtherSessions(userID int64, keepSessionID string) error {
+	sessions, err := s.repo.GetActiveSessions(userID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.repo.RevokeOtherUserSessions(userID, keepSessionID); err != nil {
+		return err
+	}
+	for _, session := range sessions {
+		if session.FamilyID != keepSessionID {
+			s.cacheRevocation(sessionCacheKey(session.FamilyID), true)
+		}
+	}
+	return nil
+}
+
+// GetSessions lists the logged in devices of a user
+func (s *Service) GetSessions(userID int64) ([]models.DeviceSession, error) {
+	return s.repo.GetActiveSessions(userID)
+}
+
+// RevokeSession logs a device of the user out, sql.ErrNoRows if it is not an active device of the user
+func (s *Service) RevokeSession(userID int64, sessionID string) error {
+	if err := s.repo.RevokeUserSessionFamily(userID, sessionID); err != nil {
+		return err
+	}
+	s.cacheRevocation(sessionCacheKey(sessionID), true)
+	return nil
+}
+
+func (s *Service) revokeToken(claims *models.Claims) error {
+	expiresAt := time.Now().Add(s.config.Auth.JWT.AccessTokenTTL)
+	if claims.ExpiresAt != nil {
+		expiresAt = claims.ExpiresAt.Time
+	}
+
+	if err := s.repo.RevokeToken(claims.ID, claims.UserID, expiresAt); err != nil {
+		return err
+	}
+	s.revocations.setRevoked(tokenCacheKey(claims.ID), expiresAt)
+	return nil
+}
+
+// cacheRevocation caches a revocation status. A revoked session only matters
+// while the access tokens issued for it are valid.
+func (s *Service) cacheRevocation(key string, revoked bool) {
+	if revoked {
+		s.revocations.setRevoked(key, time.Now().Add(s.config.Auth.JWT.AccessTokenTTL))
+		return
+	}
+	s.revocations.setNotRevoked(key)
+}
+
+// SendVerificationEmail emails a link confirming the address of a user
+func (s *Service) SendVerificationEmail(user *models.User) error {
+	if user.EmailVerifiedAt != nil {
+		return ErrEmailAlreadyVerified
+	}
+
+	// Only the last link sent is valid
+	if err := s.repo.InvalidateUserTokens(user.ID, models.EmailVerificationToken); err != nil {
+		return err
+	}
+
+	token, err := s.createUserToken(user.ID, models.EmailVerificationToken, emailVerificationTTL)
+	if err != nil {
+		return err
+	}
+
+	return s.mailer.Send(verificationEmail(user.Email, s.config.BaseURL, token))
+}
+
+// VerifyEmail confirms the email address of the user the token was sent to
+func (s *Service) VerifyEmail(token string) error {
+	userID, err := s.repo.ConsumeUserToken(hashToken(token), models.EmailVerificationToken)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrInvalidUserToken
+	}
+	if err != nil {
+		return err
+	}
+
+	return s.repo.MarkEmailVerified(userID)
+}
+
+// RequestPasswordReset emails a password reset link. Unknown emails are
+// silently ignored, so that the response does not tell which accounts exist.
+func (s *Service) RequestPasswordReset(email string) error {
+	user, err := s.repo.GetUserByEmail(email)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	token, err := s.createUserToken(user.ID, models.PasswordResetToken, passwordResetTTL)
+	if err != nil {
+		return err
+	}
+
+	return s.mailer.Send(passwordResetEmail(user.Email, s.config.BaseURL, token))
+}
+
+// ResetPassword sets a new password with a reset token and logs every device out
+func (s *Service) ResetPassword(token, password string) error {
+	// The token stays usable when the new password is rejected
+	userID, err := s.repo.FindUserToken(hashToken(token), models.PasswordResetToken)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrInvalidUserToken
+	}
+	if err != nil {
+		return err
+	}
+
+	user, err := s.repo.GetUserByID(userID)
+	if err != nil {
+		return err
+	}
+	if err := s.CheckPassword(password, user.Email); err != nil {
+		return err
+	}
+
+	userID, err = s.repo.ConsumeUserToken(hashToken(token), models.PasswordResetToken)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrInvalidUserToken
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := s.repo.UpdatePassword(userID, password); err != nil {
+		return err
+	}
+
+	// Other reset links must not work anymore
+	if err := s.repo.InvalidateUserTokens(userID, models.PasswordResetToken); err != nil {
+		return err
+	}
+
+	// The reset may follow a compromise, whoever had the password must be logged out
+	return s.revokeAllSessions(userID)
+}
+
+func (s *Service) createUserToken(userID int64, purpose models.UserTokenPurpose, ttl time.Duration) (string, error) {
+	token, err := newOpaqueToken()
+	if err != nil {
+		return "", err
+	}
+
+	if err := s.repo.CreateUserToken(userID, purpose, hashToken(token), time.Now().Add(ttl)); err != nil {
+		return "", err
+	}
+	return token, nil
+}
@@ -0,0 +1,286 @@
+package auth
+
+import (
+	"regexp"
+	"testing"
+	"time"
+
+	"go-sober/internal/audit"
+	"go-sober/internal/mailer"
+	"go-sober/internal/models"
+	"go-sober/internal/validation"
+	"go-sober/platform"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeMailer keeps the emails instead of sending them
+type fakeMailer struct {
+	messages []mailer.Message
+}
+
+func (m *fakeMailer) Send(message mailer.Message) error {
+	m.messages = append(m.messages, message)
+	return nil
+}
+
+// lastToken extracts the token of the link of the last email sent
+func (m *fakeMailer) lastToken(t *testing.T) string {
+	t.Helper()
+	require.NotEmpty(t, m.messages)
+	match := regexp.MustCompile(`token=([A-Za-z0-9_-]+)`).FindStringSubmatch(m.messages[len(m.messages)-1].Body)
+	require.NotNil(t, match)
+	return match[1]
+}
+
+func setupTestService(t *testing.T) *Service {
+	return setupTestServiceWithConfig(t, testConfig())
+}
+
+func testConfig() *platform.Config {
+	config := &platform.Config{}
+	config.BaseURL = "http://localhost:3000"
+	config.Auth.JWT.Algorithm = AlgorithmHS256
+	config.Auth.JWT.Secret = "test-secret"
+	config.Auth.JWT.KeyRefreshInterval = time.Minute
+	config.Auth.JWT.AccessTokenTTL = 15 * time.Minute
+	config.Auth.JWT.RefreshTokenTTL = time.Hour
+	config.Auth.Password = platform.PasswordConfig{MinLength: 10, MaxEmailSimilarity: 0.7}
+
+	config.Auth.Login = platform.LoginConfig{
+		FreeAttempts:       3,
+		BaseDelay:          time.Second,
+		MaxDelay:           time.Minute,
+		LockoutThreshold:   10,
+		IPFreeAttempts:     10,
+		IPLockoutThreshold: 50,
+		LockoutDuration:    15 * time.Minute,
+	}
+	config.Auth.MFA = platform.MFAConfig{Issuer: "Sober", ChallengeTTL: 5 * time.Minute}
+	config.Auth.Account = platform.AccountConfig{DeletionGracePeriod: 720 * time.Hour, PurgeInterval: time.Hour}
+	return config
+}
+
+func setupTestServiceWithConfig(t *testing.T, config *platform.Config) *Service {
+	passwords, err := validation.NewPasswordPolicy(config.Auth.Password)
+	require.NoError(t, err)
+
+	repo := setupTestDB(t)
+	service, err := NewService(repo, config, &fakeMailer{}, passwords, audit.NewRepository(repo.db))
+	require.NoError(t, err)
+	return service
+}
+
+func createTestUser(t *testing.T, service *Service) *models.User {
+	require.NoError(t, service.repo.CreateUser("test@example.com", "password123"))
+	user, err := service.repo.GetUserByEmail("test@example.com")
+	require.NoError(t, err)
+	return user
+}
+
+func TestRefreshSession(t *testing.T) {
+	service := setupTestService(t)
+	user := createTestUser(t, service)
+
+	tokens, err := service.CreateSession(user, "test-agent", "127.0.0.1")
+	require.NoError(t, err)
+	assert.Equal(t, int64(900), tokens.ExpiresIn)
+
+	claims, err := service.ValidateToken(tokens.AccessToken)
+	require.NoError(t, err)
+	assert.Equal(t, user.ID, claims.UserID)
+
+	refreshed, err := service.RefreshSession(tokens.RefreshToken, "test-agent", "127.0.0.1")
+	require.NoError(t, err)
+	assert.NotEqual(t, tokens.RefreshToken, refreshed.RefreshToken)
+
+	t.Run("unknown token", func(t *testing.T) {
+		_, err := service.RefreshSession("unknown", "test-agent", "127.0.0.1")
+		assert.ErrorIs(t, err, ErrInvalidRefreshToken)
+	})
+
+	t.Run("reuse revokes the family", func(t *testing.T) {
+		refreshedClaims, err := service.ValidateToken(refreshed.AccessToken)
+		require.NoError(t, err)
+		revoked, err := service.IsTokenRevoked(refreshedClaims)
+		require.NoError(t, err)
+		require.False(t, revoked)
+
+		_, err = service.RefreshSession(tokens.RefreshToken, "attacker", "10.0.0.1")
+		assert.ErrorIs(t, err, ErrRefreshTokenReused)
+
+		// The access tokens of the family are rejected at once, not when the cached lookup expires
+		revoked, err = service.IsTokenRevoked(refreshedClaims)
+		require.NoError(t, err)
+		assert.True(t, revoked)
+
+		// The legitimate token of the family is revoked too
+		_, err = service.RefreshSession(refreshed.RefreshToken, "test-agent", "127.0.0.1")
+		assert.ErrorIs(t, err, ErrInvalidRefreshToken)
+	})
+}
+
+func TestLogout(t *testing.T) {
+	service := setupTestService(t)
+	user := createTestUser(t, service)
+
+	phone, err := service.CreateSession(user, "phone", "127.0.0.1")
+	require.NoError(t, err)
+	laptop, err := service.CreateSession(user, "laptop", "127.0.0.1")
+	require.NoError(t, err)
+
+	phoneClaims, err := service.ValidateToken(phone.AccessToken)
+	require.NoError(t, err)
+	laptopClaims, err := service.ValidateToken(laptop.AccessToken)
+	require.NoError(t, err)
+
+	revoked, err := service.IsTokenRevoked(phoneClaims)
+	require.NoError(t, err)
+	assert.False(t, revoked)
+
+	sessions, err := service.GetSessions(user.ID)
+	require.NoError(t, err)
+	assert.Len(t, sessions, 2)
+
+	require.NoError(t, service.Logout(phoneClaims))
+
+	revoked, err = service.IsTokenRevoked(phoneClaims)
+	require.NoError(t, err)
+	assert.True(t, revoked)
+
+	_, err = service.RefreshSession(phone.RefreshToken, "phone", "127.0.0.1")
+	assert.ErrorIs(t, err, ErrInvalidRefreshToken)
+
+	// The other device is still logged in
+	revoked, err = service.IsTokenRevoked(laptopClaims)
+	require.NoError(t, err)
+	assert.False(t, revoked)
+
+	t.Run("log out all devices", func(t *testing.T) {
+		require.NoError(t, service.LogoutAll(laptopClaims))
+
+		revoked, err := service.IsTokenRevoked(laptopClaims)
+		require.NoError(t, err)
+		assert.True(t, revoked)
+
+		sessions, err := service.GetSessions(user.ID)
+		require.NoError(t, err)
+		assert.Empty(t, sessions)
+	})
+
+	t.Run("revocation survives a restart", func(t *testing.T) {
+		service.revocations = newRevocationCache()
+
+		revoked, err := service.IsTokenRevoked(phoneClaims)
+		require.NoError(t, err)
+		assert.True(t, revoked)
+	})
+}
+
+func TestVerifyEmail(t *testing.T) {
+	service := setupTestService(t)
+	mail := service.mailer.(*fakeMailer)
+	user := createTestUser(t, service)
+
+	require.NoError(t, service.SendVerificationEmail(user))
+	assert.Equal(t, user.Email, mail.messages[0].To)
+	first := mail.lastToken(t)
+
+	// Sending a new link invalidates the previous one
+	require.NoError(t, service.SendVerificationEmail(user))
+	assert.ErrorIs(t, service.VerifyEmail(first), ErrInvalidUserToken)
+
+	require.NoError(t, service.VerifyEmail(mail.lastToken(t)))
+
+	user, err := service.repo.GetUserByID(user.ID)
+	require.NoError(t, err)
+	assert.NotNil(t, user.EmailVerifiedAt)
+	assert.ErrorIs(t, service.SendVerificationEmail(user), ErrEmailAlreadyVerified)
+}
+
+func TestResetPassword(t *testing.T) {
+	service := setupTestService(t)
+	mail := service.mailer.(*fakeMailer)
+	user := createTestUser(t, service)
+
+	tokens, err := service.CreateSession(user, "phone", "127.0.0.1")
+	require.NoError(t, err)
+
+	require.NoError(t, service.RequestPasswordReset("unknown@example.com"))
+	assert.Empty(t, mail.messages, "unknown emails must not receive anything")
+
+	require.NoError(t, service.RequestPasswordReset(user.Email))
+	token := mail.lastToken(t)
+
+	// A weak password is rejected without using the token
+	var validationError *validation.Error
+	assert.ErrorAs(t, service.ResetPassword(token, "short"), &validationError)
+
+	require.NoError(t, service.ResetPassword(token, "new-password"))
+	assert.ErrorIs(t, service.ResetPassword(token, "another-password"), ErrInvalidUserToken)
+
+	_, err = service.AuthenticateUser(user.Email, "new-password")
+	assert.NoError(t, err)
+
+	// Every device is logged out
+	_, err = service.RefreshSession(tokens.RefreshToken, "phone", "127.0.0.1")
+	assert.ErrorIs(t, err, ErrInvalidRefreshToken)
+}
+
+func TestLogin(t *testing.T) {
+	service := setupTestService(t)
+	user := createTestUser(t, service)
+	auditLog := audit.NewRepository(service.repo.db)
+
+	now := time.Now()
+	service.logins.now = func() time.Time { return now }
+
+	result, err := service.Login(user.Email, "password123", "10.0.0.1", "phone")
+	require.NoError(t, err)
+	require.NotNil(t, result.Tokens)
+	assert.Empty(t, result.MFAToken)
+
+	// Unknown emails and wrong passwords fail the same way
+	_, err = service.Login("nobody@example.com", "password123", "10.0.0.1", "phone")
+	assert.Equal(t, ErrInvalidCredentials, err)
+	_, err = service.Login(user.Email, "wrong", "10.0.0.1", "phone")
+	assert.Equal(t, ErrInvalidCredentials, err)
+
+	events, err := auditLog.GetUserEvents(user.ID, 10)
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Equal(t, models.AuditLoginFailed, events[0].Type)
+	assert.Equal(t, "wrong_password", events[0].Details["reason"])
+	assert.Equal(t, "10.0.0.1", events[0].IPAddress)
+
+	// After the free attempts, even the right password must wait
+	for i := 0; i < 2; i++ {
+		_, err = service.Login(user.Email, "wrong", "10.0.0.2", "phone")
+		assert.Equal(t, ErrInvalidCredentials, err)
+	}
+	var throttled *LoginThrottledError
+	_, err = service.Login(user.Email, "password123", "10.0.0.3", "phone")
+	require.ErrorAs(t, err, &throttled)
+	assert.Equal(t, time.Second, throttled.RetryAfter)
+
+	// Failures keep counting until the account is locked
+	for i := 3; i < service.config.Auth.Login.LockoutThreshold; i++ {
+		now = now.Add(time.Minute)
+		_, err = service.Login(user.Email, "wrong", "10.0.0.4", "phone")
+		assert.Equal(t, ErrInvalidCredentials, err)
+	}
+	now = now.Add(time.Minute)
+	_, err = service.Login(user.Email, "password123", "10.0.0.5", "phone")
+	require.ErrorAs(t, err, &throttled)
+	assert.Equal(t, 14*time.Minute, throttled.RetryAfter)
+
+	events, err = auditLog.GetUserEvents(user.ID, 1)
+	require.NoError(t, err)
+	assert.Equal(t, models.AuditAccountLocked, events[0].Type)
+
+	// The lockout is temporary
+	now = now.Add(14 * time.Minute)
+	_, err = service.Login(user.Email, "password123", "10.0.0.5", "phone")
+	assert.NoError(t, err)
+}
@@ -0,0 +1,116 @@
+package auth
+
+import (
+	"sync"
+	"time"
+
+	"go-sober/platform"
+)
+
+type loginFailures struct {
+	count int
+	last  time.Time
+}
+
+// loginThrottle counts the failed logins of a key (an account or a client address).
+// After a few free attempts each failure doubles the wait before the next attempt,
+// and past the lockout threshold the key is locked for the lockout duration.
+// Counts are forgotten once a key has not failed for the lockout duration.
+type loginThrottle struct {
+	mu        sync.Mutex
+	config    platform.LoginConfig
+	failures  map[string]*loginFailures
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+func newLoginThrottle(config platform.LoginConfig) *loginThrottle {
+	return &loginThrottle{
+		config:   config,
+		failures: make(map[string]*loginFailures),
+		now:      time.Now,
+	}
+}
+
+// loginLimits are the thresholds of a kind of key
+type loginLimits struct {
+	freeAttempts     int
+	lockoutThreshold int
+}
+
+// wait returns how long the key must wait before its next attempt
+func (t *loginThrottle) wait(key string, limits loginLimits) time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := t.now()
+	t.sweep(now)
+
+	failures, ok := t.failures[key]
+	if !ok {
+		return 0
+	}
+
+	until := failures.last.Add(t.delay(failures.count, limits))
+	return max(0, until.Sub(now))
+}
+
+// fail records a failed attempt and returns the number of failures of the key
+func (t *loginThrottle) fail(key string) int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := t.now()
+	failures, ok := t.failures[key]
+	if !ok || now.Sub(failures.last) >= t.forgetAfter() {
+		failures = &loginFailures{}
+		t.failures[key] = failures
+	}
+
+	failures.count++
+	failures.last = now
+	return failures.count
+}
+
+// reset forgets the failures of a key, after a successful login
+func (t *loginThrottle) reset(key string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	delete(t.failures, key)
+}
+
+// delay is the wait imposed after a number of failures
+func (t *loginThrottle) delay(count int, limits loginLimits) time.Duration {
+	if count >= limits.lockoutThreshold {
+		return t.config.LockoutDuration
+	}
+	if count < limits.freeAttempts {
+		return 0
+	}
+
+	delay := t.config.BaseDelay
+	for i := limits.freeAttempts; i < count && delay < t.config.MaxDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, t.config.MaxDelay)
+}
+
+func (t *loginThrottle) forgetAfter() time.Duration {
+	return max(t.config.LockoutDuration, t.config.MaxDelay)
+}
+
+// sweep forgets the keys that stopped failing, at most once per lockout duration
+func (t *loginThrottle) sweep(now time.Time) {
+	forgetAfter := t.forgetAfter()
+	if now.Sub(t.lastSweep) < forgetAfter {
+		return
+	}
+	t.lastSweep = now
+
+	for key, failures := range t.failures {
+		if now.Sub(failures.last) >= forgetAfter {
+			delete(t.failures, key)
+		}
+	}
+}
@@ -0,0 +1,41 @@
+package auth
+
+import (
+	"testing"
+	"time"
+
+	"go-sober/platform"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoginThrottle(t *testing.T) {
+	throttle := newLoginThrottle(platform.LoginConfig{
+		BaseDelay:       time.Second,
+		MaxDelay:        10 * time.Second,
+		LockoutDuration: 15 * time.Minute,
+	})
+	limits := loginLimits{freeAttempts: 3, lockoutThreshold: 8}
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	throttle.now = func() time.Time { return now }
+
+	// Free attempts, then 1s, 2s, 4s, 8s, capped at 10s, then the lockout
+	expected := []time.Duration{0, 0, 1 * time.Second, 2 * time.Second, 4 * time.Second, 8 * time.Second, 10 * time.Second, 15 * time.Minute}
+	for i, wait := range expected {
+		assert.Equal(t, i+1, throttle.fail("account:a"))
+		assert.Equal(t, wait, throttle.wait("account:a", limits), "after %d failures", i+1)
+	}
+
+	now = now.Add(14 * time.Minute)
+	assert.Equal(t, time.Minute, throttle.wait("account:a", limits))
+
+	// Once the lockout is over, the count starts again
+	now = now.Add(time.Minute)
+	assert.Zero(t, throttle.wait("account:a", limits))
+	assert.Equal(t, 1, throttle.fail("account:a"))
+
+	assert.Zero(t, throttle.wait("account:b", limits), "keys are independent")
+
+	throttle.reset("account:a")
+	assert.Zero(t, throttle.wait("account:a", limits))
+}
@@ -0,0 +1,66 @@
+package auth
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTOTPCode(t *testing.T) {
+	// Test vectors of RFC 6238, appendix B, for HMAC-SHA1
+	secret := []byte("12345678901234567890")
+	tests := []struct {
+		unix int64
+		code string
+	}{
+		{59, "94287082"},
+		{1111111109, "07081804"},
+		{1111111111, "14050471"},
+		{1234567890, "89005924"},
+		{2000000000, "69279037"},
+		{20000000000, "65353130"},
+	}
+
+	for _, tt := range tests {
+		step := totpStep(time.Unix(tt.unix, 0))
+		assert.Equal(t, tt.code, totpCode(secret, step, 8), "at %d", tt.unix)
+	}
+}
+
+func TestValidateTOTP(t *testing.T) {
+	secret, err := newTOTPSecret()
+	require.NoError(t, err)
+	rawSecret, err := totpEncoding.DecodeString(secret)
+	require.NoError(t, err)
+
+	now := time.Date(2024, 6, 1, 12, 0, 10, 0, time.UTC)
+	current := totpStep(now)
+
+	step, ok := validateTOTP(secret, totpCode(rawSecret, current, totpDigits), now)
+	assert.True(t, ok)
+	assert.Equal(t, current, step)
+
+	// The previous step is still accepted, for clock drift
+	step, ok = validateTOTP(secret, totpCode(rawSecret, current-1, totpDigits), now)
+	assert.True(t, ok)
+	assert.Equal(t, current-1, step)
+
+	_, ok = validateTOTP(secret, totpCode(rawSecret, current-2, totpDigits), now)
+	assert.False(t, ok)
+
+	_, ok = validateTOTP(secret, "12345", now)
+	assert.False(t, ok)
+}
+
+func TestTOTPProvisioningURI(t *testing.T) {
+	uri := totpProvisioningURI("Sober", "jane@example.com", "JBSWY3DPEHPK3PXP")
+
+	assert.True(t, strings.HasPrefix(uri, "otpauth://totp/Sober:jane@example.com?"))
+	assert.Contains(t, uri, "secret=JBSWY3DPEHPK3PXP")
+	assert.Contains(t, uri, "issuer=Sober")
+	assert.Contains(t, uri, "digits=6")
+	assert.Contains(t, uri, "period=30")
+}
@@ -11,6 +11,7 @@ import (
 	"go-sober/internal/mappers"
 	"go-sober/internal/models"
 	"go-sober/internal/params"
+	"go-sober/internal/validation"
 )
 
 type Controller struct {
@@ -22,14 +23,14 @@ func NewController(service *Service) *Controller {
 }
 
 // @Summary Get BAC calculation
-// @Description Calculate BAC for a user
+// @Description Calculate BAC for a user, each drink with the weight of the user's profile at the time it was logged
 // @Tags bac
 // @Accept json
 // @Produce json
-// @Param Authorization header string true "Bearer token"
+// @Param Authorization header string true "Bearer token, or personal access token with the bac:read scope"
 // @Param start_time query string true "Start time"
 // @Param end_time query string true "End time"
-// @Param weight_kg query float64 true "Weight in kg"
+// @Param weight_kg query float64 false "Weight in kg, for the drinks logged before the user had a weight in their profile (default: 70)"
 // @Param gender query string true "Gender"
 // @Param time_step_mins query int true "Time step in minutes"
 // @Success 200 {object} dtos.BACCalculationResponse
@@ -66,10 +67,12 @@ func (c *Controller) GetBAC(w http.ResponseWriter, r *http.Request) {
 	// Default weight
 	weightKg := 70.0
 	if weight := query.Get("weight_kg"); weight != "" {
-		if _, err := json.Number(weight).Float64(); err != nil {
+		parsed, err := json.Number(weight).Float64()
+		if err != nil {
 			http.Error(w, "Invalid weight parameter", http.StatusBadRequest)
 			return
 		}
+		weightKg = parsed
 	}
 
 	gender := models.ToGender(query.Get("gender"))
@@ -94,6 +97,11 @@ func (c *Controller) GetBAC(w http.ResponseWriter, r *http.Request) {
 		Gender:       gender,
 		TimeStepMins: timeStepMins,
 	}
+	if err := validation.Struct(req); err != nil {
+		validation.WriteError(w, err)
+		return
+	}
+
 	// Use mapper to convert DTO to model
 	calculationParams := mappers.ToBACCalculationParams(req)
 
@@ -117,8 +125,8 @@ func (c *Controller) GetBAC(w http.ResponseWriter, r *http.Request) {
 // @Tags bac
 // @Accept json
 // @Produce json
-// @Param Authorization header string true "Bearer token"
-// @Param weight_kg query float64 true "Weight in kg"
+// @Param Authorization header string true "Bearer token, or personal access token with the bac:read scope"
+// @Param weight_kg query float64 false "Weight in kg, for the drinks logged before the user had a weight in their profile (default: 70)"
 // @Param gender query string true "Gender"
 // @Success 200 {object} dtos.CurrentBACResponse
 // @Failure 400 {object} dtos.ClientError
@@ -137,10 +145,12 @@ func (c *Controller) GetCurrentBAC(w http.ResponseWriter, r *http.Request) {
 
 	weightKg := 70.0 // Default weight
 	if weight := query.Get("weight_kg"); weight != "" {
-		if _, err := json.Number(weight).Float64(); err != nil {
+		parsed, err := json.Number(weight).Float64()
+		if err != nil {
 			http.Error(w, "Invalid weight parameter", http.StatusBadRequest)
 			return
 		}
+		weightKg = parsed
 	}
 
 	gender := models.ToGender(query.Get("gender"))
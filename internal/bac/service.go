@@ -15,8 +15,14 @@ type DrinkLogRepository interface {
 	GetDrinkLogs(userID int64, page, pageSize int, filters dtos.DrinkLogFilters) ([]models.DrinkLog, int, error)
 }
 
+// WeightHistoryRepository gives the weights of a user over time, oldest first
+type WeightHistoryRepository interface {
+	GetWeightHistory(userID int64) ([]models.WeightEntry, error)
+}
+
 type Service struct {
 	drinkLogRepo DrinkLogRepository
+	weightRepo   WeightHistoryRepository
 }
 
 // Constants for BAC calculation
@@ -38,9 +44,10 @@ type BACTimeline struct {
 	Timeline []models.BACPoint `json:"timeline"`
 }
 
-func NewService(drinkLogRepo DrinkLogRepository) *Service {
+func NewService(drinkLogRepo DrinkLogRepository, weightRepo WeightHistoryRepository) *Service {
 	return &Service{
 		drinkLogRepo: drinkLogRepo,
+		weightRepo:   weightRepo,
 	}
 }
 
@@ -56,8 +63,13 @@ func (s *Service) CalculateBAC(userID int64, params models.BACCalculationParams)
 		return models.BACCalculation{}, err
 	}
 
+	history, err := s.weightRepo.GetWeightHistory(userID)
+	if err != nil {
+		return models.BACCalculation{}, err
+	}
+
 	// Calculate BAC
-	timeline, err := s.calculateBAC(drinks, params)
+	timeline, err := s.calculateBAC(drinks, history, params)
 	if err != nil {
 		return models.BACCalculation{}, err
 	}
@@ -83,14 +95,14 @@ func (s *Service) CalculateBAC(userID int64, params models.BACCalculationParams)
 }
 
 func (s *Service) calculateBACPoints(drinks []models.DrinkLog, startTime, endTime time.Time,
-	bodyWeightGrams float64, widmarkFactor float64, timeStepMin int) []models.BACPoint {
+	bodyWeightsGrams []float64, widmarkFactor float64, timeStepMin int) []models.BACPoint {
 
 	var bacPoints []models.BACPoint
 	currentTime := startTime
 	timeStep := time.Duration(timeStepMin) * time.Minute
 
 	for !currentTime.After(endTime) {
-		bac := s.calculateBACAtTime(drinks, currentTime, bodyWeightGrams, widmarkFactor)
+		bac := s.calculateBACAtTime(drinks, currentTime, bodyWeightsGrams, widmarkFactor)
 
 		if bac > maxPhysiologicalBAC {
 			fmt.Printf("BAC %f is above max physiological BAC of %f at %s\n", bac, maxPhysiologicalBAC, currentTime)
@@ -108,18 +120,19 @@ func (s *Service) calculateBACPoints(drinks []models.DrinkLog, startTime, endTim
 	return bacPoints
 }
 
+// calculateBACAtTime sums the BAC of the drinks, each with the body weight of the time it was logged
 func (s *Service) calculateBACAtTime(drinks []models.DrinkLog, currentTime time.Time,
-	bodyWeightGrams, widmarkFactor float64) float64 {
+	bodyWeightsGrams []float64, widmarkFactor float64) float64 {
 
 	var totalBAC float64
 
-	for _, drink := range drinks {
+	for i, drink := range drinks {
 		timeElapsed := currentTime.Sub(drink.LoggedAt).Minutes()
 		if timeElapsed < 0 {
 			continue
 		}
 
-		drinkBAC := s.calculateSingleDrinkBAC(drink, timeElapsed, bodyWeightGrams, widmarkFactor)
+		drinkBAC := s.calculateSingleDrinkBAC(drink, timeElapsed, bodyWeightsGrams[i], widmarkFactor)
 		totalBAC += drinkBAC
 	}
 
@@ -181,7 +194,7 @@ func (s *Service) getWidmarkFactor(gender models.Gender) float64 {
 }
 
 // calculateBAC handles the core BAC calculation logic
-func (s *Service) calculateBAC(drinks []models.DrinkLog, params models.BACCalculationParams) (BACTimeline, error) {
+func (s *Service) calculateBAC(drinks []models.DrinkLog, history []models.WeightEntry, params models.BACCalculationParams) (BACTimeline, error) {
 
 	// Sort drinks chronologically
 	sort.Slice(drinks, func(i, j int) bool {
@@ -189,12 +202,31 @@ func (s *Service) calculateBAC(drinks []models.DrinkLog, params models.BACCalcul
 	})
 
 	widmarkFactor := s.getWidmarkFactor(params.Gender)
-	bodyWeightGrams := params.WeightKg * 1000
+	bodyWeightsGrams := make([]float64, len(drinks))
+	for i, drink := range drinks {
+		bodyWeightsGrams[i] = weightAt(history, drink.LoggedAt, params.WeightKg) * 1000
+	}
 
-	points := s.calculateBACPoints(drinks, params.StartTime, params.EndTime, bodyWeightGrams, widmarkFactor, params.TimeStepMins)
+	points := s.calculateBACPoints(drinks, params.StartTime, params.EndTime, bodyWeightsGrams, widmarkFactor, params.TimeStepMins)
 	return BACTimeline{Timeline: points}, nil
 }
 
+// weightAt returns the weight of the history that applied at a time. The first weight also
+// applies before it was recorded, and the fallback is used when there is no history.
+func weightAt(history []models.WeightEntry, at time.Time, fallbackKg float64) float64 {
+	if len(history) == 0 {
+		return fallbackKg
+	}
+
+	next := sort.Search(len(history), func(i int) bool {
+		return history[i].RecordedAt.After(at)
+	})
+	if next == 0 {
+		return history[0].WeightKg
+	}
+	return history[next-1].WeightKg
+}
+
 // calculateBACSummary generates a summary of the BAC timeline
 func (s *Service) calculateBACSummary(timeline []models.BACPoint, totalDrinksConsumed int, timeStepMins int) models.BACSummary {
 	if len(timeline) == 0 {
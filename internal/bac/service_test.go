@@ -0,0 +1,84 @@
+package bac
+
+import (
+	"testing"
+	"time"
+
+	"go-sober/internal/dtos"
+	"go-sober/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeDrinkLogRepository struct {
+	drinks []models.DrinkLog
+}
+
+func (r *fakeDrinkLogRepository) GetDrinkLogs(userID int64, page, pageSize int, filters dtos.DrinkLogFilters) ([]models.DrinkLog, int, error) {
+	var drinks []models.DrinkLog
+	for _, drink := range r.drinks {
+		if !drink.LoggedAt.Before(*filters.StartDate) && !drink.LoggedAt.After(*filters.EndDate) {
+			drinks = append(drinks, drink)
+		}
+	}
+	return drinks, len(drinks), nil
+}
+
+type fakeWeightHistoryRepository struct {
+	history []models.WeightEntry
+}
+
+func (r *fakeWeightHistoryRepository) GetWeightHistory(userID int64) ([]models.WeightEntry, error) {
+	return r.history, nil
+}
+
+func TestWeightAt(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	history := []models.WeightEntry{
+		{WeightKg: 80, RecordedAt: start},
+		{WeightKg: 75, RecordedAt: start.AddDate(0, 6, 0)},
+	}
+
+	assert.Equal(t, 70.0, weightAt(nil, start, 70))
+	assert.Equal(t, 80.0, weightAt(history, start.AddDate(-1, 0, 0), 70), "the first weight applies before it")
+	assert.Equal(t, 80.0, weightAt(history, start, 70))
+	assert.Equal(t, 80.0, weightAt(history, start.AddDate(0, 6, 0).Add(-time.Second), 70))
+	assert.Equal(t, 75.0, weightAt(history, start.AddDate(0, 6, 0), 70))
+	assert.Equal(t, 75.0, weightAt(history, start.AddDate(2, 0, 0), 70))
+}
+
+func TestCalculateBACUsesTheWeightOfTheDrinkTime(t *testing.T) {
+	lastYear := time.Date(2023, 6, 1, 20, 0, 0, 0, time.UTC)
+	drinks := &fakeDrinkLogRepository{drinks: []models.DrinkLog{
+		{Name: "Beer", ABV: 0.05, SizeValue: 500, SizeUnit: "ml", LoggedAt: lastYear},
+	}}
+	params := models.BACCalculationParams{
+		StartTime:    lastYear,
+		EndTime:      lastYear.Add(4 * time.Hour),
+		WeightKg:     70,
+		Gender:       models.Male,
+		TimeStepMins: 15,
+	}
+
+	calculate := func(history []models.WeightEntry) float64 {
+		service := NewService(drinks, &fakeWeightHistoryRepository{history: history})
+		result, err := service.CalculateBAC(1, params)
+		require.NoError(t, err)
+		return result.Summary.MaxBAC
+	}
+
+	withoutHistory := calculate(nil)
+	assert.Greater(t, withoutHistory, 0.0)
+
+	// The weight entered today does not change the BAC of last year
+	history := []models.WeightEntry{
+		{WeightKg: 70, RecordedAt: lastYear.AddDate(-1, 0, 0)},
+		{WeightKg: 120, RecordedAt: lastYear.AddDate(1, 0, 0)},
+	}
+	assert.Equal(t, withoutHistory, calculate(history))
+
+	// A lighter weight at the time gives a higher BAC
+	history[0].WeightKg = 50
+	assert.Greater(t, calculate(history), withoutHistory)
+}
@@ -60,7 +60,8 @@ type TableInfo struct {
 	RowCount int
 }
 
-func ListDBSchema(db *sql.DB) ([]string, error) {
+// ListTables returns the tables of the database with their row counts
+func ListTables(db *sql.DB) ([]TableInfo, error) {
 	// Query to get all table names first
 	query := `
 		SELECT name
@@ -82,14 +83,6 @@ func ListDBSchema(db *sql.DB) ([]string, error) {
 			slog.Error("Failed to scan table info", "error", err)
 			return nil, err
 		}
-
-		// Get row count for each table using a separate query
-		countQuery := fmt.Sprintf("SELECT COUNT(*) FROM %q", info.Name)
-		if err := db.QueryRow(countQuery).Scan(&info.RowCount); err != nil {
-			slog.Error("Failed to get row count", "table", info.Name, "error", err)
-			return nil, err
-		}
-
 		tables = append(tables, info)
 	}
 
@@ -97,6 +90,26 @@ func ListDBSchema(db *sql.DB) ([]string, error) {
 		slog.Error("Error iterating over table rows", "error", err)
 		return nil, err
 	}
+	// Release the connection before counting, the pool may only have one
+	rows.Close()
+
+	// Get row count for each table using a separate query
+	for i := range tables {
+		countQuery := fmt.Sprintf("SELECT COUNT(*) FROM %q", tables[i].Name)
+		if err := db.QueryRow(countQuery).Scan(&tables[i].RowCount); err != nil {
+			slog.Error("Failed to get row count", "table", tables[i].Name, "error", err)
+			return nil, err
+		}
+	}
+
+	return tables, nil
+}
+
+func ListDBSchema(db *sql.DB) ([]string, error) {
+	tables, err := ListTables(db)
+	if err != nil {
+		return nil, err
+	}
 
 	// Convert TableInfo slice to string slice for backward compatibility
 	results := make([]string, len(tables))
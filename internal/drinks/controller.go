@@ -4,7 +4,9 @@ package drinks
 import (
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"log/slog"
 	"net/http"
 	"strconv"
 	"time"
@@ -13,7 +15,7 @@ import (
 	"go-sober/internal/dtos"
 	"go-sober/internal/models"
 	"go-sober/internal/params"
-	"go-sober/internal/parser"
+	"go-sober/internal/validation"
 )
 
 type Controller struct {
@@ -90,7 +92,7 @@ func (c *Controller) GetDrinkTemplate(w http.ResponseWriter, r *http.Request) {
 }
 
 // @Summary Create a drink template
-// @Description Create a new drink template
+// @Description Create a new drink template. Admins only.
 // @Tags drinks
 // @Accept json
 // @Produce json
@@ -98,13 +100,15 @@ func (c *Controller) GetDrinkTemplate(w http.ResponseWriter, r *http.Request) {
 // @Param drinkTemplate body dtos.CreateDrinkTemplateRequest true "New drink template"
 // @Success 201 {object} dtos.DrinkTemplateResponse
 // @Failure 400 {object} dtos.ClientError
+// @Failure 401 {object} dtos.ClientError
+// @Failure 403 {object} dtos.ClientError
 // @Failure 500 {object} dtos.ClientError
 // @Router /drink-templates [post]
 func (c *Controller) CreateDrinkTemplate(w http.ResponseWriter, r *http.Request) {
 	// Parse request body
 	var req dtos.CreateDrinkTemplateRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+	if err := validation.DecodeJSON(r, &req); err != nil {
+		validation.WriteError(w, err)
 		return
 	}
 
@@ -129,14 +133,17 @@ func (c *Controller) CreateDrinkTemplate(w http.ResponseWriter, r *http.Request)
 }
 
 // @Summary Update a drink template
-// @Description Update a specific drink template by ID
+// @Description Update a specific drink template by ID. Admins only.
 // @Tags drinks
 // @Accept json
 // @Produce json
+// @Param Authorization header string true "Bearer token"
 // @Param id path string true "Drink template ID"
 // @Param drinkTemplate body dtos.UpdateDrinkTemplateRequest true "Updated drink template"
 // @Success 204
 // @Failure 400 {object} dtos.ClientError
+// @Failure 401 {object} dtos.ClientError
+// @Failure 403 {object} dtos.ClientError
 // @Failure 404 {object} dtos.ClientError
 // @Failure 500 {object} dtos.ClientError
 // @Router /drink-templates/{id} [put]
@@ -150,8 +157,8 @@ func (c *Controller) UpdateDrinkTemplate(w http.ResponseWriter, r *http.Request)
 
 	// Parse request body
 	var req dtos.UpdateDrinkTemplateRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+	if err := validation.DecodeJSON(r, &req); err != nil {
+		validation.WriteError(w, err)
 		return
 	}
 
@@ -166,7 +173,7 @@ func (c *Controller) UpdateDrinkTemplate(w http.ResponseWriter, r *http.Request)
 
 	// Update drink template
 	if err := c.service.UpdateDrinkTemplate(drinkTemplateID, drinkTemplate); err != nil {
-		if err.Error() == "drink template not found" {
+		if errors.Is(err, ErrDrinkTemplateNotFound) {
 			http.Error(w, err.Error(), http.StatusNotFound)
 			return
 		}
@@ -178,12 +185,15 @@ func (c *Controller) UpdateDrinkTemplate(w http.ResponseWriter, r *http.Request)
 }
 
 // @Summary Delete a drink template
-// @Description Delete a specific drink template by ID
+// @Description Delete a specific drink template by ID. Admins only.
 // @Tags drinks
 // @Accept json
 // @Produce json
+// @Param Authorization header string true "Bearer token"
 // @Param id path string true "Drink template ID"
 // @Success 204
+// @Failure 401 {object} dtos.ClientError
+// @Failure 403 {object} dtos.ClientError
 // @Failure 404 {object} dtos.ClientError
 // @Failure 500 {object} dtos.ClientError
 // @Router /drink-templates/{id} [delete]
@@ -197,7 +207,7 @@ func (c *Controller) DeleteDrinkTemplate(w http.ResponseWriter, r *http.Request)
 
 	// Delete drink template
 	if err := c.service.DeleteDrinkTemplate(drinkTemplateID); err != nil {
-		if err.Error() == "drink template not found" {
+		if errors.Is(err, ErrDrinkTemplateNotFound) {
 			http.Error(w, err.Error(), http.StatusNotFound)
 			return
 		}
@@ -209,11 +219,11 @@ func (c *Controller) DeleteDrinkTemplate(w http.ResponseWriter, r *http.Request)
 }
 
 // @Summary Create a drink log
-// @Description Create a new drink log for the current user
+// @Description Create a new drink log for the current user. With a template_id, the drink details left out of the request are taken from the template, and the details sent override the template values (e.g. an edited ABV). An unknown template_id is a validation error.
 // @Tags drinks
 // @Accept json
 // @Produce json
-// @Param Authorization header string true "Bearer token"
+// @Param Authorization header string true "Bearer token, or personal access token with the drinks:write scope"
 // @Param drinkLog body dtos.CreateDrinkLogRequest true "Create drink log request"
 // @Success 201 {object} dtos.CreateDrinkLogResponse
 // @Failure 400 {object} dtos.ClientError
@@ -229,8 +239,8 @@ func (c *Controller) CreateDrinkLog(w http.ResponseWriter, r *http.Request) {
 
 	// Parse request body
 	var req dtos.CreateDrinkLogRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+	if err := validation.DecodeJSON(r, &req); err != nil {
+		validation.WriteError(w, err)
 		return
 	}
 
@@ -246,7 +256,13 @@ func (c *Controller) CreateDrinkLog(w http.ResponseWriter, r *http.Request) {
 	// Create the drink log and get the ID
 	id, err := c.service.CreateDrinkLog(claims.UserID, req)
 	if err != nil {
-		http.Error(w, "Failed to create drink log: "+err.Error(), http.StatusInternalServerError)
+		var validationError *validation.Error
+		if errors.As(err, &validationError) {
+			validation.WriteError(w, err)
+			return
+		}
+		slog.Error("Could not create drink log", "error", err)
+		http.Error(w, "Failed to create drink log", http.StatusInternalServerError)
 		return
 	}
 
@@ -265,7 +281,7 @@ func (c *Controller) CreateDrinkLog(w http.ResponseWriter, r *http.Request) {
 // @Tags drinks
 // @Accept json
 // @Produce json
-// @Param Authorization header string true "Bearer token"
+// @Param Authorization header string true "Bearer token, or personal access token with the drinks:read scope"
 // @Param page query int false "Page number (default: 1)" minimum(1)
 // @Param page_size query int false "Page size (default: 20, max: 100)" minimum(1) maximum(100)
 // @Param start_date query string false "Start date (RFC3339 format)"
@@ -326,14 +342,15 @@ func (c *Controller) GetDrinkLogs(w http.ResponseWriter, r *http.Request) {
 }
 
 // @Summary Parse a drink log
-// @Description Parse a drink log and return the drink parsed
+// @Description Parse a drink log and return the drink parsed, with the best matching drink templates
 // @Tags drinks
 // @Accept json
 // @Produce json
-// @Param Authorization header string true "Bearer token"
+// @Param Authorization header string true "Bearer token, or personal access token with the drinks:write scope"
 // @Param drinkLog body dtos.ParseDrinkLogRequest true "Parse drink log request"
 // @Success 200 {object} dtos.ParseDrinkLogResponse
 // @Failure 400 {object} dtos.ClientError
+// @Failure 429 {object} dtos.ClientError
 // @Failure 500 {object} dtos.ClientError
 // @Router /drink-logs/parse [post]
 func (c *Controller) ParseDrinkLog(w http.ResponseWriter, r *http.Request) {
@@ -344,13 +361,12 @@ func (c *Controller) ParseDrinkLog(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var req dtos.ParseDrinkLogRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+	if err := validation.DecodeJSON(r, &req); err != nil {
+		validation.WriteError(w, err)
 		return
 	}
 
-	parser := parser.NewDrinkParser()
-	match, err := parser.Parse(req.Text)
+	match, err := c.service.ParseDrinkLog(claims.UserID, req.Text)
 	if err != nil {
 		http.Error(w, "Could not parse drink description", http.StatusBadRequest)
 		return
@@ -370,7 +386,7 @@ func (c *Controller) ParseDrinkLog(w http.ResponseWriter, r *http.Request) {
 // @Tags drinks
 // @Accept json
 // @Produce json
-// @Param Authorization header string true "Bearer token"
+// @Param Authorization header string true "Bearer token, or personal access token with the drinks:write scope"
 // @Param id path string true "Drink log ID"
 // @Param drinkLog body dtos.UpdateDrinkLogRequest true "Update drink log request"
 // @Success 200 {object} dtos.UpdateDrinkLogResponse
@@ -389,8 +405,8 @@ func (c *Controller) UpdateDrinkLog(w http.ResponseWriter, r *http.Request) {
 
 	// Parse request body
 	var req dtos.UpdateDrinkLogRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+	if err := validation.DecodeJSON(r, &req); err != nil {
+		validation.WriteError(w, err)
 		return
 	}
 
@@ -421,7 +437,7 @@ func (c *Controller) UpdateDrinkLog(w http.ResponseWriter, r *http.Request) {
 // @Tags drinks
 // @Accept json
 // @Produce json
-// @Param Authorization header string true "Bearer token"
+// @Param Authorization header string true "Bearer token, or personal access token with the drinks:write scope"
 // @Param id path string true "Drink log ID"
 // @Success 200 {object} dtos.DeleteDrinkLogResponse
 // @Failure 400 {object} dtos.ClientError
@@ -0,0 +1,254 @@
+// internal/drinks/matcher.go
+package drinks
+
+import (
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"go-sober/internal/models"
+)
+
+// Weights of each criterion in the final match score
+const (
+	nameWeight = 0.6
+	typeWeight = 0.25
+	sizeWeight = 0.15
+
+	// Score given to a criterion we cannot evaluate (e.g. unknown size)
+	neutralScore = 0.5
+
+	// Candidates below this score are not returned
+	minMatchScore = 0.45
+	// Minimum score of the best candidate to borrow its ABV
+	minABVFillScore = 0.6
+	maxMatches      = 3
+)
+
+// Words that carry no information about the drink itself
+var matchStopWords = map[string]bool{
+	"a": true, "an": true, "the": true, "of": true, "on": true, "at": true,
+	"glass": true, "bottle": true, "can": true, "tap": true, "draft": true,
+	"ml": true, "cl": true, "l": true,
+	"un": true, "une": true, "de": true, "du": true, "d": true, "verre": true, "bouteille": true,
+}
+
+// Matcher scores parsed drinks against known drinks
+type Matcher struct{}
+
+func NewMatcher() *Matcher {
+	return &Matcher{}
+}
+
+// Match returns the best candidates for a parsed drink, best first
+func (m *Matcher) Match(parsed *models.DrinkParsed, templates []models.DrinkTemplate, history []models.DrinkHistoryEntry) []models.DrinkMatch {
+	var candidates []models.DrinkMatch
+
+	for _, template := range templates {
+		score := m.score(parsed, template.Name, template.Type, float64(template.SizeValue), template.SizeUnit)
+		candidates = append(candidates, models.DrinkMatch{
+			TemplateID: template.ID,
+			Name:       template.Name,
+			Type:       template.Type,
+			SizeValue:  template.SizeValue,
+			SizeUnit:   template.SizeUnit,
+			ABV:        template.ABV,
+			Score:      score,
+			Source:     models.DrinkMatchSourceCatalogue,
+		})
+	}
+
+	for _, entry := range history {
+		score := m.score(parsed, entry.Name, entry.Type, float64(entry.SizeValue), entry.SizeUnit)
+		match := models.DrinkMatch{
+			DetailsID: entry.DetailsID,
+			Name:      entry.Name,
+			Type:      entry.Type,
+			SizeValue: entry.SizeValue,
+			SizeUnit:  entry.SizeUnit,
+			ABV:       entry.ABV,
+			Score:     score,
+			Source:    models.DrinkMatchSourceHistory,
+		}
+		if entry.TemplateID != nil {
+			match.TemplateID = *entry.TemplateID
+		}
+		candidates = append(candidates, match)
+	}
+
+	// Keep the best candidates only
+	var matches []models.DrinkMatch
+	for _, candidate := range candidates {
+		if candidate.Score >= minMatchScore {
+			matches = append(matches, candidate)
+		}
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].Score > matches[j].Score
+	})
+
+	if len(matches) > maxMatches {
+		matches = matches[:maxMatches]
+	}
+
+	return matches
+}
+
+// FillUnknownABV uses the ABV of the best match when the parser could not find one.
+// It returns true when the ABV was filled in.
+func (m *Matcher) FillUnknownABV(parsed *models.DrinkParsed) bool {
+	if parsed.ABV >= 0 || len(parsed.Matches) == 0 {
+		return false
+	}
+
+	best := parsed.Matches[0]
+	if best.Score < minABVFillScore {
+		return false
+	}
+
+	parsed.ABV = best.ABV
+	parsed.ABVInferred = true
+	return true
+}
+
+func (m *Matcher) score(parsed *models.DrinkParsed, name, drinkType string, sizeValue float64, sizeUnit string) float64 {
+	nameScore := nameSimilarity(parsed.Name, name)
+
+	typeScore := neutralScore
+	if parsed.Type != "" {
+		typeScore = 0
+		if strings.EqualFold(strings.TrimSpace(parsed.Type), strings.TrimSpace(drinkType)) {
+			typeScore = 1
+		}
+	}
+
+	sizeScore := neutralScore
+	parsedMl, okParsed := models.VolumeInMl(parsed.SizeValue, parsed.SizeUnit)
+	candidateMl, okCandidate := models.VolumeInMl(sizeValue, sizeUnit)
+	if okParsed && okCandidate && parsedMl > 0 && candidateMl > 0 {
+		sizeScore = 1 - math.Abs(parsedMl-candidateMl)/math.Max(parsedMl, candidateMl)
+	}
+
+	score := nameWeight*nameScore + typeWeight*typeScore + sizeWeight*sizeScore
+	return math.Round(score*1000) / 1000
+}
+
+// nameSimilarity compares two drink names, ignoring volumes, percentages and filler words.
+// It is the best of a token overlap and a character bigram similarity, so that both
+// "Guinness" vs "Guinness Stout" and "Pilsener" vs "Pilsner" score well.
+func nameSimilarity(a, b string) float64 {
+	tokensA := nameTokens(a)
+	tokensB := nameTokens(b)
+	if len(tokensA) == 0 || len(tokensB) == 0 {
+		return 0
+	}
+
+	return math.Max(tokenOverlap(tokensA, tokensB), diceCoefficient(strings.Join(tokensA, " "), strings.Join(tokensB, " ")))
+}
+
+// nameTokens lowercases a name and keeps the words describing the drink
+func nameTokens(name string) []string {
+	fields := strings.FieldsFunc(strings.ToLower(name), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '.' && r != '%'
+	})
+
+	var tokens []string
+	for _, field := range fields {
+		field = strings.Trim(field, ".")
+		if field == "" || matchStopWords[field] || !containsLetter(field) || isMeasure(field) {
+			continue
+		}
+		tokens = append(tokens, field)
+	}
+	return tokens
+}
+
+func containsLetter(s string) bool {
+	for _, r := range s {
+		if unicode.IsLetter(r) {
+			return true
+		}
+	}
+	return false
+}
+
+// Units a measure token such as "500ml" may end with
+var measureSuffixes = []string{"ml", "cl", "dl", "l", "oz", "%"}
+
+// isMeasure reports whether a token is a quantity such as "500ml" or "33cl"
+func isMeasure(token string) bool {
+	for _, suffix := range measureSuffixes {
+		if strings.HasSuffix(token, suffix) {
+			token = strings.TrimSuffix(token, suffix)
+			break
+		}
+	}
+	_, err := strconv.ParseFloat(token, 64)
+	return err == nil
+}
+
+// tokenOverlap is the share of the shortest token set found in the other one
+func tokenOverlap(a, b []string) float64 {
+	setB := make(map[string]bool, len(b))
+	for _, token := range b {
+		setB[token] = true
+	}
+
+	common := 0
+	for _, token := range a {
+		if setB[token] {
+			common++
+		}
+	}
+
+	shortest := math.Min(float64(len(a)), float64(len(b)))
+	overlap := float64(common) / shortest
+
+	// Penalise matching a single word of a long name
+	longest := math.Max(float64(len(a)), float64(len(b)))
+	return overlap * (0.75 + 0.25*shortest/longest)
+}
+
+// diceCoefficient computes the Sørensen–Dice coefficient over character bigrams
+func diceCoefficient(a, b string) float64 {
+	if a == b {
+		return 1
+	}
+
+	bigramsA := bigrams(a)
+	bigramsB := bigrams(b)
+	if len(bigramsA) == 0 || len(bigramsB) == 0 {
+		return 0
+	}
+
+	counts := make(map[string]int, len(bigramsA))
+	for _, bigram := range bigramsA {
+		counts[bigram]++
+	}
+
+	common := 0
+	for _, bigram := range bigramsB {
+		if counts[bigram] > 0 {
+			counts[bigram]--
+			common++
+		}
+	}
+
+	return 2 * float64(common) / float64(len(bigramsA)+len(bigramsB))
+}
+
+func bigrams(s string) []string {
+	runes := []rune(s)
+	if len(runes) < 2 {
+		return nil
+	}
+
+	result := make([]string, 0, len(runes)-1)
+	for i := 0; i < len(runes)-1; i++ {
+		result = append(result, string(runes[i:i+2]))
+	}
+	return result
+}
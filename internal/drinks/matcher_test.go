@@ -0,0 +1,95 @@
+package drinks
+
+import (
+	"testing"
+
+	"go-sober/internal/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMatcher(t *testing.T) {
+	templates := []models.DrinkTemplate{
+		{ID: 1, Name: "Beer Pint", Type: "beer", SizeValue: 50, SizeUnit: "cl", ABV: 0.05},
+		{ID: 2, Name: "Guinness", Type: "beer", SizeValue: 568, SizeUnit: "ml", ABV: 0.042},
+		{ID: 3, Name: "Red Wine", Type: "wine", SizeValue: 15, SizeUnit: "cl", ABV: 0.13},
+		{ID: 4, Name: "Pilsner", Type: "beer", SizeValue: 50, SizeUnit: "cl", ABV: 0.048},
+	}
+	matcher := NewMatcher()
+
+	t.Run("matches a template by name, type and size", func(t *testing.T) {
+		parsed := &models.DrinkParsed{Name: "Guinness, 568ml", Type: "beer", SizeValue: 568, SizeUnit: "ml", ABV: -1}
+
+		matches := matcher.Match(parsed, templates, nil)
+		assert.NotEmpty(t, matches)
+		assert.Equal(t, 2, matches[0].TemplateID)
+		assert.Equal(t, models.DrinkMatchSourceCatalogue, matches[0].Source)
+		assert.Greater(t, matches[0].Score, 0.9)
+	})
+
+	t.Run("tolerates spelling variants", func(t *testing.T) {
+		parsed := &models.DrinkParsed{Name: "Pilsener, 500ml, 4.8%", Type: "beer", SizeValue: 500, SizeUnit: "ml", ABV: 0.048}
+
+		matches := matcher.Match(parsed, templates, nil)
+		assert.NotEmpty(t, matches)
+		assert.Equal(t, 4, matches[0].TemplateID)
+	})
+
+	t.Run("prefers the user's history when closer", func(t *testing.T) {
+		templateID := 3
+		history := []models.DrinkHistoryEntry{
+			{DetailsID: 42, TemplateID: &templateID, Name: "Red Wine", Type: "wine", SizeValue: 150, SizeUnit: "ml", ABV: 0.13, TimesUsed: 5},
+			{DetailsID: 43, Name: "Côtes du Rhône", Type: "wine", SizeValue: 125, SizeUnit: "ml", ABV: 0.14, TimesUsed: 2},
+		}
+		parsed := &models.DrinkParsed{Name: "Cotes du Rhone, 125ml", Type: "wine", SizeValue: 125, SizeUnit: "ml", ABV: -1}
+
+		matches := matcher.Match(parsed, templates, history)
+		assert.NotEmpty(t, matches)
+		assert.Equal(t, int64(43), matches[0].DetailsID)
+		assert.Equal(t, models.DrinkMatchSourceHistory, matches[0].Source)
+	})
+
+	t.Run("returns nothing for unrelated drinks", func(t *testing.T) {
+		parsed := &models.DrinkParsed{Name: "Mojito Cocktail", Type: "cocktail", SizeValue: 240, SizeUnit: "ml", ABV: 0.1}
+
+		matches := matcher.Match(parsed, templates, nil)
+		assert.Empty(t, matches)
+	})
+
+	t.Run("limits the number of matches", func(t *testing.T) {
+		parsed := &models.DrinkParsed{Name: "Beer", Type: "beer", SizeValue: 500, SizeUnit: "ml", ABV: 0.05}
+
+		matches := matcher.Match(parsed, templates, nil)
+		assert.LessOrEqual(t, len(matches), maxMatches)
+		for i := 1; i < len(matches); i++ {
+			assert.GreaterOrEqual(t, matches[i-1].Score, matches[i].Score)
+		}
+	})
+}
+
+func TestFillUnknownABV(t *testing.T) {
+	matcher := NewMatcher()
+
+	t.Run("fills unknown ABV from the best match", func(t *testing.T) {
+		parsed := &models.DrinkParsed{ABV: -1, Matches: []models.DrinkMatch{{ABV: 0.042, Score: 0.9}}}
+
+		assert.True(t, matcher.FillUnknownABV(parsed))
+		assert.Equal(t, 0.042, parsed.ABV)
+		assert.True(t, parsed.ABVInferred)
+	})
+
+	t.Run("keeps an explicit ABV", func(t *testing.T) {
+		parsed := &models.DrinkParsed{ABV: 0.05, Matches: []models.DrinkMatch{{ABV: 0.042, Score: 0.9}}}
+
+		assert.False(t, matcher.FillUnknownABV(parsed))
+		assert.Equal(t, 0.05, parsed.ABV)
+		assert.False(t, parsed.ABVInferred)
+	})
+
+	t.Run("ignores weak matches", func(t *testing.T) {
+		parsed := &models.DrinkParsed{ABV: -1, Matches: []models.DrinkMatch{{ABV: 0.042, Score: 0.5}}}
+
+		assert.False(t, matcher.FillUnknownABV(parsed))
+		assert.Equal(t, -1.0, parsed.ABV)
+	})
+}
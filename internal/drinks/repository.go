@@ -3,6 +3,7 @@ package drinks
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
 	"time"
 
@@ -15,6 +16,8 @@ var (
 	MaxTime = time.Unix(1<<63-1, 999999999)
 )
 
+var ErrDrinkTemplateNotFound = errors.New("drink template not found")
+
 type Repository struct {
 	db *sql.DB
 }
@@ -61,7 +64,7 @@ func (r *Repository) GetDrinkTemplate(id int) (*models.DrinkTemplate, error) {
 		Scan(&drinkTemplate.ID, &drinkTemplate.Name, &drinkTemplate.Type, &drinkTemplate.SizeValue, &drinkTemplate.SizeUnit, &drinkTemplate.ABV)
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return nil, fmt.Errorf("drink template not found")
+			return nil, ErrDrinkTemplateNotFound
 		}
 		return nil, err
 	}
@@ -113,7 +116,7 @@ func (r *Repository) UpdateDrinkTemplate(id int, template *models.DrinkTemplate)
 	}
 
 	if rowsAffected == 0 {
-		return fmt.Errorf("drink template not found")
+		return ErrDrinkTemplateNotFound
 	}
 
 	return nil
@@ -131,13 +134,13 @@ func (r *Repository) DeleteDrinkTemplate(id int) error {
 	}
 
 	if rowsAffected == 0 {
-		return fmt.Errorf("drink template not found")
+		return ErrDrinkTemplateNotFound
 	}
 
 	return nil
 }
 
-func (r *Repository) CreateDrinkLog(userID int64, params dtos.CreateDrinkLogRequest) (int64, error) {
+func (r *Repository) CreateDrinkLog(userID int64, params models.NewDrinkLog) (int64, error) {
 	// Start transaction
 	tx, err := r.db.Begin()
 	if err != nil {
@@ -145,7 +148,7 @@ func (r *Repository) CreateDrinkLog(userID int64, params dtos.CreateDrinkLogRequ
 	}
 	defer tx.Rollback() // Rollback if we return early due to an error
 
-	// hash all the CreateDrinkLogRequest
+	// hash all the drink details
 	hashKey := fmt.Sprintf("%s-%s-%d-%s-%f", params.Name, params.Type, params.SizeValue, params.SizeUnit, params.ABV)
 
 	// check if the hashKey is in drink_log_details
@@ -165,8 +168,8 @@ func (r *Repository) CreateDrinkLog(userID int64, params dtos.CreateDrinkLogRequ
 
 	// if the hashKey is not in drink_log_details, create a new drink_log_details
 	if err == sql.ErrNoRows {
-		query = `INSERT INTO drink_log_details (name, type, size_value, size_unit, abv, hash_key, created_at) VALUES (?, ?, ?, ?, ?, ?, ?)`
-		result, err := tx.Exec(query, params.Name, params.Type, params.SizeValue, params.SizeUnit, params.ABV, hashKey, loggedAt)
+		query = `INSERT INTO drink_log_details (name, type, size_value, size_unit, abv, template_id, hash_key, created_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?)`
+		result, err := tx.Exec(query, params.Name, params.Type, params.SizeValue, params.SizeUnit, params.ABV, params.TemplateID, hashKey, loggedAt)
 		if err != nil {
 			return 0, fmt.Errorf("failed to create drink log detail: %w", err)
 		}
@@ -422,3 +425,56 @@ func (r *Repository) DeleteDrinkLog(logID int64, userID int64) error {
 
 	return nil
 }
+
+// GetUserDrinkHistory returns the distinct drinks logged by a user, most used first
+func (r *Repository) GetUserDrinkHistory(userID int64, limit int) ([]models.DrinkHistoryEntry, error) {
+	query := `
+        SELECT 
+            dld.id, dld.template_id, dld.name, dld.type,
+            dld.size_value, dld.size_unit, dld.abv,
+            COUNT(*) as times_used
+        FROM drink_logs dl
+        JOIN drink_log_details dld ON dl.drink_details_id = dld.id
+        WHERE dl.user_id = ?
+        GROUP BY dld.id
+        ORDER BY times_used DESC, MAX(dl.logged_at) DESC
+        LIMIT ?`
+
+	rows, err := r.db.Query(query, userID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("error querying drink history: %w", err)
+	}
+	defer rows.Close()
+
+	var history []models.DrinkHistoryEntry
+	for rows.Next() {
+		var entry models.DrinkHistoryEntry
+		var templateID sql.NullInt64
+		err := rows.Scan(
+			&entry.DetailsID,
+			&templateID,
+			&entry.Name,
+			&entry.Type,
+			&entry.SizeValue,
+			&entry.SizeUnit,
+			&entry.ABV,
+			&entry.TimesUsed,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning drink history: %w", err)
+		}
+
+		if templateID.Valid {
+			id := int(templateID.Int64)
+			entry.TemplateID = &id
+		}
+
+		history = append(history, entry)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating drink history: %w", err)
+	}
+
+	return history, nil
+}
@@ -70,7 +70,7 @@ func TestGetDrinkLogs(t *testing.T) {
 	userID := int64(1)
 
 	// Insert test data
-	params := dtos.CreateDrinkLogRequest{
+	params := models.NewDrinkLog{
 		Name:      "Test Beer",
 		Type:      "Beer",
 		SizeValue: 330,
@@ -164,7 +164,7 @@ func TestUpdateDrinkLog(t *testing.T) {
 
 	t.Run("successful update", func(t *testing.T) {
 		// Create initial drink log
-		params := dtos.CreateDrinkLogRequest{
+		params := models.NewDrinkLog{
 			Name:      "Original Beer",
 			Type:      "Beer",
 			SizeValue: 330,
@@ -221,7 +221,7 @@ func TestDeleteDrinkLog(t *testing.T) {
 
 	t.Run("successful deletion", func(t *testing.T) {
 		// Create a drink log first
-		params := dtos.CreateDrinkLogRequest{
+		params := models.NewDrinkLog{
 			Name:      "Delete Test Beer",
 			Type:      "Beer",
 			SizeValue: 330,
@@ -245,7 +245,7 @@ func TestDeleteDrinkLog(t *testing.T) {
 
 	t.Run("unauthorized deletion", func(t *testing.T) {
 		// Create a drink log
-		params := dtos.CreateDrinkLogRequest{
+		params := models.NewDrinkLog{
 			Name:      "Delete Test Beer",
 			Type:      "Beer",
 			SizeValue: 330,
@@ -352,8 +352,7 @@ func TestDrinkTemplates(t *testing.T) {
 
 		// Verify deletion
 		_, err = repo.GetDrinkTemplate(template.ID)
-		assert.Error(t, err)
-		assert.Contains(t, err.Error(), "not found")
+		assert.ErrorIs(t, err, ErrDrinkTemplateNotFound)
 	})
 }
 
@@ -361,7 +360,7 @@ func TestDrinkLogUpdatedAt(t *testing.T) {
 	t.Run("updated_at is null on creation", func(t *testing.T) {
 		repo := setupTestDB(t)
 		// Create initial drink log
-		createReq := dtos.CreateDrinkLogRequest{
+		createReq := models.NewDrinkLog{
 			Name:      "Beer",
 			Type:      "Lager",
 			SizeValue: 330,
@@ -385,7 +384,7 @@ func TestDrinkLogUpdatedAt(t *testing.T) {
 	t.Run("updated_at is set when updating", func(t *testing.T) {
 		repo := setupTestDB(t)
 		// Create initial drink log
-		createReq := dtos.CreateDrinkLogRequest{
+		createReq := models.NewDrinkLog{
 			Name:      "Beer",
 			Type:      "Lager",
 			SizeValue: 330,
@@ -423,7 +422,7 @@ func TestDrinkLogUpdatedAt(t *testing.T) {
 	t.Run("updated_at is set even if not provided in update", func(t *testing.T) {
 		repo := setupTestDB(t)
 		// Create initial drink log
-		createReq := dtos.CreateDrinkLogRequest{
+		createReq := models.NewDrinkLog{
 			Name:      "Beer Miam Miam",
 			Type:      "Lager",
 			SizeValue: 330,
@@ -455,3 +454,35 @@ func TestDrinkLogUpdatedAt(t *testing.T) {
 		assert.NotNil(t, logs[0].UpdatedAt)
 	})
 }
+
+func TestGetUserDrinkHistory(t *testing.T) {
+	repo := setupTestDB(t)
+	userID := int64(1)
+
+	template := models.DrinkTemplate{Name: "Guinness", Type: "beer", SizeValue: 568, SizeUnit: "ml", ABV: 0.042}
+	err := repo.CreateDrinkTemplate(&template)
+	assert.NoError(t, err)
+
+	guinness := models.NewDrinkLog{Name: "Guinness", Type: "beer", SizeValue: 568, SizeUnit: "ml", ABV: 0.042, TemplateID: &template.ID}
+	wine := models.NewDrinkLog{Name: "Red Wine", Type: "wine", SizeValue: 150, SizeUnit: "ml", ABV: 0.13}
+
+	for _, req := range []models.NewDrinkLog{guinness, guinness, wine} {
+		_, err := repo.CreateDrinkLog(userID, req)
+		assert.NoError(t, err)
+	}
+	// Another user's drink is not part of the history
+	_, err = repo.CreateDrinkLog(2, models.NewDrinkLog{Name: "Cider", Type: "cider", SizeValue: 500, SizeUnit: "ml", ABV: 0.045})
+	assert.NoError(t, err)
+
+	history, err := repo.GetUserDrinkHistory(userID, 10)
+	assert.NoError(t, err)
+	assert.Len(t, history, 2)
+
+	assert.Equal(t, "Guinness", history[0].Name)
+	assert.Equal(t, 2, history[0].TimesUsed)
+	assert.NotNil(t, history[0].TemplateID)
+	assert.Equal(t, template.ID, *history[0].TemplateID)
+
+	assert.Equal(t, "Red Wine", history[1].Name)
+	assert.Nil(t, history[1].TemplateID)
+}
@@ -2,16 +2,32 @@
 package drinks
 
 import (
+	"errors"
+	"log/slog"
+
 	"go-sober/internal/dtos"
 	"go-sober/internal/models"
+	"go-sober/internal/parser"
+	"go-sober/internal/validation"
 )
 
+// Number of distinct drinks from the user's history considered when matching
+const matchHistorySize = 100
+
 type Service struct {
-	repo *Repository
+	repo       *Repository
+	matcher    *Matcher
+	llmParser  parser.DrinkParser
+	ruleParser parser.DrinkParser
 }
 
-func NewService(repo *Repository) *Service {
-	return &Service{repo: repo}
+func NewService(repo *Repository, llmParser, ruleParser parser.DrinkParser) *Service {
+	return &Service{
+		repo:       repo,
+		matcher:    NewMatcher(),
+		llmParser:  llmParser,
+		ruleParser: ruleParser,
+	}
 }
 
 func (s *Service) GetDrinkTemplates() ([]models.DrinkTemplate, error) {
@@ -34,8 +50,61 @@ func (s *Service) DeleteDrinkTemplate(id int) error {
 	return s.repo.DeleteDrinkTemplate(id)
 }
 
+// CreateDrinkLog logs a drink. With a template, the fields left out of the request are taken
+// from the template and the fields sent override it, e.g. a template with an edited ABV.
 func (s *Service) CreateDrinkLog(userID int64, createDrinkLogRequest dtos.CreateDrinkLogRequest) (int64, error) {
-	return s.repo.CreateDrinkLog(userID, createDrinkLogRequest)
+	var template *models.DrinkTemplate
+	if createDrinkLogRequest.TemplateID != nil {
+		var err error
+		template, err = s.repo.GetDrinkTemplate(*createDrinkLogRequest.TemplateID)
+		if errors.Is(err, ErrDrinkTemplateNotFound) {
+			// e.g. a parse match pointing to a template deleted since
+			return 0, &validation.Error{Message: "Invalid drink log", Fields: []dtos.FieldError{
+				{Field: "template_id", Rule: "exists", Message: "must be an existing drink template"},
+			}}
+		}
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	return s.repo.CreateDrinkLog(userID, newDrinkLog(createDrinkLogRequest, template))
+}
+
+// newDrinkLog resolves the drink to log: the template values, if any, overridden by the
+// fields sent. The drink stays linked to the template only when nothing was overridden,
+// so that the drink details of a template are shared.
+func newDrinkLog(req dtos.CreateDrinkLogRequest, template *models.DrinkTemplate) models.NewDrinkLog {
+	drink := models.NewDrinkLog{LoggedAt: req.LoggedAt}
+	if template != nil {
+		drink.Name = template.Name
+		drink.Type = template.Type
+		drink.SizeValue = template.SizeValue
+		drink.SizeUnit = template.SizeUnit
+		drink.ABV = template.ABV
+	}
+
+	if req.Name != nil {
+		drink.Name = *req.Name
+	}
+	if req.Type != nil {
+		drink.Type = *req.Type
+	}
+	if req.SizeValue != nil {
+		drink.SizeValue = *req.SizeValue
+	}
+	if req.SizeUnit != nil {
+		drink.SizeUnit = *req.SizeUnit
+	}
+	if req.ABV != nil {
+		drink.ABV = *req.ABV
+	}
+
+	if template != nil && drink.Name == template.Name && drink.Type == template.Type && drink.SizeValue == template.SizeValue &&
+		drink.SizeUnit == template.SizeUnit && drink.ABV == template.ABV {
+		drink.TemplateID = req.TemplateID
+	}
+	return drink
 }
 
 func (s *Service) UpdateDrinkLog(userID int64, updateDrinkLogRequest dtos.UpdateDrinkLogRequest) error {
@@ -49,3 +118,54 @@ func (s *Service) DeleteDrinkLog(userID int64, logID int64) error {
 func (s *Service) GetDrinkLogs(userID int64, page, pageSize int, filters dtos.DrinkLogFilters) ([]models.DrinkLog, int, error) {
 	return s.repo.GetDrinkLogs(userID, page, pageSize, filters)
 }
+
+// ParseDrinkLog parses a drink description and matches it against known drinks.
+// The LLM result is cross-checked with the rule-based parser to score its confidence,
+// and the rule-based result is used when the LLM fails.
+func (s *Service) ParseDrinkLog(userID int64, text string) (*models.DrinkParsed, error) {
+	llmParsed, err := s.llmParser.Parse(text)
+	if err != nil {
+		return nil, err
+	}
+
+	ruleParsed, err := s.ruleParser.Parse(text)
+	if err != nil {
+		return nil, err
+	}
+
+	parsed, reference := llmParsed, ruleParsed
+	if !llmParsed.Success && ruleParsed.Success {
+		slog.Info("LLM could not parse drink, falling back to rules", "error", llmParsed.ErrorMessage)
+		parsed, reference = ruleParsed, nil
+	}
+
+	if parsed.Success {
+		if err := s.MatchParsedDrink(userID, parsed); err != nil {
+			// Matching is a best effort, the parsed drink is still usable
+			slog.Warn("Could not match parsed drink", "error", err)
+		}
+	}
+
+	parser.ScoreConfidence(parsed, reference)
+
+	return parsed, nil
+}
+
+// MatchParsedDrink fills the matches of a parsed drink from the template
+// catalogue and the user's history, and completes an unknown ABV.
+func (s *Service) MatchParsedDrink(userID int64, parsed *models.DrinkParsed) error {
+	templates, err := s.repo.GetDrinkTemplates()
+	if err != nil {
+		return err
+	}
+
+	history, err := s.repo.GetUserDrinkHistory(userID, matchHistorySize)
+	if err != nil {
+		return err
+	}
+
+	parsed.Matches = s.matcher.Match(parsed, templates, history)
+	s.matcher.FillUnknownABV(parsed)
+
+	return nil
+}
@@ -0,0 +1,68 @@
+package drinks
+
+import (
+	"testing"
+
+	"go-sober/internal/dtos"
+	"go-sober/internal/models"
+	"go-sober/internal/validation"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewDrinkLog(t *testing.T) {
+	template := &models.DrinkTemplate{ID: 3, Name: "Guinness", Type: "beer", SizeValue: 568, SizeUnit: "ml", ABV: 0.042}
+	templateID := 3
+
+	t.Run("missing details come from the template", func(t *testing.T) {
+		drink := newDrinkLog(dtos.CreateDrinkLogRequest{TemplateID: &templateID}, template)
+		assert.Equal(t, "Guinness", drink.Name)
+		assert.Equal(t, "beer", drink.Type)
+		assert.Equal(t, 568, drink.SizeValue)
+		assert.Equal(t, "ml", drink.SizeUnit)
+		assert.Equal(t, 0.042, drink.ABV)
+		assert.Equal(t, &templateID, drink.TemplateID)
+	})
+
+	t.Run("details sent override the template", func(t *testing.T) {
+		abv := 0.05
+		drink := newDrinkLog(dtos.CreateDrinkLogRequest{ABV: &abv, TemplateID: &templateID}, template)
+		assert.Equal(t, "Guinness", drink.Name)
+		assert.Equal(t, 568, drink.SizeValue)
+		assert.Equal(t, 0.05, drink.ABV)
+		assert.Nil(t, drink.TemplateID, "an edited drink is no longer the template")
+	})
+
+	t.Run("a zero ABV overrides the template", func(t *testing.T) {
+		alcoholFree := 0.0
+		drink := newDrinkLog(dtos.CreateDrinkLogRequest{ABV: &alcoholFree, TemplateID: &templateID}, template)
+		assert.Equal(t, 0.0, drink.ABV)
+		assert.Nil(t, drink.TemplateID)
+	})
+
+	t.Run("details equal to the template keep the link", func(t *testing.T) {
+		name, size := "Guinness", 568
+		drink := newDrinkLog(dtos.CreateDrinkLogRequest{Name: &name, SizeValue: &size, TemplateID: &templateID}, template)
+		assert.Equal(t, &templateID, drink.TemplateID)
+	})
+
+	t.Run("without a template", func(t *testing.T) {
+		name, drinkType, size, unit, abv := "Cider", "cider", 500, "ml", 0.045
+		drink := newDrinkLog(dtos.CreateDrinkLogRequest{Name: &name, Type: &drinkType, SizeValue: &size, SizeUnit: &unit, ABV: &abv}, nil)
+		assert.Equal(t, models.NewDrinkLog{Name: "Cider", Type: "cider", SizeValue: 500, SizeUnit: "ml", ABV: 0.045}, drink)
+	})
+}
+
+func TestCreateDrinkLogWithUnknownTemplate(t *testing.T) {
+	service := NewService(setupTestDB(t), nil, nil)
+	templateID := 42
+
+	// e.g. a parse match pointing to a template an admin deleted since
+	_, err := service.CreateDrinkLog(1, dtos.CreateDrinkLogRequest{TemplateID: &templateID})
+
+	var validationError *validation.Error
+	require.ErrorAs(t, err, &validationError)
+	require.Len(t, validationError.Fields, 1)
+	assert.Equal(t, "template_id", validationError.Fields[0].Field)
+}
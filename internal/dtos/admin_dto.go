@@ -0,0 +1,44 @@
+package dtos
+
+import (
+	"time"
+
+	"go-sober/internal/models"
+)
+
+type AdminUserResponse struct {
+	ID              int64       `json:"id"`
+	Email           string      `json:"email"`
+	Role            models.Role `json:"role"`
+	EmailVerifiedAt *time.Time  `json:"email_verified_at,omitempty"`
+	DisabledAt      *time.Time  `json:"disabled_at,omitempty"`
+	CreatedAt       time.Time   `json:"created_at"`
+}
+
+type AdminUsersResponse struct {
+	Users    []AdminUserResponse `json:"users"`
+	Total    int                 `json:"total"`
+	Page     int                 `json:"page"`
+	PageSize int                 `json:"page_size"`
+}
+
+type SetUserRoleRequest struct {
+	Role models.Role `json:"role" validate:"required,oneof=user admin"`
+}
+
+type UserStats struct {
+	Total    int `json:"total"`
+	Admins   int `json:"admins"`
+	Disabled int `json:"disabled"`
+	Verified int `json:"verified"` // With a verified email address
+}
+
+type TableStats struct {
+	Name     string `json:"name"`
+	RowCount int    `json:"row_count"`
+}
+
+type AdminStatsResponse struct {
+	Users  UserStats    `json:"users"`
+	Tables []TableStats `json:"tables"`
+}
@@ -30,3 +30,118 @@ type MonthlyBACStatsResponse struct {
 	Stats      []MonthlyBACStats    `json:"stats"`
 	Categories []models.BACCategory `json:"categories"`
 }
+
+// GuidelinesFilters represents the query parameters for the guidelines, the complete weeks
+// between the dates are evaluated
+type GuidelinesFilters struct {
+	StartDate *time.Time `json:"start_date,omitempty"` // Since the first drink when omitted
+	EndDate   *time.Time `json:"end_date,omitempty"`   // Until last week when omitted
+}
+
+// LimitProgress compares standard drinks with a limit, the limit is null when the guideline has none
+type LimitProgress struct {
+	StandardDrinks float64  `json:"standard_drinks"`
+	Limit          *float64 `json:"limit"`
+	Remaining      *float64 `json:"remaining"` // Standard drinks left before the limit, 0 over it
+	OverLimit      bool     `json:"over_limit"`
+}
+
+// DayProgress is the progress of the current drinking day
+type DayProgress struct {
+	Date string `json:"date"` // 2025-07-25
+	LimitProgress
+}
+
+// WeekProgress is the progress of the current week, from Monday to today
+type WeekProgress struct {
+	StartDate string `json:"start_date"` // Monday of the week
+	LimitProgress
+	DryDays         int `json:"dry_days"` // Days without a drink so far, today included
+	DryDaysRequired int `json:"dry_days_required"`
+}
+
+// GuidelineProgress is the current progress and the history of a user against a guideline
+type GuidelineProgress struct {
+	Guideline      models.Guideline `json:"guideline"`
+	Today          DayProgress      `json:"today"`
+	ThisWeek       WeekProgress     `json:"this_week"`
+	WeeksEvaluated int              `json:"weeks_evaluated"`
+	WeeksWithin    int              `json:"weeks_within"`     // Weeks within every limit of the guideline
+	ComplianceRate *float64         `json:"compliance_rate"`  // Percentage of the weeks within, null without weeks
+	WeeksOverLimit int              `json:"weeks_over_limit"` // Weeks over the weekly limit
+	DaysOverLimit  int              `json:"days_over_limit"`  // Days of the weeks over the daily limit
+}
+
+// GuidelinesResponse represents the response for the guidelines, the personal limits first
+type GuidelinesResponse struct {
+	Guidelines []GuidelineProgress `json:"guidelines"`
+}
+
+// StreaksFilters represents the query parameters for the streaks, the alcohol-free days per week
+// and month are counted between the dates
+type StreaksFilters struct {
+	StartDate *time.Time `json:"start_date,omitempty"` // Since the tracking started when omitted
+	EndDate   *time.Time `json:"end_date,omitempty"`   // Until today when omitted
+}
+
+// Streak is a run of consecutive alcohol-free days, without dates when it is empty
+type Streak struct {
+	Days      int    `json:"days"`
+	StartDate string `json:"start_date,omitempty"` // 2025-07-25
+	EndDate   string `json:"end_date,omitempty"`
+}
+
+// DryDaysPoint counts the alcohol-free days of a week or a month
+type DryDaysPoint struct {
+	StartDate string `json:"start_date"` // Monday of the week, or first day of the month
+	DryDays   int    `json:"dry_days"`
+	Days      int    `json:"days"` // Tracked days of the period, until today
+}
+
+// Milestone is a length of alcohol-free streak to reach
+type Milestone struct {
+	Days      int     `json:"days"`
+	Reached   bool    `json:"reached"`
+	ReachedOn *string `json:"reached_on"` // Day a streak first reached it
+	DaysToGo  int     `json:"days_to_go"` // Days left for the current streak to reach it
+}
+
+// StreaksResponse represents the response for the alcohol-free streaks. The tracking starts at
+// the first drink logged, or at the creation of the profile.
+type StreaksResponse struct {
+	TrackedSince    *string        `json:"tracked_since"`
+	CurrentStreak   Streak         `json:"current_streak"` // Today included while it is alcohol-free
+	LongestStreak   Streak         `json:"longest_streak"`
+	DryDaysPerWeek  []DryDaysPoint `json:"dry_days_per_week"`
+	DryDaysPerMonth []DryDaysPoint `json:"dry_days_per_month"`
+	Milestones      []Milestone    `json:"milestones"`
+}
+
+// HeatmapResponse represents the drinks by day of the week and hour of the day. The rows are the
+// days from Monday, the columns the hours from midnight in the time zone of the user. A drink
+// after midnight is in the row of its drinking day, so a Friday night out stays on Friday.
+type HeatmapResponse struct {
+	Weekdays            []string       `json:"weekdays"`
+	DrinkCounts         [7][24]int     `json:"drink_counts"`
+	StandardDrinks      [7][24]float64 `json:"standard_drinks"`
+	TotalDrinks         int            `json:"total_drinks"`
+	TotalStandardDrinks float64        `json:"total_standard_drinks"`
+}
+
+// BreakdownGroup is the share of a group of drinks in the drinks of the period
+type BreakdownGroup struct {
+	Group               string  `json:"group"`
+	DrinkCount          int     `json:"drink_count"`
+	StandardDrinks      float64 `json:"standard_drinks"`
+	DrinkShare          float64 `json:"drink_share"`           // Percentage of the drinks
+	StandardDrinksShare float64 `json:"standard_drinks_share"` // Percentage of the standard drinks
+}
+
+// BreakdownResponse represents the drinks grouped by type, name, ABV band or size, the groups with
+// the most standard drinks first
+type BreakdownResponse struct {
+	GroupBy             models.BreakdownGroupBy `json:"group_by"`
+	Groups              []BreakdownGroup        `json:"groups"`
+	TotalDrinks         int                     `json:"total_drinks"`
+	TotalStandardDrinks float64                 `json:"total_standard_drinks"`
+}
@@ -19,3 +19,10 @@ type ClientError struct {
 func (c ClientError) Error() string {
 	return c.Message
 }
+
+// FieldError describes why a field of a request is invalid, it is used as a ClientError detail.
+type FieldError struct {
+	Field   string `json:"field" example:"email"`                           // The JSON name of the field
+	Rule    string `json:"rule" example:"email"`                            // The rule the value breaks
+	Message string `json:"message" example:"must be a valid email address"` // A human-readable explanation
+} // @name FieldError
@@ -6,13 +6,17 @@ import (
 	"time"
 )
 
+// The drink details are only required without a template. With a template, the details
+// left out are taken from it and the details sent override it, zero values included
+// (e.g. "abv": 0 for the alcohol-free version of a drink).
 type CreateDrinkLogRequest struct {
-	Name      string     `json:"name" validate:"required"`
-	Type      string     `json:"type" validate:"required"`
-	SizeValue int        `json:"size_value" validate:"required,gt=0"`
-	SizeUnit  string     `json:"size_unit" validate:"required"`
-	ABV       float64    `json:"abv" validate:"required,gt=0"`
-	LoggedAt  *time.Time `json:"logged_at,omitempty"`
+	Name       *string    `json:"name" validate:"required_without=TemplateID,omitempty,min=1"`
+	Type       *string    `json:"type" validate:"required_without=TemplateID,omitempty,min=1"`
+	SizeValue  *int       `json:"size_value" validate:"required_without=TemplateID,omitempty,gt=0"`
+	SizeUnit   *string    `json:"size_unit" validate:"required_without=TemplateID,omitempty,min=1"`
+	ABV        *float64   `json:"abv" validate:"required_without=TemplateID,omitempty,gte=0,lte=1"` // Ratio, e.g. 0.05
+	TemplateID *int       `json:"template_id,omitempty" validate:"omitempty,gt=0"`                  // Optional, reuse a drink template (e.g. a parse match)
+	LoggedAt   *time.Time `json:"logged_at,omitempty"`
 }
 
 type UpdateDrinkLogRequest struct {
@@ -21,7 +25,7 @@ type UpdateDrinkLogRequest struct {
 	Type      string     `json:"type" validate:"required"`
 	SizeValue int        `json:"size_value" validate:"required,gt=0"`
 	SizeUnit  string     `json:"size_unit" validate:"required"`
-	ABV       float64    `json:"abv" validate:"required,gt=0"`
+	ABV       float64    `json:"abv" validate:"required,gt=0,lte=1"`
 	UpdatedAt *time.Time `json:"updated_at,omitempty"`
 }
 
@@ -42,7 +46,7 @@ type GetDrinkLogsResponse struct {
 }
 
 type ParseDrinkLogRequest struct {
-	Text string `json:"text"`
+	Text string `json:"text" validate:"required,max=500"`
 }
 
 type ParseDrinkLogResponse struct {
@@ -15,7 +15,7 @@ type UpdateDrinkTemplateRequest struct {
 	Type      string  `json:"type" validate:"required"`
 	SizeValue int     `json:"size_value" validate:"required,gt=0"`
 	SizeUnit  string  `json:"size_unit" validate:"required"`
-	ABV       float64 `json:"abv" validate:"required,gt=0"`
+	ABV       float64 `json:"abv" validate:"required,gt=0,lte=1"`
 }
 
 type CreateDrinkTemplateRequest struct {
@@ -23,5 +23,5 @@ type CreateDrinkTemplateRequest struct {
 	Type      string  `json:"type" validate:"required"`
 	SizeValue int     `json:"size_value" validate:"required,gt=0"`
 	SizeUnit  string  `json:"size_unit" validate:"required"`
-	ABV       float64 `json:"abv" validate:"required,gt=0"`
+	ABV       float64 `json:"abv" validate:"required,gt=0,lte=1"`
 }
@@ -0,0 +1,32 @@
+package dtos
+
+import "go-sober/internal/models"
+
+// GoalRequest creates or replaces a goal. The period is required for the recurring goals, the
+// end date for a dry period.
+type GoalRequest struct {
+	Name      string            `json:"name" validate:"required,max=100"`
+	Type      models.GoalType   `json:"type" validate:"required,oneof=max_standard_drinks min_dry_days dry_period"`
+	Period    models.TimePeriod `json:"period,omitempty" validate:"omitempty,oneof=daily weekly monthly"`
+	Target    float64           `json:"target" validate:"gte=0"`                                       // Standard drinks, or alcohol-free days
+	StartDate string            `json:"start_date,omitempty" validate:"omitempty,datetime=2006-01-02"` // Today when omitted
+	EndDate   *string           `json:"end_date,omitempty" validate:"omitempty,datetime=2006-01-02"`   // Included
+}
+
+// GoalResponse is a goal with the outcome of its current period, and the counts of the periods
+// met and failed. The goal endpoint adds the outcome of every period.
+type GoalResponse struct {
+	Goal    models.Goal          `json:"goal"`
+	Current *models.GoalOutcome  `json:"current"` // Null before the goal starts
+	Met     int                  `json:"met"`
+	Failed  int                  `json:"failed"`
+	History []models.GoalOutcome `json:"history,omitempty"` // Oldest first
+}
+
+type GoalsResponse struct {
+	Goals []GoalResponse `json:"goals"`
+}
+
+type DeleteGoalResponse struct {
+	ID int64 `json:"id"`
+}
@@ -1,8 +1,14 @@
 package dtos
 
+import (
+	"time"
+
+	"go-sober/internal/models"
+)
+
 type UserSignupRequest struct {
-	Email    string `json:"email"`
-	Password string `json:"password"`
+	Email    string `json:"email" validate:"required,email,max=254"`
+	Password string `json:"password" validate:"required"` // Checked against the password policy
 }
 
 type UserSignupResponse struct {
@@ -10,16 +16,134 @@ type UserSignupResponse struct {
 }
 
 type UserLoginRequest struct {
-	Email    string `json:"email"`
-	Password string `json:"password"`
+	Email    string `json:"email" validate:"required"`
+	Password string `json:"password" validate:"required"`
 }
 
+// UserLoginResponse holds the tokens, or an MFA challenge when the account has two-factor authentication
 type UserLoginResponse struct {
-	Message string `json:"message"`
-	Token   string `json:"token"`
+	Message      string `json:"message"`
+	Token        string `json:"token,omitempty"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+	ExpiresIn    int64  `json:"expires_in,omitempty"`   // Lifetime of the access token, in seconds
+	MFARequired  bool   `json:"mfa_required,omitempty"` // Send the code and the MFA token to /auth/login/mfa
+	MFAToken     string `json:"mfa_token,omitempty"`
+}
+
+type LoginMFARequest struct {
+	MFAToken string `json:"mfa_token" validate:"required"`
+	Code     string `json:"code" validate:"required"` // A code of the authenticator app or a recovery code
+}
+
+type OIDCLoginResponse struct {
+	AuthorizationURL string `json:"authorization_url"` // Identity provider page to send the user to
+	ClientKey        string `json:"client_key"`        // To keep on the client, e.g. in sessionStorage, and send back with the code
+}
+
+type OIDCCallbackRequest struct {
+	Code      string `json:"code" validate:"required"`
+	State     string `json:"state" validate:"required"`
+	ClientKey string `json:"client_key" validate:"required"` // The key given when the login started
+}
+
+type TOTPEnrolmentResponse struct {
+	Secret          string `json:"secret"`           // To type in the authenticator app
+	ProvisioningURI string `json:"provisioning_uri"` // otpauth:// URI, to show as a QR code
+}
+
+type MFACodeRequest struct {
+	Code string `json:"code" validate:"required"` // A code of the authenticator app or a recovery code
+}
+
+type RecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"` // Shown once, each code can be used once
+}
+
+type RefreshTokenRequest struct {
+	RefreshToken string `json:"refresh_token" validate:"required"`
+}
+
+type RefreshTokenResponse struct {
+	Token        string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int64  `json:"expires_in"` // Lifetime of the access token, in seconds
 }
 
 type UserMeResponse struct {
-	UserID int64  `json:"user_id"`
-	Email  string `json:"email"`
+	UserID int64       `json:"user_id"`
+	Email  string      `json:"email"`
+	Role   models.Role `json:"role"`
+}
+
+type MessageResponse struct {
+	Message string `json:"message"`
+}
+
+type VerifyEmailRequest struct {
+	Token string `json:"token" validate:"required"`
+}
+
+type ForgotPasswordRequest struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+type ResetPasswordRequest struct {
+	Token    string `json:"token" validate:"required"`
+	Password string `json:"password" validate:"required"` // Checked against the password policy
+}
+
+type SessionResponse struct {
+	ID           string    `json:"id"`
+	UserAgent    string    `json:"user_agent"`
+	IPAddress    string    `json:"ip_address"`
+	SignedInAt   time.Time `json:"signed_in_at"`
+	LastActiveAt time.Time `json:"last_active_at"`
+	ExpiresAt    time.Time `json:"expires_at"`
+	Current      bool      `json:"current"` // The session of the token making the request
+}
+
+type SessionsResponse struct {
+	Sessions []SessionResponse `json:"sessions"`
+}
+
+type CreatePersonalAccessTokenRequest struct {
+	Name          string   `json:"name" validate:"required,max=100"` // What the token is for, e.g. "Home Assistant"
+	Scopes        []string `json:"scopes" validate:"required,min=1,dive,oneof=drinks:read drinks:write bac:read analytics:read profile:read profile:write goals:read goals:write"`
+	ExpiresInDays int      `json:"expires_in_days,omitempty" validate:"omitempty,gt=0,lte=365"` // Never expires when omitted
+}
+
+type PersonalAccessTokenResponse struct {
+	ID         int64      `json:"id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"` // Start of the token, to recognise it
+	Scopes     []string   `json:"scopes"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+}
+
+// CreatePersonalAccessTokenResponse is the only time the token is shown
+type CreatePersonalAccessTokenResponse struct {
+	Token string `json:"token"`
+	PersonalAccessTokenResponse
+}
+
+type PersonalAccessTokensResponse struct {
+	Tokens []PersonalAccessTokenResponse `json:"tokens"`
+}
+
+// JSONWebKey is a public key verifying the access tokens (RFC 7517)
+type JSONWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`   // RSA modulus
+	E   string `json:"e,omitempty"`   // RSA exponent
+	Crv string `json:"crv,omitempty"` // Ed25519
+	X   string `json:"x,omitempty"`   // Ed25519 public key
+}
+
+type JWKSResponse struct {
+	Keys []JSONWebKey `json:"keys"`
 }
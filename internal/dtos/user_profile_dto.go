@@ -6,13 +6,48 @@ import (
 )
 
 type UpdateUserProfileRequest struct {
-	WeightKg float64       `json:"weight_kg" validate:"required,gt=0"`
-	Gender   models.Gender `json:"gender" validate:"required,oneof=male female unknown"`
+	WeightKg        float64       `json:"weight_kg" validate:"required,gt=0"`
+	Gender          models.Gender `json:"gender" validate:"required,oneof=male female unknown"`
+	Timezone        string        `json:"timezone,omitempty" validate:"omitempty,timezone"`              // IANA name, unchanged when omitted
+	DayRolloverHour *int          `json:"day_rollover_hour,omitempty" validate:"omitempty,gte=0,lte=23"` // Unchanged when omitted
 }
 
 type UserProfileResponse struct {
-	WeightKg  float64       `json:"weight_kg"`
-	Gender    models.Gender `json:"gender"`
-	CreatedAt time.Time     `json:"created_at"`
-	UpdatedAt time.Time     `json:"updated_at"`
+	WeightKg        float64       `json:"weight_kg"`
+	Gender          models.Gender `json:"gender"`
+	Timezone        string        `json:"timezone"`
+	DayRolloverHour int           `json:"day_rollover_hour"`
+	CreatedAt       time.Time     `json:"created_at"`
+	UpdatedAt       time.Time     `json:"updated_at"`
+}
+
+// WeightHistoryResponse is every weight of the user, oldest first
+type WeightHistoryResponse struct {
+	Weights []models.WeightEntry `json:"weights"`
+}
+
+// DrinkLimitsRequest replaces the limits of the user, in standard drinks. A null limit does not apply.
+type DrinkLimitsRequest struct {
+	DailyLimit     *float64 `json:"daily_limit" validate:"omitempty,gt=0"`
+	WeeklyLimit    *float64 `json:"weekly_limit" validate:"omitempty,gt=0"`
+	DryDaysPerWeek int      `json:"dry_days_per_week" validate:"gte=0,lte=7"`
+}
+
+type DrinkLimitsResponse struct {
+	Limits models.DrinkLimits `json:"limits"`
+}
+
+type AccountDeletionResponse struct {
+	Message  string    `json:"message"`
+	DeleteAt time.Time `json:"delete_at"` // Logging in before this date cancels the deletion
+}
+
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password" validate:"required"`
+	NewPassword     string `json:"new_password" validate:"required"` // Checked against the password policy
+}
+
+type ChangeEmailRequest struct {
+	Email    string `json:"email" validate:"required,email,max=254"`
+	Password string `json:"password" validate:"required"` // The current password
 }
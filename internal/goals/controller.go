@@ -0,0 +1,191 @@
+package goals
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"go-sober/internal/constants"
+	"go-sober/internal/dtos"
+	"go-sober/internal/models"
+	"go-sober/internal/validation"
+)
+
+type Controller struct {
+	service *Service
+}
+
+func NewController(service *Service) *Controller {
+	return &Controller{service: service}
+}
+
+// @Summary Create a goal
+// @Description Create a goal: at most a number of standard drinks a day, week or month (max_standard_drinks), at least a number of alcohol-free days a week or month (min_dry_days), or no drink between two dates (dry_period). The weeks start on Monday and the days follow the time zone and rollover hour of the profile.
+// @Tags goals
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Bearer token, or personal access token with the goals:write scope"
+// @Param goal body dtos.GoalRequest true "Goal"
+// @Success 201 {object} dtos.GoalResponse
+// @Failure 400 {object} dtos.ClientError
+// @Failure 401 {object} dtos.ClientError
+// @Failure 500 {object} dtos.ClientError
+// @Router /goals [post]
+func (c *Controller) CreateGoal(w http.ResponseWriter, r *http.Request) {
+	claims := r.Context().Value(constants.UserContextKey).(*models.Claims)
+
+	var req dtos.GoalRequest
+	if err := validation.DecodeJSON(r, &req); err != nil {
+		validation.WriteError(w, err)
+		return
+	}
+
+	goal, err := c.service.CreateGoal(claims.UserID, req)
+	if err != nil {
+		writeGoalError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(goal)
+}
+
+// @Summary Get the goals
+// @Description Get the goals of the current user, with the outcome of their current period and the number of periods met and failed
+// @Tags goals
+// @Produce json
+// @Param Authorization header string true "Bearer token, or personal access token with the goals:read scope"
+// @Success 200 {object} dtos.GoalsResponse
+// @Failure 401 {object} dtos.ClientError
+// @Failure 500 {object} dtos.ClientError
+// @Router /goals [get]
+func (c *Controller) GetGoals(w http.ResponseWriter, r *http.Request) {
+	claims := r.Context().Value(constants.UserContextKey).(*models.Claims)
+
+	goals, err := c.service.GetGoals(claims.UserID)
+	if err != nil {
+		writeGoalError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(dtos.GoalsResponse{Goals: goals})
+}
+
+// @Summary Get a goal
+// @Description Get a goal of the current user with the outcome of each of its periods, oldest first
+// @Tags goals
+// @Produce json
+// @Param Authorization header string true "Bearer token, or personal access token with the goals:read scope"
+// @Param id path int true "Goal ID"
+// @Success 200 {object} dtos.GoalResponse
+// @Failure 400 {object} dtos.ClientError
+// @Failure 404 {object} dtos.ClientError
+// @Failure 500 {object} dtos.ClientError
+// @Router /goals/{id} [get]
+func (c *Controller) GetGoal(w http.ResponseWriter, r *http.Request) {
+	claims := r.Context().Value(constants.UserContextKey).(*models.Claims)
+
+	goalID, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid goal ID", http.StatusBadRequest)
+		return
+	}
+
+	goal, err := c.service.GetGoal(claims.UserID, goalID)
+	if err != nil {
+		writeGoalError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(goal)
+}
+
+// @Summary Update a goal
+// @Description Replace a goal of the current user, its start date is kept when omitted
+// @Tags goals
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Bearer token, or personal access token with the goals:write scope"
+// @Param id path int true "Goal ID"
+// @Param goal body dtos.GoalRequest true "Goal"
+// @Success 200 {object} dtos.GoalResponse
+// @Failure 400 {object} dtos.ClientError
+// @Failure 404 {object} dtos.ClientError
+// @Failure 500 {object} dtos.ClientError
+// @Router /goals/{id} [put]
+func (c *Controller) UpdateGoal(w http.ResponseWriter, r *http.Request) {
+	claims := r.Context().Value(constants.UserContextKey).(*models.Claims)
+
+	goalID, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid goal ID", http.StatusBadRequest)
+		return
+	}
+
+	var req dtos.GoalRequest
+	if err := validation.DecodeJSON(r, &req); err != nil {
+		validation.WriteError(w, err)
+		return
+	}
+
+	goal, err := c.service.UpdateGoal(claims.UserID, goalID, req)
+	if err != nil {
+		writeGoalError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(goal)
+}
+
+// @Summary Delete a goal
+// @Description Delete a goal of the current user
+// @Tags goals
+// @Produce json
+// @Param Authorization header string true "Bearer token, or personal access token with the goals:write scope"
+// @Param id path int true "Goal ID"
+// @Success 200 {object} dtos.DeleteGoalResponse
+// @Failure 400 {object} dtos.ClientError
+// @Failure 404 {object} dtos.ClientError
+// @Failure 500 {object} dtos.ClientError
+// @Router /goals/{id} [delete]
+func (c *Controller) DeleteGoal(w http.ResponseWriter, r *http.Request) {
+	claims := r.Context().Value(constants.UserContextKey).(*models.Claims)
+
+	goalID, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid goal ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := c.service.DeleteGoal(claims.UserID, goalID); err != nil {
+		writeGoalError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(dtos.DeleteGoalResponse{ID: goalID})
+}
+
+func writeGoalError(w http.ResponseWriter, err error) {
+	var validationError *validation.Error
+	switch {
+	case errors.As(err, &validationError):
+		validation.WriteError(w, err)
+	case errors.Is(err, sql.ErrNoRows):
+		http.Error(w, "Goal not found", http.StatusNotFound)
+	default:
+		slog.Error("Could not handle goal", "error", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	}
+}
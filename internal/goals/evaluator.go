@@ -0,0 +1,139 @@
+package goals
+
+import (
+	"math"
+	"time"
+
+	"go-sober/internal/analytics"
+	"go-sober/internal/models"
+)
+
+const dateFormat = "2006-01-02"
+
+// evaluate returns the outcome of each period of a goal that started by today, oldest first.
+// The periods follow the calendar of the user: days, weeks from Monday and months. The first
+// and last periods are cut to the dates of the goal, a dry period is a single period.
+func evaluate(goal models.Goal, calendar analytics.Calendar, standardDrinksByDay map[time.Time]float64, start time.Time, end *time.Time, today time.Time) []models.GoalOutcome {
+	outcomes := []models.GoalOutcome{}
+	for periodStart := firstPeriod(goal, calendar, start); !periodStart.After(today); periodStart = nextPeriod(goal, calendar, periodStart) {
+		if end != nil && periodStart.After(*end) {
+			break
+		}
+
+		from, to := periodStart, calendar.AddDays(nextPeriod(goal, calendar, periodStart), -1)
+		if from.Before(start) {
+			from = start
+		}
+		if end != nil && to.After(*end) {
+			to = *end
+		}
+		outcomes = append(outcomes, evaluatePeriod(goal, calendar, standardDrinksByDay, from, to, today))
+	}
+	return outcomes
+}
+
+func firstPeriod(goal models.Goal, calendar analytics.Calendar, start time.Time) time.Time {
+	switch {
+	case goal.Type == models.GoalTypeDryPeriod:
+		return start
+	case goal.Period == models.TimePeriodWeekly:
+		return calendar.WeekStart(start)
+	case goal.Period == models.TimePeriodMonthly:
+		return calendar.MonthStart(start)
+	default:
+		return start
+	}
+}
+
+func nextPeriod(goal models.Goal, calendar analytics.Calendar, periodStart time.Time) time.Time {
+	switch {
+	case goal.Type == models.GoalTypeDryPeriod:
+		// A single period, that never ends without an end date
+		return time.Date(9999, 12, 31, 0, 0, 0, 0, periodStart.Location())
+	case goal.Period == models.TimePeriodWeekly:
+		return calendar.AddDays(periodStart, 7)
+	case goal.Period == models.TimePeriodMonthly:
+		return calendar.MonthStart(calendar.AddDays(periodStart, 31))
+	default:
+		return calendar.AddDays(periodStart, 1)
+	}
+}
+
+// evaluatePeriod computes the outcome of a goal between two days, both included. Today counts
+// with the drinks so far, but is not an alcohol-free day before it ends.
+func evaluatePeriod(goal models.Goal, calendar analytics.Calendar, standardDrinksByDay map[time.Time]float64, from, to, today time.Time) models.GoalOutcome {
+	var standardDrinks float64
+	var days, pastDays, dryDays int
+	for day := from; !day.After(to); day = calendar.AddDays(day, 1) {
+		days++
+		if day.After(today) {
+			continue
+		}
+		standardDrinks += standardDrinksByDay[day]
+		if day.Before(today) {
+			pastDays++
+			if standardDrinksByDay[day] == 0 {
+				dryDays++
+			}
+		}
+	}
+	standardDrinks = round(standardDrinks)
+	complete := to.Before(today)
+
+	outcome := models.GoalOutcome{
+		StartDate: from.Format(dateFormat),
+		EndDate:   to.Format(dateFormat),
+		Target:    goal.Target,
+		Status:    models.GoalStatusInProgress,
+	}
+
+	switch goal.Type {
+	case models.GoalTypeMinDryDays:
+		outcome.Target = math.Min(goal.Target, float64(days))
+		outcome.Value = float64(dryDays)
+		outcome.Progress = math.Min(100, percentage(outcome.Value, outcome.Target))
+		switch {
+		case outcome.Value >= outcome.Target:
+			outcome.Status = models.GoalStatusMet
+		case outcome.Value+float64(days-pastDays) < outcome.Target:
+			outcome.Status = models.GoalStatusFailed
+		}
+
+	case models.GoalTypeDryPeriod:
+		outcome.Target = 0
+		outcome.Value = standardDrinks
+		outcome.Progress = percentage(float64(pastDays), float64(days))
+		switch {
+		case standardDrinks > 0:
+			outcome.Status = models.GoalStatusFailed
+		case complete:
+			outcome.Status = models.GoalStatusMet
+		}
+
+	default:
+		outcome.Value = standardDrinks
+		outcome.Progress = percentage(standardDrinks, goal.Target)
+		switch {
+		case standardDrinks > goal.Target:
+			outcome.Status = models.GoalStatusFailed
+		case complete:
+			outcome.Status = models.GoalStatusMet
+		}
+	}
+	return outcome
+}
+
+// percentage returns a value as a percentage of a target, 100 for any value over a zero target
+func percentage(value, target float64) float64 {
+	if target <= 0 {
+		if value > 0 {
+			return 100
+		}
+		return 0
+	}
+	return round(value * 100 / target)
+}
+
+func round(value float64) float64 {
+	return math.Round(value*100) / 100
+}
@@ -0,0 +1,133 @@
+package goals
+
+import (
+	"testing"
+	"time"
+
+	"go-sober/internal/analytics"
+	"go-sober/internal/models"
+	"go-sober/internal/validation"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func day(month time.Month, day int) time.Time {
+	return time.Date(2025, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+func TestEvaluateMaxStandardDrinks(t *testing.T) {
+	calendar := analytics.NewCalendar(time.UTC, 6)
+	goal := models.Goal{Type: models.GoalTypeMaxStandardDrinks, Period: models.TimePeriodWeekly, Target: 10}
+	standardDrinksByDay := map[time.Time]float64{
+		day(1, 8): 4, day(1, 10): 5, // Within
+		day(1, 13): 6, day(1, 17): 6, // Over
+		day(1, 20): 3, // Current week
+	}
+
+	// Starting on Wednesday the 8th, the first week is cut
+	outcomes := evaluate(goal, calendar, standardDrinksByDay, day(1, 8), nil, day(1, 22))
+	require.Len(t, outcomes, 3)
+	assert.Equal(t, models.GoalOutcome{StartDate: "2025-01-08", EndDate: "2025-01-12", Value: 9, Target: 10, Progress: 90, Status: models.GoalStatusMet}, outcomes[0])
+	assert.Equal(t, models.GoalOutcome{StartDate: "2025-01-13", EndDate: "2025-01-19", Value: 12, Target: 10, Progress: 120, Status: models.GoalStatusFailed}, outcomes[1])
+	assert.Equal(t, models.GoalOutcome{StartDate: "2025-01-20", EndDate: "2025-01-26", Value: 3, Target: 10, Progress: 30, Status: models.GoalStatusInProgress}, outcomes[2])
+
+	// A goal starting later has no outcome yet
+	assert.Empty(t, evaluate(goal, calendar, standardDrinksByDay, day(2, 1), nil, day(1, 22)))
+}
+
+func TestEvaluateMinDryDays(t *testing.T) {
+	calendar := analytics.NewCalendar(time.UTC, 6)
+	goal := models.Goal{Type: models.GoalTypeMinDryDays, Period: models.TimePeriodWeekly, Target: 3}
+	standardDrinksByDay := map[time.Time]float64{
+		// 2 alcohol-free days
+		day(1, 6): 1, day(1, 7): 1, day(1, 8): 1, day(1, 9): 1, day(1, 10): 1,
+		// Wednesday, with drinks every day so far
+		day(1, 13): 1, day(1, 14): 1, day(1, 15): 1,
+	}
+
+	outcomes := evaluate(goal, calendar, standardDrinksByDay, day(1, 6), nil, day(1, 15))
+	require.Len(t, outcomes, 2)
+	assert.Equal(t, 2.0, outcomes[0].Value)
+	assert.Equal(t, models.GoalStatusFailed, outcomes[0].Status)
+	// Today is not over, 4 days are left for 3 alcohol-free days
+	assert.Equal(t, 0.0, outcomes[1].Value)
+	assert.Equal(t, models.GoalStatusInProgress, outcomes[1].Status)
+
+	// Two more drinking days and only 2 days are left
+	standardDrinksByDay[day(1, 16)] = 1
+	standardDrinksByDay[day(1, 17)] = 1
+	outcomes = evaluate(goal, calendar, standardDrinksByDay, day(1, 6), nil, day(1, 18))
+	assert.Equal(t, models.GoalStatusFailed, outcomes[1].Status)
+
+	// Met as soon as the alcohol-free days are there, with the target capped by a shorter period
+	outcomes = evaluate(goal, calendar, standardDrinksByDay, day(1, 11), nil, day(1, 13))
+	assert.Equal(t, models.GoalOutcome{StartDate: "2025-01-11", EndDate: "2025-01-12", Value: 2, Target: 2, Progress: 100, Status: models.GoalStatusMet}, outcomes[0])
+}
+
+func TestEvaluateDryPeriod(t *testing.T) {
+	calendar := analytics.NewCalendar(time.UTC, 6)
+	goal := models.Goal{Type: models.GoalTypeDryPeriod}
+	end := day(1, 31)
+
+	outcomes := evaluate(goal, calendar, nil, day(1, 1), &end, day(1, 11))
+	require.Len(t, outcomes, 1)
+	assert.Equal(t, models.GoalOutcome{StartDate: "2025-01-01", EndDate: "2025-01-31", Value: 0, Target: 0, Progress: 32.26, Status: models.GoalStatusInProgress}, outcomes[0])
+
+	outcomes = evaluate(goal, calendar, nil, day(1, 1), &end, day(3, 1))
+	assert.Equal(t, models.GoalStatusMet, outcomes[0].Status)
+	assert.Equal(t, 100.0, outcomes[0].Progress)
+
+	outcomes = evaluate(goal, calendar, map[time.Time]float64{day(1, 20): 1.5}, day(1, 1), &end, day(3, 1))
+	assert.Equal(t, 1.5, outcomes[0].Value)
+	assert.Equal(t, models.GoalStatusFailed, outcomes[0].Status)
+}
+
+func TestEvaluateMonthly(t *testing.T) {
+	calendar := analytics.NewCalendar(time.UTC, 6)
+	goal := models.Goal{Type: models.GoalTypeMaxStandardDrinks, Period: models.TimePeriodMonthly, Target: 20}
+	end := day(3, 15)
+
+	outcomes := evaluate(goal, calendar, map[time.Time]float64{day(2, 28): 25}, day(1, 31), &end, day(6, 1))
+	require.Len(t, outcomes, 3)
+	assert.Equal(t, "2025-01-31", outcomes[0].StartDate)
+	assert.Equal(t, "2025-02-01", outcomes[1].StartDate)
+	assert.Equal(t, "2025-02-28", outcomes[1].EndDate)
+	assert.Equal(t, models.GoalStatusFailed, outcomes[1].Status)
+	assert.Equal(t, "2025-03-15", outcomes[2].EndDate)
+	assert.Equal(t, models.GoalStatusMet, outcomes[2].Status)
+}
+
+func TestCheckGoal(t *testing.T) {
+	calendar := analytics.NewCalendar(time.UTC, 6)
+	endDate := "2024-12-31"
+
+	tests := []struct {
+		name   string
+		goal   models.Goal
+		fields []string
+	}{
+		{"valid", models.Goal{Type: models.GoalTypeMaxStandardDrinks, Period: models.TimePeriodDaily, Target: 2, StartDate: "2025-01-01"}, nil},
+		{"no period", models.Goal{Type: models.GoalTypeMaxStandardDrinks, Target: 2, StartDate: "2025-01-01"}, []string{"period"}},
+		{"daily dry days", models.Goal{Type: models.GoalTypeMinDryDays, Period: models.TimePeriodDaily, Target: 1, StartDate: "2025-01-01"}, []string{"period"}},
+		{"8 dry days a week", models.Goal{Type: models.GoalTypeMinDryDays, Period: models.TimePeriodWeekly, Target: 8, StartDate: "2025-01-01"}, []string{"target"}},
+		{"endless dry period", models.Goal{Type: models.GoalTypeDryPeriod, StartDate: "2025-01-01"}, []string{"end_date"}},
+		{"ending before it starts", models.Goal{Type: models.GoalTypeDryPeriod, StartDate: "2025-01-01", EndDate: &endDate}, []string{"end_date"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkGoal(&tt.goal, calendar)
+			if tt.fields == nil {
+				assert.NoError(t, err)
+				return
+			}
+			var validationError *validation.Error
+			require.ErrorAs(t, err, &validationError)
+			var fields []string
+			for _, field := range validationError.Fields {
+				fields = append(fields, field.Field)
+			}
+			assert.Equal(t, tt.fields, fields)
+		})
+	}
+}
@@ -0,0 +1,104 @@
+package goals
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"go-sober/internal/models"
+)
+
+type Repository struct {
+	db *sql.DB
+}
+
+func NewRepository(db *sql.DB) *Repository {
+	return &Repository{db: db}
+}
+
+const goalColumns = "id, user_id, name, type, period, target, start_date, end_date, created_at, updated_at"
+
+func scanGoal(row interface{ Scan(...any) error }) (*models.Goal, error) {
+	goal := &models.Goal{}
+	err := row.Scan(&goal.ID, &goal.UserID, &goal.Name, &goal.Type, &goal.Period, &goal.Target,
+		&goal.StartDate, &goal.EndDate, &goal.CreatedAt, &goal.UpdatedAt)
+	return goal, err
+}
+
+// CreateGoal saves a new goal and sets its ID and dates
+func (r *Repository) CreateGoal(goal *models.Goal) error {
+	now := time.Now().UTC()
+	result, err := r.db.Exec(`
+        INSERT INTO goals (user_id, name, type, period, target, start_date, end_date, created_at, updated_at)
+        VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+    `, goal.UserID, goal.Name, goal.Type, goal.Period, goal.Target, goal.StartDate, goal.EndDate, now, now)
+	if err != nil {
+		return fmt.Errorf("error creating goal: %w", err)
+	}
+
+	goal.ID, err = result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("error getting goal ID: %w", err)
+	}
+	goal.CreatedAt, goal.UpdatedAt = now, now
+	return nil
+}
+
+// GetGoals returns the goals of a user, oldest first
+func (r *Repository) GetGoals(userID int64) ([]models.Goal, error) {
+	rows, err := r.db.Query("SELECT "+goalColumns+" FROM goals WHERE user_id = ? ORDER BY id", userID)
+	if err != nil {
+		return nil, fmt.Errorf("error querying goals: %w", err)
+	}
+	defer rows.Close()
+
+	goals := []models.Goal{}
+	for rows.Next() {
+		goal, err := scanGoal(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning goal: %w", err)
+		}
+		goals = append(goals, *goal)
+	}
+	return goals, rows.Err()
+}
+
+// GetGoal returns a goal of a user, sql.ErrNoRows if the user has no such goal
+func (r *Repository) GetGoal(userID, goalID int64) (*models.Goal, error) {
+	goal, err := scanGoal(r.db.QueryRow("SELECT "+goalColumns+" FROM goals WHERE id = ? AND user_id = ?", goalID, userID))
+	if err == sql.ErrNoRows {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error querying goal: %w", err)
+	}
+	return goal, nil
+}
+
+// UpdateGoal replaces a goal of a user, sql.ErrNoRows if the user has no such goal
+func (r *Repository) UpdateGoal(goal *models.Goal) error {
+	result, err := r.db.Exec(`
+        UPDATE goals
+        SET name = ?, type = ?, period = ?, target = ?, start_date = ?, end_date = ?, updated_at = ?
+        WHERE id = ? AND user_id = ?
+    `, goal.Name, goal.Type, goal.Period, goal.Target, goal.StartDate, goal.EndDate, time.Now().UTC(), goal.ID, goal.UserID)
+	if err != nil {
+		return fmt.Errorf("error updating goal: %w", err)
+	}
+	if rows, err := result.RowsAffected(); err != nil || rows == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// DeleteGoal deletes a goal of a user, sql.ErrNoRows if the user has no such goal
+func (r *Repository) DeleteGoal(userID, goalID int64) error {
+	result, err := r.db.Exec("DELETE FROM goals WHERE id = ? AND user_id = ?", goalID, userID)
+	if err != nil {
+		return fmt.Errorf("error deleting goal: %w", err)
+	}
+	if rows, err := result.RowsAffected(); err != nil || rows == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
@@ -0,0 +1,92 @@
+package goals
+
+import (
+	"database/sql"
+	"testing"
+
+	"go-sober/internal/models"
+
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupTestDB(t *testing.T) *Repository {
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("Failed to create database: %v", err)
+	}
+	db.SetMaxOpenConns(1)
+
+	_, err = db.Exec(`
+		CREATE TABLE goals (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			user_id INTEGER NOT NULL,
+			name TEXT NOT NULL,
+			type TEXT NOT NULL,
+			period TEXT NOT NULL DEFAULT '',
+			target REAL NOT NULL DEFAULT 0,
+			start_date TEXT NOT NULL,
+			end_date TEXT,
+			created_at DATETIME NOT NULL,
+			updated_at DATETIME NOT NULL
+		);
+	`)
+	if err != nil {
+		t.Fatalf("Failed to create goals table: %v", err)
+	}
+
+	return NewRepository(db)
+}
+
+func TestGoalsCRUD(t *testing.T) {
+	repo := setupTestDB(t)
+
+	goal := &models.Goal{
+		UserID:    1,
+		Name:      "Ten a week",
+		Type:      models.GoalTypeMaxStandardDrinks,
+		Period:    models.TimePeriodWeekly,
+		Target:    10,
+		StartDate: "2025-01-06",
+	}
+	require.NoError(t, repo.CreateGoal(goal))
+	assert.NotZero(t, goal.ID)
+
+	endDate := "2025-01-31"
+	require.NoError(t, repo.CreateGoal(&models.Goal{
+		UserID: 1, Name: "Dry January", Type: models.GoalTypeDryPeriod, StartDate: "2025-01-01", EndDate: &endDate,
+	}))
+	require.NoError(t, repo.CreateGoal(&models.Goal{
+		UserID: 2, Name: "Someone else's", Type: models.GoalTypeMinDryDays, Period: models.TimePeriodWeekly, Target: 3, StartDate: "2025-01-06",
+	}))
+
+	goals, err := repo.GetGoals(1)
+	require.NoError(t, err)
+	require.Len(t, goals, 2)
+	assert.Equal(t, "Ten a week", goals[0].Name)
+	assert.Nil(t, goals[0].EndDate)
+	assert.Equal(t, "2025-01-31", *goals[1].EndDate)
+
+	saved, err := repo.GetGoal(1, goal.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.TimePeriodWeekly, saved.Period)
+	assert.Equal(t, 10.0, saved.Target)
+
+	saved.Target = 8
+	require.NoError(t, repo.UpdateGoal(saved))
+	saved, err = repo.GetGoal(1, goal.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 8.0, saved.Target)
+
+	// The goals of other users are out of reach
+	_, err = repo.GetGoal(2, goal.ID)
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+	saved.UserID = 2
+	assert.ErrorIs(t, repo.UpdateGoal(saved), sql.ErrNoRows)
+	assert.ErrorIs(t, repo.DeleteGoal(2, goal.ID), sql.ErrNoRows)
+
+	require.NoError(t, repo.DeleteGoal(1, goal.ID))
+	_, err = repo.GetGoal(1, goal.ID)
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+}
@@ -0,0 +1,242 @@
+package goals
+
+import (
+	"time"
+
+	"go-sober/internal/analytics"
+	"go-sober/internal/dtos"
+	"go-sober/internal/models"
+	"go-sober/internal/validation"
+)
+
+// DrinksRepository gives the standard drinks of each day of a user, the goals are evaluated with
+type DrinksRepository interface {
+	GetStandardDrinksByDay(userID int64, firstDay, lastDay time.Time, calendar analytics.Calendar) (map[time.Time]float64, error)
+}
+
+// ProfileRepository gives the profile of a user, for their time zone and drinking days
+type ProfileRepository interface {
+	GetUserProfile(userID int64) (*models.UserProfile, error)
+}
+
+type Service struct {
+	repo        *Repository
+	drinksRepo  DrinksRepository
+	profileRepo ProfileRepository
+}
+
+func NewService(repo *Repository, drinksRepo DrinksRepository, profileRepo ProfileRepository) *Service {
+	return &Service{repo: repo, drinksRepo: drinksRepo, profileRepo: profileRepo}
+}
+
+func (s *Service) calendar(userID int64) (analytics.Calendar, error) {
+	profile, err := s.profileRepo.GetUserProfile(userID)
+	if err != nil {
+		return analytics.Calendar{}, err
+	}
+	return analytics.ProfileCalendar(profile), nil
+}
+
+// CreateGoal saves a new goal of a user, starting today when the request has no start date
+func (s *Service) CreateGoal(userID int64, req dtos.GoalRequest) (*dtos.GoalResponse, error) {
+	calendar, err := s.calendar(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	goal := newGoal(userID, req, calendar)
+	if err := checkGoal(goal, calendar); err != nil {
+		return nil, err
+	}
+	if err := s.repo.CreateGoal(goal); err != nil {
+		return nil, err
+	}
+	return s.evaluate(goal, calendar, false)
+}
+
+// UpdateGoal replaces a goal of a user, sql.ErrNoRows if the user has no such goal
+func (s *Service) UpdateGoal(userID, goalID int64, req dtos.GoalRequest) (*dtos.GoalResponse, error) {
+	calendar, err := s.calendar(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	existing, err := s.repo.GetGoal(userID, goalID)
+	if err != nil {
+		return nil, err
+	}
+	if req.StartDate == "" {
+		req.StartDate = existing.StartDate
+	}
+
+	goal := newGoal(userID, req, calendar)
+	goal.ID = goalID
+	if err := checkGoal(goal, calendar); err != nil {
+		return nil, err
+	}
+	if err := s.repo.UpdateGoal(goal); err != nil {
+		return nil, err
+	}
+
+	updated, err := s.repo.GetGoal(userID, goalID)
+	if err != nil {
+		return nil, err
+	}
+	return s.evaluate(updated, calendar, false)
+}
+
+func (s *Service) DeleteGoal(userID, goalID int64) error {
+	return s.repo.DeleteGoal(userID, goalID)
+}
+
+// GetGoals returns the goals of a user with the outcome of their current period
+func (s *Service) GetGoals(userID int64) ([]dtos.GoalResponse, error) {
+	calendar, err := s.calendar(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	goals, err := s.repo.GetGoals(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	responses := make([]dtos.GoalResponse, 0, len(goals))
+	for i := range goals {
+		response, err := s.evaluate(&goals[i], calendar, false)
+		if err != nil {
+			return nil, err
+		}
+		responses = append(responses, *response)
+	}
+	return responses, nil
+}
+
+// GetGoal returns a goal of a user with the outcome of each of its periods, sql.ErrNoRows if the
+// user has no such goal
+func (s *Service) GetGoal(userID, goalID int64) (*dtos.GoalResponse, error) {
+	calendar, err := s.calendar(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	goal, err := s.repo.GetGoal(userID, goalID)
+	if err != nil {
+		return nil, err
+	}
+	return s.evaluate(goal, calendar, true)
+}
+
+// evaluate computes the outcomes of a goal from the drinks of its user
+func (s *Service) evaluate(goal *models.Goal, calendar analytics.Calendar, withHistory bool) (*dtos.GoalResponse, error) {
+	start, end, err := goalDays(goal, calendar)
+	if err != nil {
+		return nil, err
+	}
+
+	today := calendar.Day(time.Now())
+	lastDay := today
+	if end != nil && end.Before(lastDay) {
+		lastDay = *end
+	}
+	standardDrinksByDay, err := s.drinksRepo.GetStandardDrinksByDay(goal.UserID, start, lastDay, calendar)
+	if err != nil {
+		return nil, err
+	}
+
+	outcomes := evaluate(*goal, calendar, standardDrinksByDay, start, end, today)
+	response := &dtos.GoalResponse{Goal: *goal}
+	for _, outcome := range outcomes {
+		switch outcome.Status {
+		case models.GoalStatusMet:
+			response.Met++
+		case models.GoalStatusFailed:
+			response.Failed++
+		}
+	}
+	if len(outcomes) > 0 {
+		response.Current = &outcomes[len(outcomes)-1]
+	}
+	if withHistory {
+		response.History = outcomes
+	}
+	return response, nil
+}
+
+func newGoal(userID int64, req dtos.GoalRequest, calendar analytics.Calendar) *models.Goal {
+	goal := &models.Goal{
+		UserID:    userID,
+		Name:      req.Name,
+		Type:      req.Type,
+		Period:    req.Period,
+		Target:    req.Target,
+		StartDate: req.StartDate,
+		EndDate:   req.EndDate,
+	}
+	if goal.StartDate == "" {
+		goal.StartDate = calendar.Day(time.Now()).Format(dateFormat)
+	}
+	if goal.Type == models.GoalTypeDryPeriod {
+		goal.Period = ""
+		goal.Target = 0
+	}
+	return goal
+}
+
+// goalDays returns the first and last days of a goal, the last one is nil for a goal without end
+func goalDays(goal *models.Goal, calendar analytics.Calendar) (time.Time, *time.Time, error) {
+	start, err := calendar.ParseDay(goal.StartDate)
+	if err != nil {
+		return time.Time{}, nil, err
+	}
+	if goal.EndDate == nil {
+		return start, nil, nil
+	}
+	end, err := calendar.ParseDay(*goal.EndDate)
+	if err != nil {
+		return time.Time{}, nil, err
+	}
+	return start, &end, nil
+}
+
+// checkGoal returns a *validation.Error listing what does not fit the type of a goal, or nil
+func checkGoal(goal *models.Goal, calendar analytics.Calendar) error {
+	var fields []dtos.FieldError
+	reject := func(field, rule, message string) {
+		fields = append(fields, dtos.FieldError{Field: field, Rule: rule, Message: message})
+	}
+
+	switch goal.Type {
+	case models.GoalTypeDryPeriod:
+		if goal.EndDate == nil {
+			reject("end_date", "required", "is required for a dry period")
+		}
+	case models.GoalTypeMinDryDays:
+		switch goal.Period {
+		case models.TimePeriodWeekly:
+			if goal.Target < 1 || goal.Target > 7 {
+				reject("target", "between", "must be between 1 and 7 alcohol-free days a week")
+			}
+		case models.TimePeriodMonthly:
+			if goal.Target < 1 || goal.Target > 31 {
+				reject("target", "between", "must be between 1 and 31 alcohol-free days a month")
+			}
+		default:
+			reject("period", "oneof", "must be one of: weekly, monthly")
+		}
+	default:
+		if goal.Period == "" {
+			reject("period", "required", "is required")
+		}
+	}
+
+	start, end, err := goalDays(goal, calendar)
+	if err == nil && end != nil && end.Before(start) {
+		reject("end_date", "gtefield", "must not be before the start date")
+	}
+
+	if len(fields) == 0 {
+		return nil
+	}
+	return &validation.Error{Message: "Invalid goal", Fields: fields}
+}
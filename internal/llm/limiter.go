@@ -0,0 +1,43 @@
+package llm
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrProviderBusy is returned when no call slot frees up in time
+var ErrProviderBusy = errors.New("LLM provider is busy")
+
+// LimitedProvider caps the number of concurrent calls to a provider.
+// Callers wait for a free slot up to the queue timeout.
+type LimitedProvider struct {
+	provider     Provider
+	slots        chan struct{}
+	queueTimeout time.Duration
+}
+
+func NewLimitedProvider(provider Provider, maxConcurrentCalls int, queueTimeout time.Duration) *LimitedProvider {
+	if maxConcurrentCalls < 1 {
+		maxConcurrentCalls = 1
+	}
+
+	return &LimitedProvider{
+		provider:     provider,
+		slots:        make(chan struct{}, maxConcurrentCalls),
+		queueTimeout: queueTimeout,
+	}
+}
+
+func (p *LimitedProvider) Call(systemPrompt string, userPrompt string) (string, error) {
+	timer := time.NewTimer(p.queueTimeout)
+	defer timer.Stop()
+
+	select {
+	case p.slots <- struct{}{}:
+	case <-timer.C:
+		return "", ErrProviderBusy
+	}
+	defer func() { <-p.slots }()
+
+	return p.provider.Call(systemPrompt, userPrompt)
+}
@@ -2,34 +2,44 @@ package llm
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"go-sober/platform"
-	"log"
 
-	"github.com/joho/godotenv"
 	"github.com/tmc/langchaingo/llms"
 	"github.com/tmc/langchaingo/llms/openai"
 )
 
-// RunLLM executes the LLM generation with the given prompt
-func Call(systemPrompt string, userPrompt string) (string, error) {
-	// Load the Groq API key from the .env file if you use it
-	err := godotenv.Load()
-	if err != nil {
-		log.Fatalf("Error loading .env file")
-	}
+// Provider generates a completion from a system and a user prompt
+type Provider interface {
+	Call(systemPrompt string, userPrompt string) (string, error)
+}
+
+// GroqProvider calls the Groq OpenAI-compatible API.
+// The client is built once and shared by every call.
+type GroqProvider struct {
+	client *openai.LLM
+}
 
-	llm, err := openai.New(
-		openai.WithModel(platform.AppConfig.LLM.Groq.Model),
-		openai.WithBaseURL(platform.AppConfig.LLM.Groq.BaseURL),
-		openai.WithToken(platform.AppConfig.LLM.Groq.APIKey),
+func NewGroqProvider(config platform.LLMConfig) (*GroqProvider, error) {
+	client, err := openai.New(
+		openai.WithModel(config.Groq.Model),
+		openai.WithBaseURL(config.Groq.BaseURL),
+		openai.WithToken(config.Groq.APIKey),
 		openai.WithResponseFormat(openai.ResponseFormatJSON),
 	)
 	if err != nil {
-		log.Fatal(err)
+		return nil, fmt.Errorf("could not create LLM client: %w", err)
 	}
+
+	return &GroqProvider{client: client}, nil
+}
+
+// Call executes the LLM generation with the given prompt
+func (p *GroqProvider) Call(systemPrompt string, userPrompt string) (string, error) {
 	ctx := context.Background()
 
-	completion, err := llm.GenerateContent(ctx, []llms.MessageContent{
+	completion, err := p.client.GenerateContent(ctx, []llms.MessageContent{
 		{
 			Role:  llms.ChatMessageTypeSystem,
 			Parts: []llms.ContentPart{llms.TextContent{Text: systemPrompt}},
@@ -46,7 +56,11 @@ func Call(systemPrompt string, userPrompt string) (string, error) {
 	)
 
 	if err != nil {
-		log.Fatal(err)
+		return "", fmt.Errorf("could not generate content: %w", err)
+	}
+
+	if len(completion.Choices) == 0 {
+		return "", errors.New("LLM returned no choices")
 	}
 
 	return completion.Choices[0].Content, nil
@@ -0,0 +1,129 @@
+package llm
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+)
+
+// How to refresh a recording after a prompt change
+const reRecordHint = "re-record with task test:parser:live"
+
+var (
+	ErrMissingResponse = errors.New("no recorded response")
+	ErrStaleResponse   = errors.New("response recorded with another prompt")
+)
+
+// Recording is a set of LLM responses keyed by user prompt.
+// It lets tests and evaluations replay a model without network access.
+type Recording struct {
+	Model     string                      `json:"model"`
+	Responses map[string]RecordedResponse `json:"responses"`
+}
+
+// RecordedResponse is a response with the hash of the full prompt it answered, so that
+// a change of the system prompt is noticed instead of replaying outdated answers
+type RecordedResponse struct {
+	PromptHash string `json:"prompt_hash"`
+	Response   string `json:"response"`
+}
+
+// PromptHash identifies a system and user prompt pair
+func PromptHash(systemPrompt, userPrompt string) string {
+	hash := sha256.New()
+	hash.Write([]byte(systemPrompt))
+	hash.Write([]byte{0})
+	hash.Write([]byte(userPrompt))
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// Response returns the recorded response to a prompt. It fails when there is none, or when
+// it was recorded with another system prompt.
+func (r *Recording) Response(systemPrompt, userPrompt string) (string, error) {
+	recorded, ok := r.Responses[userPrompt]
+	if !ok {
+		return "", fmt.Errorf("%w for %s, %s", ErrMissingResponse, userPrompt, reRecordHint)
+	}
+	if recorded.PromptHash != PromptHash(systemPrompt, userPrompt) {
+		return "", fmt.Errorf("%w for %s, %s", ErrStaleResponse, userPrompt, reRecordHint)
+	}
+	return recorded.Response, nil
+}
+
+// LoadRecording reads a recording from a JSON file
+func LoadRecording(path string) (*Recording, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not read recording: %w", err)
+	}
+
+	var recording Recording
+	if err := json.Unmarshal(data, &recording); err != nil {
+		return nil, fmt.Errorf("could not decode recording: %w", err)
+	}
+	if recording.Responses == nil {
+		recording.Responses = make(map[string]RecordedResponse)
+	}
+	return &recording, nil
+}
+
+// Save writes a recording to a JSON file
+func (r *Recording) Save(path string) error {
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return fmt.Errorf("could not encode recording: %w", err)
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
+
+// ReplayProvider answers with recorded responses only
+type ReplayProvider struct {
+	recording *Recording
+}
+
+func NewReplayProvider(recording *Recording) *ReplayProvider {
+	return &ReplayProvider{recording: recording}
+}
+
+func (p *ReplayProvider) Call(systemPrompt string, userPrompt string) (string, error) {
+	return p.recording.Response(systemPrompt, userPrompt)
+}
+
+// RecordingProvider forwards calls to a provider and records its responses
+type RecordingProvider struct {
+	provider  Provider
+	recording *Recording
+	mu        sync.Mutex
+}
+
+func NewRecordingProvider(provider Provider, model string) *RecordingProvider {
+	return &RecordingProvider{
+		provider:  provider,
+		recording: &Recording{Model: model, Responses: make(map[string]RecordedResponse)},
+	}
+}
+
+func (p *RecordingProvider) Call(systemPrompt string, userPrompt string) (string, error) {
+	response, err := p.provider.Call(systemPrompt, userPrompt)
+	if err != nil {
+		return "", err
+	}
+
+	p.mu.Lock()
+	p.recording.Responses[userPrompt] = RecordedResponse{
+		PromptHash: PromptHash(systemPrompt, userPrompt),
+		Response:   response,
+	}
+	p.mu.Unlock()
+
+	return response, nil
+}
+
+// Recording returns the responses recorded so far
+func (p *RecordingProvider) Recording() *Recording {
+	return p.recording
+}
@@ -0,0 +1,40 @@
+package llm
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// echoProvider answers with the user prompt
+type echoProvider struct{}
+
+func (echoProvider) Call(systemPrompt string, userPrompt string) (string, error) {
+	return "echo " + userPrompt, nil
+}
+
+func TestRecording(t *testing.T) {
+	recorder := NewRecordingProvider(echoProvider{}, "test-model")
+	_, err := recorder.Call("system v1", "pint of stout")
+	require.NoError(t, err)
+
+	path := filepath.Join(t.TempDir(), "recording.json")
+	require.NoError(t, recorder.Recording().Save(path))
+	recording, err := LoadRecording(path)
+	require.NoError(t, err)
+	replay := NewReplayProvider(recording)
+
+	response, err := replay.Call("system v1", "pint of stout")
+	require.NoError(t, err)
+	assert.Equal(t, "echo pint of stout", response)
+
+	_, err = replay.Call("system v1", "glass of merlot")
+	assert.ErrorIs(t, err, ErrMissingResponse)
+
+	// A prompt change is not hidden by the old answers
+	_, err = replay.Call("system v2", "pint of stout")
+	assert.ErrorIs(t, err, ErrStaleResponse)
+	assert.Contains(t, err.Error(), "re-record")
+}
@@ -0,0 +1,32 @@
+package mailer
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"time"
+)
+
+var unsafeFileChars = regexp.MustCompile(`[^a-zA-Z0-9@._-]`)
+
+// FileMailer writes each email to a .eml file, to read them during local development
+type FileMailer struct {
+	dir  string
+	from string
+}
+
+func NewFileMailer(dir, from string) (*FileMailer, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("could not create mail directory: %w", err)
+	}
+	return &FileMailer{dir: dir, from: from}, nil
+}
+
+func (m *FileMailer) Send(message Message) error {
+	name := fmt.Sprintf("%s-%s.eml", time.Now().Format("20060102-150405.000000000"), unsafeFileChars.ReplaceAllString(message.To, "_"))
+	if err := os.WriteFile(filepath.Join(m.dir, name), formatMessage(m.from, message), 0o644); err != nil {
+		return fmt.Errorf("could not write email: %w", err)
+	}
+	return nil
+}
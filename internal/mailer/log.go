@@ -0,0 +1,17 @@
+package mailer
+
+import "log/slog"
+
+// LogMailer logs emails instead of sending them
+type LogMailer struct {
+	from string
+}
+
+func NewLogMailer(from string) *LogMailer {
+	return &LogMailer{from: from}
+}
+
+func (m *LogMailer) Send(message Message) error {
+	slog.Info("Email", "from", m.from, "to", message.To, "subject", message.Subject, "body", message.Body)
+	return nil
+}
@@ -0,0 +1,40 @@
+package mailer
+
+import (
+	"fmt"
+
+	"go-sober/platform"
+)
+
+// Mail drivers
+const (
+	DriverSMTP = "smtp"
+	DriverFile = "file"
+	DriverLog  = "log"
+)
+
+// Message is a plain text email
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer sends emails
+type Mailer interface {
+	Send(message Message) error
+}
+
+// New creates the mailer selected by the configuration
+func New(config platform.MailerConfig) (Mailer, error) {
+	switch config.Driver {
+	case DriverSMTP:
+		return NewSMTPMailer(config.SMTP.Host, config.SMTP.Port, config.SMTP.Username, config.SMTP.Password, config.From), nil
+	case DriverFile:
+		return NewFileMailer(config.FileDir, config.From)
+	case DriverLog:
+		return NewLogMailer(config.From), nil
+	default:
+		return nil, fmt.Errorf("unknown mail driver %q", config.Driver)
+	}
+}
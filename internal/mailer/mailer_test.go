@@ -0,0 +1,102 @@
+package mailer
+
+import (
+	"bufio"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeSMTPServer accepts a single email and sends its data on the returned channel
+func fakeSMTPServer(t *testing.T) (host string, port int, received <-chan string) {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { listener.Close() })
+
+	data := make(chan string, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		reader := bufio.NewReader(conn)
+		reply := func(line string) { conn.Write([]byte(line + "\r\n")) }
+
+		reply("220 localhost fake SMTP")
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				return
+			}
+			command := strings.ToUpper(strings.TrimSpace(line))
+
+			switch {
+			case strings.HasPrefix(command, "EHLO"), strings.HasPrefix(command, "HELO"):
+				reply("250 localhost")
+			case strings.HasPrefix(command, "DATA"):
+				reply("354 End data with <CR><LF>.<CR><LF>")
+				var body strings.Builder
+				for {
+					line, err := reader.ReadString('\n')
+					if err != nil {
+						return
+					}
+					if line == ".\r\n" {
+						break
+					}
+					body.WriteString(line)
+				}
+				data <- body.String()
+				reply("250 OK")
+			case strings.HasPrefix(command, "QUIT"):
+				reply("221 Bye")
+				return
+			default:
+				reply("250 OK")
+			}
+		}
+	}()
+
+	addr := listener.Addr().(*net.TCPAddr)
+	return addr.IP.String(), addr.Port, data
+}
+
+func TestSMTPMailer(t *testing.T) {
+	host, port, received := fakeSMTPServer(t)
+	mailer := NewSMTPMailer(host, port, "", "", "sober@example.com")
+
+	err := mailer.Send(Message{To: "user@example.com", Subject: "Hello", Body: "First line\nSecond line"})
+	require.NoError(t, err)
+
+	data := <-received
+	assert.Contains(t, data, "From: sober@example.com\r\n")
+	assert.Contains(t, data, "To: user@example.com\r\n")
+	assert.Contains(t, data, "Subject: Hello\r\n")
+	assert.Contains(t, data, "First line\r\nSecond line")
+}
+
+func TestFileMailer(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "mails")
+	mailer, err := NewFileMailer(dir, "sober@example.com")
+	require.NoError(t, err)
+
+	require.NoError(t, mailer.Send(Message{To: "user@example.com", Subject: "Hello", Body: "Body"}))
+
+	files, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+	assert.True(t, strings.HasSuffix(files[0].Name(), "user@example.com.eml"))
+
+	content, err := os.ReadFile(filepath.Join(dir, files[0].Name()))
+	require.NoError(t, err)
+	assert.Contains(t, string(content), "Subject: Hello")
+}
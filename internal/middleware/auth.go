@@ -3,11 +3,13 @@ package middleware
 import (
 	"context"
 	"errors"
+	"log/slog"
 	"net/http"
 	"strings"
 
 	"go-sober/internal/auth"
 	"go-sober/internal/constants"
+	"go-sober/internal/models"
 )
 
 type AuthMiddleware struct {
@@ -18,6 +20,7 @@ func NewAuthMiddleware(service *auth.Service) *AuthMiddleware {
 	return &AuthMiddleware{service: service}
 }
 
+// RequireAuth only accepts the access tokens of a session, for the routes that manage the account
 func (m *AuthMiddleware) RequireAuth(next http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		token, err := extractTokenFromHeader(r)
@@ -26,9 +29,13 @@ func (m *AuthMiddleware) RequireAuth(next http.HandlerFunc) http.HandlerFunc {
 			return
 		}
 
-		claims, err := m.service.ValidateToken(token)
-		if err != nil {
-			http.Error(w, "Invalid token", http.StatusUnauthorized)
+		if auth.IsPersonalAccessToken(token) {
+			http.Error(w, "Personal access tokens are not accepted here", http.StatusForbidden)
+			return
+		}
+
+		claims, ok := m.validateAccessToken(w, token)
+		if !ok {
 			return
 		}
 
@@ -38,6 +45,84 @@ func (m *AuthMiddleware) RequireAuth(next http.HandlerFunc) http.HandlerFunc {
 	}
 }
 
+// RequireScope accepts the access tokens of a session, and the personal access tokens with the scope
+func (m *AuthMiddleware) RequireScope(scope string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token, err := extractTokenFromHeader(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+
+		var claims *models.Claims
+		if auth.IsPersonalAccessToken(token) {
+			claims, err = m.service.AuthenticatePersonalAccessToken(token)
+			if errors.Is(err, auth.ErrInvalidPersonalAccessToken) {
+				http.Error(w, "Invalid token", http.StatusUnauthorized)
+				return
+			}
+			if err != nil {
+				slog.Error("Could not check personal access token", "error", err)
+				http.Error(w, "Could not validate token", http.StatusInternalServerError)
+				return
+			}
+		} else {
+			var ok bool
+			if claims, ok = m.validateAccessToken(w, token); !ok {
+				return
+			}
+		}
+
+		if !claims.HasScope(scope) {
+			http.Error(w, "Token is missing the "+scope+" scope", http.StatusForbidden)
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), constants.UserContextKey, claims)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	}
+}
+
+// RequireRole only lets users with the role through. It runs inside RequireAuth or RequireScope,
+// which put the claims in the context.
+func (m *AuthMiddleware) RequireRole(role models.Role, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		claims, ok := r.Context().Value(constants.UserContextKey).(*models.Claims)
+		if !ok || claims == nil {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		if claims.Role != role {
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	}
+}
+
+// validateAccessToken checks a JWT access token, it writes the response when it is not valid
+func (m *AuthMiddleware) validateAccessToken(w http.ResponseWriter, token string) (*models.Claims, bool) {
+	claims, err := m.service.ValidateToken(token)
+	if err != nil {
+		http.Error(w, "Invalid token", http.StatusUnauthorized)
+		return nil, false
+	}
+
+	revoked, err := m.service.IsTokenRevoked(claims)
+	if err != nil {
+		slog.Error("Could not check token revocation", "error", err)
+		http.Error(w, "Could not validate token", http.StatusInternalServerError)
+		return nil, false
+	}
+	if revoked {
+		http.Error(w, "Token revoked", http.StatusUnauthorized)
+		return nil, false
+	}
+	return claims, true
+}
+
 func extractTokenFromHeader(r *http.Request) (string, error) {
 	authHeader := r.Header.Get("Authorization")
 	if authHeader == "" {
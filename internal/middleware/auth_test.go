@@ -1,8 +1,13 @@
 package middleware
 
 import (
+	"context"
 	"net/http"
+	"net/http/httptest"
 	"testing"
+
+	"go-sober/internal/constants"
+	"go-sober/internal/models"
 )
 
 func TestExtractTokenFromHeader(t *testing.T) {
@@ -64,3 +69,51 @@ func TestExtractTokenFromHeader(t *testing.T) {
 		})
 	}
 }
+
+func TestRequireAuthRejectsPersonalAccessTokens(t *testing.T) {
+	m := NewAuthMiddleware(nil)
+	handler := m.RequireAuth(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("handler should not be called")
+	})
+
+	req, _ := http.NewRequest("GET", "/", nil)
+	req.Header.Set("Authorization", "Bearer sober_pat_abc123")
+	rec := httptest.NewRecorder()
+	handler(rec, req)
+
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("expected status %d, got %d", http.StatusForbidden, rec.Code)
+	}
+}
+
+func TestRequireRole(t *testing.T) {
+	tests := []struct {
+		name           string
+		claims         *models.Claims
+		expectedStatus int
+	}{
+		{name: "Admin", claims: &models.Claims{UserID: 1, Role: models.RoleAdmin}, expectedStatus: http.StatusOK},
+		{name: "User", claims: &models.Claims{UserID: 2, Role: models.RoleUser}, expectedStatus: http.StatusForbidden},
+		{name: "No claims", expectedStatus: http.StatusUnauthorized},
+	}
+
+	m := NewAuthMiddleware(nil)
+	handler := m.RequireRole(models.RoleAdmin, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest("GET", "/", nil)
+			if tt.claims != nil {
+				req = req.WithContext(context.WithValue(req.Context(), constants.UserContextKey, tt.claims))
+			}
+			rec := httptest.NewRecorder()
+			handler(rec, req)
+
+			if rec.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d", tt.expectedStatus, rec.Code)
+			}
+		})
+	}
+}
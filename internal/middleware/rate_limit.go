@@ -0,0 +1,64 @@
+package middleware
+
+import (
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"go-sober/internal/constants"
+	"go-sober/internal/models"
+	"go-sober/internal/ratelimit"
+)
+
+type RateLimitMiddleware struct {
+	limiter *ratelimit.Limiter
+}
+
+func NewRateLimitMiddleware(limiter *ratelimit.Limiter) *RateLimitMiddleware {
+	return &RateLimitMiddleware{limiter: limiter}
+}
+
+// LimitPerUser rejects the requests of a user above the limit.
+// It must be wrapped by RequireAuth so that the claims are in the context.
+func (m *RateLimitMiddleware) LimitPerUser(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		claims, ok := r.Context().Value(constants.UserContextKey).(*models.Claims)
+		if !ok {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		allowed, retryAfter := m.limiter.Allow(strconv.FormatInt(claims.UserID, 10))
+		if !allowed {
+			tooManyRequests(w, retryAfter)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	}
+}
+
+// LimitPerIP rejects the requests of a client address above the limit, for public routes
+func (m *RateLimitMiddleware) LimitPerIP(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		host, _, err := net.SplitHostPort(r.RemoteAddr)
+		if err != nil {
+			host = r.RemoteAddr
+		}
+
+		allowed, retryAfter := m.limiter.Allow(host)
+		if !allowed {
+			tooManyRequests(w, retryAfter)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	}
+}
+
+func tooManyRequests(w http.ResponseWriter, retryAfter time.Duration) {
+	w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+	http.Error(w, "Too many requests", http.StatusTooManyRequests)
+}
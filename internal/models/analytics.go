@@ -54,3 +54,28 @@ func ToTimePeriodDateFormatter(period TimePeriod) TimePeriodDateFormatter {
 	}
 	return DailyDateFormatter
 }
+
+// BreakdownGroupBy is what the drinks of a breakdown are grouped by
+type BreakdownGroupBy string
+
+const (
+	BreakdownByType    BreakdownGroupBy = "type"     // beer, wine...
+	BreakdownByName    BreakdownGroupBy = "name"     // Name of the drink, e.g. the brand
+	BreakdownByABVBand BreakdownGroupBy = "abv_band" // Alcohol by volume, e.g. 5-8%
+	BreakdownBySize    BreakdownGroupBy = "size"     // Volume in ml
+	BreakdownUnknown   BreakdownGroupBy = "unknown"
+)
+
+func ToBreakdownGroupBy(groupBy string) BreakdownGroupBy {
+	switch groupBy {
+	case "type":
+		return BreakdownByType
+	case "name":
+		return BreakdownByName
+	case "abv_band":
+		return BreakdownByABVBand
+	case "size":
+		return BreakdownBySize
+	}
+	return BreakdownUnknown
+}
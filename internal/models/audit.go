@@ -0,0 +1,33 @@
+package models
+
+import "time"
+
+type AuditEventType string
+
+const (
+	AuditLoginFailed       AuditEventType = "login_failed"
+	AuditAccountLocked     AuditEventType = "account_locked"
+	AuditMFAFailed         AuditEventType = "mfa_failed"
+	AuditMFAEnabled        AuditEventType = "mfa_enabled"
+	AuditMFADisabled       AuditEventType = "mfa_disabled"
+	AuditIdentityLinked    AuditEventType = "identity_linked"
+	AuditUserDisabled      AuditEventType = "user_disabled"
+	AuditUserEnabled       AuditEventType = "user_enabled"
+	AuditRoleChanged       AuditEventType = "role_changed"
+	AuditDeletionRequested AuditEventType = "deletion_requested"
+	AuditDeletionCancelled AuditEventType = "deletion_cancelled"
+	AuditPasswordChanged   AuditEventType = "password_changed"
+	AuditEmailChanged      AuditEventType = "email_changed"
+)
+
+// AuditEvent is a security relevant event, UserID is nil when no account matched
+type AuditEvent struct {
+	ID        int64             `json:"id"`
+	UserID    *int64            `json:"user_id,omitempty"`
+	Type      AuditEventType    `json:"type"`
+	Email     string            `json:"email,omitempty"`
+	IPAddress string            `json:"ip_address"`
+	UserAgent string            `json:"user_agent"`
+	Details   map[string]string `json:"details,omitempty"`
+	CreatedAt time.Time         `json:"created_at"`
+}
@@ -1,9 +1,26 @@
 package models
 
-import "github.com/golang-jwt/jwt/v5"
+import (
+	"slices"
 
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Claims of an access token. The JWT ID (jti) identifies the token and
+// the session ID is the refresh token family it was issued for.
+// Requests made with a personal access token get claims without a session,
+// limited to the scopes of the token.
 type Claims struct {
-	UserID int64  `json:"user_id"`
-	Email  string `json:"email"`
+	UserID                int64    `json:"user_id"`
+	Email                 string   `json:"email"`
+	SessionID             string   `json:"sid"`
+	Role                  Role     `json:"role"`
+	PersonalAccessTokenID int64    `json:"-"`
+	Scopes                []string `json:"-"`
 	jwt.RegisteredClaims
 }
+
+// HasScope tells whether the request may use a scope. Session tokens have every scope.
+func (c *Claims) HasScope(scope string) bool {
+	return c.PersonalAccessTokenID == 0 || slices.Contains(c.Scopes, scope)
+}
@@ -39,7 +39,7 @@ const (
 type BACCalculationParams struct {
 	StartTime    time.Time `json:"start_time"`
 	EndTime      time.Time `json:"end_time"`
-	WeightKg     float64   `json:"weight_kg"`
+	WeightKg     float64   `json:"weight_kg"` // Used when the user has no weight history
 	Gender       Gender    `json:"gender" validate:"oneof=male female unknown"`
 	TimeStepMins int       `json:"time_step_mins,omitempty"` // Add this field
 }
@@ -18,6 +18,17 @@ type DrinkLog struct {
 	StandardDrinks float64    `json:"standard_drinks"`
 }
 
+// NewDrinkLog is a drink to log, its details resolved from the request and the template
+type NewDrinkLog struct {
+	Name       string
+	Type       string
+	SizeValue  int
+	SizeUnit   string
+	ABV        float64
+	TemplateID *int // Set only when the details are the template ones
+	LoggedAt   *time.Time
+}
+
 func (d *DrinkLog) GetVolumeInMl() float64 {
 	switch d.SizeUnit {
 	case "cl":
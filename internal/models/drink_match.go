@@ -0,0 +1,34 @@
+package models
+
+// DrinkMatchSource tells where a match candidate comes from
+type DrinkMatchSource string
+
+const (
+	DrinkMatchSourceCatalogue DrinkMatchSource = "catalogue" // drink_templates
+	DrinkMatchSourceHistory   DrinkMatchSource = "history"   // drink_log_details already logged by the user
+)
+
+// DrinkMatch is a candidate template for a parsed drink
+type DrinkMatch struct {
+	TemplateID int              `json:"template_id,omitempty"` // Set when the candidate is (or derives from) a drink template
+	DetailsID  int64            `json:"details_id,omitempty"`  // Set when the candidate comes from the user's history
+	Name       string           `json:"name"`
+	Type       string           `json:"type"`
+	SizeValue  int              `json:"size_value"`
+	SizeUnit   string           `json:"size_unit"`
+	ABV        float64          `json:"abv"`
+	Score      float64          `json:"score"` // Between 0 and 1
+	Source     DrinkMatchSource `json:"source"`
+}
+
+// DrinkHistoryEntry is a distinct drink a user has already logged
+type DrinkHistoryEntry struct {
+	DetailsID  int64   `json:"details_id"`
+	TemplateID *int    `json:"template_id,omitempty"`
+	Name       string  `json:"name"`
+	Type       string  `json:"type"`
+	SizeValue  int     `json:"size_value"`
+	SizeUnit   string  `json:"size_unit"`
+	ABV        float64 `json:"abv"`
+	TimesUsed  int     `json:"times_used"`
+}
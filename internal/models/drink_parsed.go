@@ -1,13 +1,37 @@
 package models
 
+// Fields of a parsed drink that carry a confidence score
+const (
+	ParsedFieldName = "name"
+	ParsedFieldType = "type"
+	ParsedFieldSize = "size"
+	ParsedFieldABV  = "abv"
+)
+
 type DrinkParsed struct {
-	Name          string  `json:"name"`
-	Type          string  `json:"type"`
-	SizeValue     float64 `json:"size_value"`
-	SizeUnit      string  `json:"size_unit"`
-	ABV           float64 `json:"abv"`
-	Success       bool    `json:"success"`
-	Confidence    float64 `json:"confidence"`
-	ErrorMessage  string  `json:"error_message"`
-	OriginalInput string  `json:"original_input"`
+	Name                string             `json:"name"`
+	Type                string             `json:"type"`
+	SizeValue           float64            `json:"size_value"`
+	SizeUnit            string             `json:"size_unit"`
+	ABV                 float64            `json:"abv"`
+	ABVInferred         bool               `json:"abv_inferred"` // ABV was filled in from a matched template
+	Success             bool               `json:"success"`
+	Confidence          float64            `json:"confidence"`
+	FieldConfidence     map[string]float64 `json:"field_confidence,omitempty"`      // Confidence per field, between 0 and 1
+	LowConfidenceFields []string           `json:"low_confidence_fields,omitempty"` // Fields the user should confirm
+	ErrorMessage        string             `json:"error_message"`
+	OriginalInput       string             `json:"original_input"`
+	Language            string             `json:"language,omitempty"` // Detected language of the input, e.g. "fr"
+	Matches             []DrinkMatch       `json:"matches,omitempty"`
+	Evidence            ParseEvidence      `json:"-"`
+}
+
+// ParseEvidence records how each field was obtained by a parser
+type ParseEvidence struct {
+	NameExplicit   bool   // The name is written in the input
+	ABVExplicit    bool   // The alcohol content is written in the input
+	SizeExplicit   bool   // The volume is written in the input
+	SizeFromServe  bool   // The volume comes from a serving name (pint, shot...)
+	UnitNormalized bool   // The unit was converted to a supported one
+	TypeKeyword    string // The drink type deduced from a keyword of the input
 }
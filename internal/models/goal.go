@@ -0,0 +1,46 @@
+package models
+
+import "time"
+
+// GoalType is what a goal limits
+type GoalType string
+
+const (
+	GoalTypeMaxStandardDrinks GoalType = "max_standard_drinks" // At most Target standard drinks each period
+	GoalTypeMinDryDays        GoalType = "min_dry_days"        // At least Target alcohol-free days each period
+	GoalTypeDryPeriod         GoalType = "dry_period"          // No drink from the start to the end date, e.g. dry January
+)
+
+// GoalStatus is the outcome of a period of a goal
+type GoalStatus string
+
+const (
+	GoalStatusInProgress GoalStatus = "in_progress"
+	GoalStatusMet        GoalStatus = "met"
+	GoalStatusFailed     GoalStatus = "failed"
+)
+
+// Goal is a target a user set for themselves. Its dates are days of the calendar of the user,
+// such as 2025-01-31, and both are included.
+type Goal struct {
+	ID        int64      `json:"id"`
+	UserID    int64      `json:"user_id"`
+	Name      string     `json:"name"`
+	Type      GoalType   `json:"type"`
+	Period    TimePeriod `json:"period,omitempty"` // daily, weekly or monthly, empty for a dry period
+	Target    float64    `json:"target"`
+	StartDate string     `json:"start_date"`
+	EndDate   *string    `json:"end_date"` // Null for a goal without end
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+}
+
+// GoalOutcome is the result of a goal over one of its periods
+type GoalOutcome struct {
+	StartDate string     `json:"start_date"`
+	EndDate   string     `json:"end_date"`
+	Value     float64    `json:"value"`    // Standard drinks, or alcohol-free days for a min_dry_days goal
+	Target    float64    `json:"target"`   // Target of the period, the days of a shorter period cap the dry days
+	Progress  float64    `json:"progress"` // Percentage of the allowance used, of the dry days or of the dry period done
+	Status    GoalStatus `json:"status"`
+}
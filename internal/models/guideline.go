@@ -0,0 +1,42 @@
+package models
+
+import "time"
+
+// Guideline is a set of drinking limits, in standard drinks of 10 grams of alcohol.
+// A nil limit does not apply.
+type Guideline struct {
+	Key            string   `json:"key"`
+	Name           string   `json:"name"`
+	Description    string   `json:"description"`
+	DailyLimit     *float64 `json:"daily_limit"`
+	WeeklyLimit    *float64 `json:"weekly_limit"`
+	DryDaysPerWeek int      `json:"dry_days_per_week"` // Minimum number of days without a drink
+}
+
+// GuidelineCustom is the key of the guideline made of the limits of the user
+const GuidelineCustom = "custom"
+
+// DrinkLimits are the limits a user set for themselves, in standard drinks
+type DrinkLimits struct {
+	DailyLimit     *float64  `json:"daily_limit"`
+	WeeklyLimit    *float64  `json:"weekly_limit"`
+	DryDaysPerWeek int       `json:"dry_days_per_week"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+// IsSet tells whether the user set any limit
+func (l *DrinkLimits) IsSet() bool {
+	return l != nil && (l.DailyLimit != nil || l.WeeklyLimit != nil || l.DryDaysPerWeek > 0)
+}
+
+// Guideline returns the custom guideline of the limits
+func (l *DrinkLimits) Guideline() Guideline {
+	return Guideline{
+		Key:            GuidelineCustom,
+		Name:           "Personal limits",
+		Description:    "The limits you set for yourself",
+		DailyLimit:     l.DailyLimit,
+		WeeklyLimit:    l.WeeklyLimit,
+		DryDaysPerWeek: l.DryDaysPerWeek,
+	}
+}
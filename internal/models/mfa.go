@@ -0,0 +1,25 @@
+package models
+
+import "time"
+
+// TOTPFactor is the authenticator app of a user, pending until EnabledAt is set
+type TOTPFactor struct {
+	UserID       int64      `json:"user_id"`
+	Secret       string     `json:"-"`
+	LastUsedStep int64      `json:"-"`
+	CreatedAt    time.Time  `json:"created_at"`
+	EnabledAt    *time.Time `json:"enabled_at,omitempty"`
+}
+
+// TOTPEnrolment is what an authenticator app needs to be set up
+type TOTPEnrolment struct {
+	Secret          string
+	ProvisioningURI string // otpauth:// URI, rendered as a QR code
+}
+
+// LoginResult is the outcome of the password step of a login: either the tokens,
+// or a challenge to answer with a second factor when MFA is enabled
+type LoginResult struct {
+	Tokens   *TokenPair
+	MFAToken string
+}
@@ -0,0 +1,33 @@
+package models
+
+import "time"
+
+// Scopes a personal access token can be given
+const (
+	ScopeDrinksRead    = "drinks:read"
+	ScopeDrinksWrite   = "drinks:write"
+	ScopeBACRead       = "bac:read"
+	ScopeAnalyticsRead = "analytics:read"
+	ScopeProfileRead   = "profile:read"
+	ScopeProfileWrite  = "profile:write"
+	ScopeGoalsRead     = "goals:read"
+	ScopeGoalsWrite    = "goals:write"
+)
+
+var PersonalAccessTokenScopes = []string{
+	ScopeDrinksRead, ScopeDrinksWrite, ScopeBACRead, ScopeAnalyticsRead, ScopeProfileRead, ScopeProfileWrite,
+	ScopeGoalsRead, ScopeGoalsWrite,
+}
+
+// PersonalAccessToken is a long-lived token for scripts, the token itself is only stored hashed
+type PersonalAccessToken struct {
+	ID         int64      `json:"id"`
+	UserID     int64      `json:"user_id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Scopes     []string   `json:"scopes"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+}
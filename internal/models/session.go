@@ -0,0 +1,35 @@
+package models
+
+import "time"
+
+// Session is a refresh token. The tokens of a device share a family,
+// each refresh rotates the token and adds a session to the family.
+type Session struct {
+	ID        int64      `json:"id"`
+	UserID    int64      `json:"user_id"`
+	FamilyID  string     `json:"family_id"`
+	TokenHash string     `json:"-"`
+	UserAgent string     `json:"user_agent"`
+	IPAddress string     `json:"ip_address"`
+	CreatedAt time.Time  `json:"created_at"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	RotatedAt *time.Time `json:"rotated_at,omitempty"` // The token was exchanged for a new one
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+}
+
+// TokenPair is returned on login and refresh
+type TokenPair struct {
+	AccessToken  string
+	RefreshToken string
+	ExpiresIn    int64 // Lifetime of the access token, in seconds
+}
+
+// DeviceSession is a logged in device, the active token of a session family
+type DeviceSession struct {
+	FamilyID     string    `json:"id"`
+	UserAgent    string    `json:"user_agent"`
+	IPAddress    string    `json:"ip_address"`
+	SignedInAt   time.Time `json:"signed_in_at"`
+	LastActiveAt time.Time `json:"last_active_at"` // Last login or refresh
+	ExpiresAt    time.Time `json:"expires_at"`
+}
@@ -0,0 +1,12 @@
+package models
+
+import "time"
+
+// SigningKey is a key pair signing access tokens, identified by the kid header of the tokens
+type SigningKey struct {
+	ID         string     `json:"id"`
+	Algorithm  string     `json:"algorithm"`
+	PrivateKey string     `json:"-"` // PKCS #8 PEM
+	CreatedAt  time.Time  `json:"created_at"`
+	RetiredAt  *time.Time `json:"retired_at,omitempty"`
+}
@@ -3,12 +3,36 @@ package models
 import "time"
 
 type User struct {
-	ID        int64     `json:"id"`
-	Email     string    `json:"email"`
-	Password  string    `json:"-"` // "-" means this won't be included in JSON
-	CreatedAt time.Time `json:"created_at"`
+	ID              int64      `json:"id"`
+	Email           string     `json:"email"`
+	Password        string     `json:"-"` // "-" means this won't be included in JSON
+	Role            Role       `json:"role"`
+	EmailVerifiedAt *time.Time `json:"email_verified_at,omitempty"`
+	DisabledAt      *time.Time `json:"disabled_at,omitempty"` // Disabled users cannot log in
+	// DeletionRequestedAt is set when the user deleted their account, until it is purged or they log in again
+	DeletionRequestedAt *time.Time `json:"deletion_requested_at,omitempty"`
+	CreatedAt           time.Time  `json:"created_at"`
 }
 
+type Role string
+
+const (
+	RoleUser  Role = "user"
+	RoleAdmin Role = "admin" // Operators: template catalogue, users and system stats
+)
+
+func (r Role) Valid() bool {
+	return r == RoleUser || r == RoleAdmin
+}
+
+// UserTokenPurpose is what a token sent by email allows
+type UserTokenPurpose string
+
+const (
+	EmailVerificationToken UserTokenPurpose = "email_verification"
+	PasswordResetToken     UserTokenPurpose = "password_reset"
+)
+
 type Gender string
 
 const (
@@ -25,3 +49,14 @@ func ToGender(gender string) Gender {
 	}
 	return Unknown
 }
+
+// UserIdentity is an account of an external identity provider linked to a user
+type UserIdentity struct {
+	ID          int64      `json:"id"`
+	UserID      int64      `json:"user_id"`
+	Issuer      string     `json:"issuer"`
+	Subject     string     `json:"subject"`
+	Email       string     `json:"email"`
+	CreatedAt   time.Time  `json:"created_at"`
+	LastLoginAt *time.Time `json:"last_login_at,omitempty"`
+}
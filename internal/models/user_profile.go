@@ -3,9 +3,32 @@ package models
 import "time"
 
 type UserProfile struct {
-	UserID    int64     `json:"user_id"`
-	WeightKg  float64   `json:"weight_kg"`
-	Gender    Gender    `json:"gender"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	UserID          int64     `json:"user_id"`
+	WeightKg        float64   `json:"weight_kg"`
+	Gender          Gender    `json:"gender"`
+	Timezone        string    `json:"timezone"`          // IANA name, e.g. Europe/Paris
+	DayRolloverHour int       `json:"day_rollover_hour"` // Local hour at which a drinking day ends
+	CreatedAt       time.Time `json:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at"`
+}
+
+// DefaultDayRolloverHour ends the drinking days at 6am, after most nights out
+const DefaultDayRolloverHour = 6
+
+// Location returns the time zone of the user, UTC when it is not set or unknown
+func (p *UserProfile) Location() *time.Location {
+	if p == nil || p.Timezone == "" {
+		return time.UTC
+	}
+	location, err := time.LoadLocation(p.Timezone)
+	if err != nil {
+		return time.UTC
+	}
+	return location
+}
+
+// WeightEntry is a weight of a user, applying from RecordedAt until the next entry
+type WeightEntry struct {
+	WeightKg   float64   `json:"weight_kg"`
+	RecordedAt time.Time `json:"recorded_at"`
 }
@@ -0,0 +1,21 @@
+package models
+
+import "strings"
+
+// VolumeInMl converts a volume to millilitres.
+// The boolean is false when the unit is not recognised.
+func VolumeInMl(value float64, unit string) (float64, bool) {
+	switch strings.ToLower(strings.TrimSpace(unit)) {
+	case "ml":
+		return value, true
+	case "cl":
+		return value * 10, true
+	case "dl":
+		return value * 100, true
+	case "l":
+		return value * 1000, true
+	case "oz", "fl oz":
+		return value * 29.5735, true
+	}
+	return value, false
+}
@@ -0,0 +1,189 @@
+package parser
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"log/slog"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go-sober/internal/models"
+)
+
+// Bump when the LLM parser turns a response into a result differently, so that the results
+// cached by the previous code are not served anymore
+const llmParserVersion = "2"
+
+// ParseCache stores parse results by normalised input text
+type ParseCache interface {
+	Get(key string) (*models.DrinkParsed, bool, error)
+	Set(key string, parsed *models.DrinkParsed) error
+}
+
+// NormalizeParseText builds the cache key of a drink description.
+// Case, repeated spaces and trailing punctuation do not change the parse result.
+func NormalizeParseText(text string) string {
+	text = strings.Join(strings.Fields(strings.ToLower(text)), " ")
+	return strings.TrimRight(text, ".!?; ")
+}
+
+// LLMCacheVersion identifies the prompt, the model and the parser code behind LLM results.
+// It prefixes the cache keys, so that an upgrade of any of them does not serve the results
+// of the previous ones, which expire with the cache TTL.
+func LLMCacheVersion(model string) string {
+	hash := sha256.New()
+	for _, part := range []string{llmParserVersion, model, BEVERAGE_PARSER_PROMPT} {
+		hash.Write([]byte(part))
+		hash.Write([]byte{0})
+	}
+	return hex.EncodeToString(hash.Sum(nil))[:16]
+}
+
+// CachedParser answers from the caches before calling the wrapped parser.
+// Only successful results are cached, failures may come from a transient LLM error.
+type CachedParser struct {
+	parser  DrinkParser
+	version string       // Prefix of the keys, changes with whatever produces the results
+	caches  []ParseCache // Checked in order, fastest first
+	hits    atomic.Int64
+	misses  atomic.Int64
+}
+
+func NewCachedParser(parser DrinkParser, version string, caches ...ParseCache) *CachedParser {
+	return &CachedParser{parser: parser, version: version, caches: caches}
+}
+
+func (p *CachedParser) Parse(text string) (*models.DrinkParsed, error) {
+	key := p.version + ":" + NormalizeParseText(text)
+
+	for i, cache := range p.caches {
+		parsed, ok, err := cache.Get(key)
+		if err != nil {
+			slog.Warn("Could not read parse cache", "error", err)
+			continue
+		}
+		if !ok {
+			continue
+		}
+
+		// Warm up the faster caches
+		for _, faster := range p.caches[:i] {
+			if err := faster.Set(key, parsed); err != nil {
+				slog.Warn("Could not write parse cache", "error", err)
+			}
+		}
+
+		p.logLookup(true)
+		parsed.OriginalInput = text
+		return parsed, nil
+	}
+
+	p.logLookup(false)
+	parsed, err := p.parser.Parse(text)
+	if err != nil || !parsed.Success {
+		return parsed, err
+	}
+
+	for _, cache := range p.caches {
+		if err := cache.Set(key, parsed); err != nil {
+			slog.Warn("Could not write parse cache", "error", err)
+		}
+	}
+	return parsed, nil
+}
+
+// HitRatio is the share of lookups answered by a cache
+func (p *CachedParser) HitRatio() float64 {
+	hits, misses := p.hits.Load(), p.misses.Load()
+	if hits+misses == 0 {
+		return 0
+	}
+	return float64(hits) / float64(hits+misses)
+}
+
+func (p *CachedParser) logLookup(hit bool) {
+	if hit {
+		p.hits.Add(1)
+	} else {
+		p.misses.Add(1)
+	}
+	slog.Info("Drink parse cache lookup", "hit", hit, "hits", p.hits.Load(), "misses", p.misses.Load(), "hit_ratio", roundRatio(p.HitRatio()))
+}
+
+// copyParsed returns a copy that the caller may modify without altering the cache
+func copyParsed(parsed *models.DrinkParsed) *models.DrinkParsed {
+	copied := *parsed
+	copied.Matches = nil
+	copied.FieldConfidence = nil
+	copied.LowConfidenceFields = nil
+	return &copied
+}
+
+type memoryCacheEntry struct {
+	key       string
+	parsed    *models.DrinkParsed
+	expiresAt time.Time
+}
+
+// MemoryParseCache is an in-process LRU cache with a TTL
+type MemoryParseCache struct {
+	mu         sync.Mutex
+	ttl        time.Duration
+	maxEntries int
+	entries    map[string]*list.Element
+	order      *list.List // Most recently used first
+	now        func() time.Time
+}
+
+func NewMemoryParseCache(ttl time.Duration, maxEntries int) *MemoryParseCache {
+	return &MemoryParseCache{
+		ttl:        ttl,
+		maxEntries: maxEntries,
+		entries:    make(map[string]*list.Element),
+		order:      list.New(),
+		now:        time.Now,
+	}
+}
+
+func (c *MemoryParseCache) Get(key string) (*models.DrinkParsed, bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	element, ok := c.entries[key]
+	if !ok {
+		return nil, false, nil
+	}
+
+	entry := element.Value.(*memoryCacheEntry)
+	if !c.now().Before(entry.expiresAt) {
+		c.order.Remove(element)
+		delete(c.entries, key)
+		return nil, false, nil
+	}
+
+	c.order.MoveToFront(element)
+	return copyParsed(entry.parsed), true, nil
+}
+
+func (c *MemoryParseCache) Set(key string, parsed *models.DrinkParsed) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry := &memoryCacheEntry{key: key, parsed: copyParsed(parsed), expiresAt: c.now().Add(c.ttl)}
+	if element, ok := c.entries[key]; ok {
+		element.Value = entry
+		c.order.MoveToFront(element)
+		return nil
+	}
+
+	c.entries[key] = c.order.PushFront(entry)
+	for c.maxEntries > 0 && c.order.Len() > c.maxEntries {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*memoryCacheEntry).key)
+	}
+	return nil
+}
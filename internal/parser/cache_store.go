@@ -0,0 +1,79 @@
+package parser
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"go-sober/internal/models"
+)
+
+// cachedParse is the stored form of a parse result.
+// The evidence is not part of the JSON of a parsed drink, so it is kept aside.
+type cachedParse struct {
+	Parsed   models.DrinkParsed   `json:"parsed"`
+	Evidence models.ParseEvidence `json:"evidence"`
+}
+
+// SQLiteParseCache keeps parse results in the parse_cache table, so that they survive restarts
+type SQLiteParseCache struct {
+	db  *sql.DB
+	ttl time.Duration
+	now func() time.Time
+}
+
+func NewSQLiteParseCache(db *sql.DB, ttl time.Duration) *SQLiteParseCache {
+	return &SQLiteParseCache{db: db, ttl: ttl, now: time.Now}
+}
+
+func (c *SQLiteParseCache) Get(key string) (*models.DrinkParsed, bool, error) {
+	var result string
+	err := c.db.QueryRow(
+		"SELECT result FROM parse_cache WHERE cache_key = ? AND expires_at > ?",
+		key, c.now().UTC(),
+	).Scan(&result)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("error querying parse cache: %w", err)
+	}
+
+	var cached cachedParse
+	if err := json.Unmarshal([]byte(result), &cached); err != nil {
+		return nil, false, fmt.Errorf("error decoding cached parse: %w", err)
+	}
+
+	parsed := cached.Parsed
+	parsed.Evidence = cached.Evidence
+	return &parsed, true, nil
+}
+
+func (c *SQLiteParseCache) Set(key string, parsed *models.DrinkParsed) error {
+	stored := copyParsed(parsed)
+	result, err := json.Marshal(cachedParse{Parsed: *stored, Evidence: stored.Evidence})
+	if err != nil {
+		return fmt.Errorf("error encoding parse: %w", err)
+	}
+
+	now := c.now().UTC()
+	_, err = c.db.Exec(
+		"INSERT OR REPLACE INTO parse_cache (cache_key, result, created_at, expires_at) VALUES (?, ?, ?, ?)",
+		key, string(result), now, now.Add(c.ttl),
+	)
+	if err != nil {
+		return fmt.Errorf("error writing parse cache: %w", err)
+	}
+	return nil
+}
+
+// PurgeExpired deletes the expired results and returns how many were removed
+func (c *SQLiteParseCache) PurgeExpired() (int64, error) {
+	result, err := c.db.Exec("DELETE FROM parse_cache WHERE expires_at <= ?", c.now().UTC())
+	if err != nil {
+		return 0, fmt.Errorf("error purging parse cache: %w", err)
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,138 @@
+package parser
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"go-sober/internal/models"
+
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// countingParser counts its calls and parses every text the same way
+type countingParser struct {
+	calls   int
+	success bool
+}
+
+func (p *countingParser) Parse(text string) (*models.DrinkParsed, error) {
+	p.calls++
+	return &models.DrinkParsed{
+		Name:          "Guinness",
+		Type:          "beer",
+		SizeValue:     500,
+		SizeUnit:      "ml",
+		ABV:           0.042,
+		Success:       p.success,
+		OriginalInput: text,
+		Evidence:      models.ParseEvidence{SizeFromServe: true},
+	}, nil
+}
+
+func TestNormalizeParseText(t *testing.T) {
+	assert.Equal(t, "a pint of guinness", NormalizeParseText("  A pint  of Guinness.  "))
+	assert.Equal(t, "ipa 4,5%", NormalizeParseText("IPA 4,5%!"))
+}
+
+func TestCachedParser(t *testing.T) {
+	inner := &countingParser{success: true}
+	cached := NewCachedParser(inner, "v1", NewMemoryParseCache(time.Hour, 10))
+
+	first, err := cached.Parse("A pint of Guinness")
+	require.NoError(t, err)
+	first.Matches = []models.DrinkMatch{{Name: "Guinness"}} // Must not leak into the cache
+
+	second, err := cached.Parse("a pint of guinness.")
+	require.NoError(t, err)
+
+	assert.Equal(t, 1, inner.calls)
+	assert.Equal(t, "a pint of guinness.", second.OriginalInput)
+	assert.Empty(t, second.Matches)
+	assert.True(t, second.Evidence.SizeFromServe)
+	assert.Equal(t, 0.5, cached.HitRatio())
+}
+
+func TestCachedParserVersion(t *testing.T) {
+	inner := &countingParser{success: true}
+	cache := NewMemoryParseCache(time.Hour, 10)
+
+	_, err := NewCachedParser(inner, "v1", cache).Parse("pint of guinness")
+	require.NoError(t, err)
+	_, err = NewCachedParser(inner, "v2", cache).Parse("pint of guinness")
+	require.NoError(t, err)
+	assert.Equal(t, 2, inner.calls, "results of another prompt or model are not served")
+
+	assert.Equal(t, LLMCacheVersion("gemma2-9b-it"), LLMCacheVersion("gemma2-9b-it"))
+	assert.NotEqual(t, LLMCacheVersion("gemma2-9b-it"), LLMCacheVersion("llama-3.1-8b-instant"))
+}
+
+func TestCachedParserSkipsFailures(t *testing.T) {
+	inner := &countingParser{success: false}
+	cached := NewCachedParser(inner, "v1", NewMemoryParseCache(time.Hour, 10))
+
+	_, _ = cached.Parse("something")
+	_, _ = cached.Parse("something")
+
+	assert.Equal(t, 2, inner.calls)
+}
+
+func TestMemoryParseCache(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	cache := NewMemoryParseCache(time.Hour, 2)
+	cache.now = func() time.Time { return now }
+
+	for _, key := range []string{"a", "b", "c"} {
+		require.NoError(t, cache.Set(key, &models.DrinkParsed{Name: key}))
+	}
+
+	_, ok, _ := cache.Get("a")
+	assert.False(t, ok, "oldest entry should be evicted")
+
+	parsed, ok, _ := cache.Get("c")
+	require.True(t, ok)
+	assert.Equal(t, "c", parsed.Name)
+
+	now = now.Add(time.Hour)
+	_, ok, _ = cache.Get("c")
+	assert.False(t, ok, "entry should expire after the TTL")
+}
+
+func TestSQLiteParseCache(t *testing.T) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	require.NoError(t, err)
+	defer db.Close()
+
+	_, err = db.Exec(`
+		CREATE TABLE parse_cache (
+			cache_key TEXT PRIMARY KEY,
+			result TEXT NOT NULL,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			expires_at DATETIME NOT NULL
+		)`)
+	require.NoError(t, err)
+
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	cache := NewSQLiteParseCache(db, time.Hour)
+	cache.now = func() time.Time { return now }
+
+	parsed := &models.DrinkParsed{Name: "Guinness", ABV: 0.042, Success: true, Evidence: models.ParseEvidence{ABVExplicit: true}}
+	require.NoError(t, cache.Set("guinness", parsed))
+
+	cached, ok, err := cache.Get("guinness")
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.Equal(t, "Guinness", cached.Name)
+	assert.True(t, cached.Evidence.ABVExplicit)
+
+	now = now.Add(2 * time.Hour)
+	_, ok, err = cache.Get("guinness")
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	purged, err := cache.PurgeExpired()
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), purged)
+}
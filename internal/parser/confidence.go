@@ -0,0 +1,184 @@
+package parser
+
+import (
+	"math"
+
+	"go-sober/internal/models"
+)
+
+// Confidence given to a field depending on how it was obtained
+const (
+	explicitConfidence = 0.95 // Written in the input
+	servingConfidence  = 0.8  // Deduced from a serving name such as "pint"
+	keywordConfidence  = 0.9  // Deduced from a keyword such as "stout"
+	guessedConfidence  = 0.5  // Filled in by the LLM without evidence
+	inferredConfidence = 0.6  // Borrowed from a matched template, scaled by the match score
+
+	// Converting L or oz to ml loses a bit of precision (UK vs US ounces...)
+	normalizedUnitFactor = 0.9
+
+	// Another parser agreeing adds this bonus, disagreeing applies the penalty factor
+	agreementBonus    = 0.1
+	disagreementScale = 0.7
+
+	// Fields below this confidence should be confirmed by the user
+	LowConfidenceThreshold = 0.7
+)
+
+// Weight of each field in the overall confidence
+var fieldWeights = map[string]float64{
+	models.ParsedFieldName: 0.1,
+	models.ParsedFieldType: 0.3,
+	models.ParsedFieldSize: 0.3,
+	models.ParsedFieldABV:  0.3,
+}
+
+// Fields in the order they are reported
+var scoredFields = []string{
+	models.ParsedFieldName,
+	models.ParsedFieldType,
+	models.ParsedFieldSize,
+	models.ParsedFieldABV,
+}
+
+// ScoreConfidence fills the per-field and overall confidence of a parsed drink.
+// The reference is the result of another parser on the same input and may be nil.
+func ScoreConfidence(parsed, reference *models.DrinkParsed) {
+	scores := make(map[string]float64, len(scoredFields))
+	for _, field := range scoredFields {
+		scores[field] = 0
+	}
+
+	parsed.FieldConfidence = scores
+	parsed.LowConfidenceFields = nil
+	parsed.Confidence = 0
+
+	if !parsed.Success {
+		parsed.LowConfidenceFields = append(parsed.LowConfidenceFields, scoredFields...)
+		return
+	}
+
+	var bestMatch *models.DrinkMatch
+	if len(parsed.Matches) > 0 {
+		bestMatch = &parsed.Matches[0]
+	}
+
+	scores[models.ParsedFieldName] = nameConfidence(parsed, bestMatch)
+	scores[models.ParsedFieldType] = typeConfidence(parsed, bestMatch)
+	scores[models.ParsedFieldSize] = sizeConfidence(parsed)
+	scores[models.ParsedFieldABV] = abvConfidence(parsed, bestMatch)
+
+	if reference != nil && reference.Success {
+		applyAgreement(scores, parsed, reference)
+	}
+
+	var overall float64
+	for _, field := range scoredFields {
+		scores[field] = roundConfidence(scores[field])
+		overall += fieldWeights[field] * scores[field]
+
+		if scores[field] < LowConfidenceThreshold {
+			parsed.LowConfidenceFields = append(parsed.LowConfidenceFields, field)
+		}
+	}
+	parsed.Confidence = roundConfidence(overall)
+}
+
+func nameConfidence(parsed *models.DrinkParsed, bestMatch *models.DrinkMatch) float64 {
+	if parsed.Name == "" {
+		return 0
+	}
+
+	// A name the parser made up stays low until a template or the history confirms it
+	confidence := inferredConfidence
+	if bestMatch != nil {
+		confidence = math.Max(guessedConfidence, 0.5+0.5*bestMatch.Score)
+	}
+	if parsed.Evidence.NameExplicit {
+		confidence = math.Max(confidence, explicitConfidence)
+	}
+	return confidence
+}
+
+func typeConfidence(parsed *models.DrinkParsed, bestMatch *models.DrinkMatch) float64 {
+	if parsed.Type == "" {
+		return 0
+	}
+
+	var confidence float64
+	switch parsed.Evidence.TypeKeyword {
+	case parsed.Type:
+		confidence = keywordConfidence
+	case "":
+		confidence = inferredConfidence
+	default:
+		// The input contains a keyword of another type
+		confidence = guessedConfidence * disagreementScale
+	}
+
+	if bestMatch != nil && bestMatch.Type == parsed.Type {
+		confidence += 0.05
+	}
+	return confidence
+}
+
+func sizeConfidence(parsed *models.DrinkParsed) float64 {
+	if parsed.SizeValue <= 0 {
+		return 0
+	}
+
+	confidence := guessedConfidence
+	switch {
+	case parsed.Evidence.SizeExplicit:
+		confidence = explicitConfidence
+	case parsed.Evidence.SizeFromServe:
+		confidence = servingConfidence
+	}
+
+	if parsed.Evidence.UnitNormalized {
+		confidence *= normalizedUnitFactor
+	}
+	return confidence
+}
+
+func abvConfidence(parsed *models.DrinkParsed, bestMatch *models.DrinkMatch) float64 {
+	switch {
+	case parsed.ABV < 0:
+		return 0
+	case parsed.ABVInferred && bestMatch != nil:
+		return inferredConfidence * bestMatch.Score
+	case parsed.Evidence.ABVExplicit:
+		return explicitConfidence
+	}
+	return guessedConfidence
+}
+
+// applyAgreement raises the fields both parsers agree on and lowers the others
+func applyAgreement(scores map[string]float64, parsed, reference *models.DrinkParsed) {
+	adjust := func(field string, agree bool) {
+		if agree {
+			scores[field] = math.Min(1, scores[field]+agreementBonus)
+		} else {
+			scores[field] *= disagreementScale
+		}
+	}
+
+	if parsed.Type != "" && reference.Type != "" {
+		adjust(models.ParsedFieldType, parsed.Type == reference.Type)
+	}
+
+	parsedMl, okParsed := models.VolumeInMl(parsed.SizeValue, parsed.SizeUnit)
+	referenceMl, okReference := models.VolumeInMl(reference.SizeValue, reference.SizeUnit)
+	if okParsed && okReference && parsedMl > 0 && referenceMl > 0 {
+		adjust(models.ParsedFieldSize, math.Abs(parsedMl-referenceMl)/math.Max(parsedMl, referenceMl) <= 0.1)
+	}
+
+	// The reference cannot infer an ABV, only compare explicit values
+	if !parsed.ABVInferred && parsed.ABV >= 0 && reference.ABV >= 0 {
+		adjust(models.ParsedFieldABV, math.Abs(parsed.ABV-reference.ABV) <= 0.005)
+	}
+}
+
+func roundConfidence(confidence float64) float64 {
+	return math.Round(math.Max(0, math.Min(1, confidence))*100) / 100
+}
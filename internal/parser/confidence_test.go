@@ -0,0 +1,80 @@
+package parser
+
+import (
+	"testing"
+
+	"go-sober/internal/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScoreConfidence(t *testing.T) {
+	t.Run("explicit fields with an agreeing parser", func(t *testing.T) {
+		parsed := &models.DrinkParsed{
+			Name: "Stout, 500ml, 4.2%", Type: "beer", SizeValue: 500, SizeUnit: "ml", ABV: 0.042, Success: true,
+			Evidence: models.ParseEvidence{ABVExplicit: true, SizeExplicit: true, TypeKeyword: "beer"},
+		}
+		reference := &models.DrinkParsed{Type: "beer", SizeValue: 50, SizeUnit: "cl", ABV: 0.042, Success: true}
+
+		ScoreConfidence(parsed, reference)
+		assert.Equal(t, 1.0, parsed.FieldConfidence[models.ParsedFieldType])
+		assert.Equal(t, 1.0, parsed.FieldConfidence[models.ParsedFieldSize])
+		assert.Equal(t, 1.0, parsed.FieldConfidence[models.ParsedFieldABV])
+		assert.Equal(t, []string{models.ParsedFieldName}, parsed.LowConfidenceFields)
+		assert.Greater(t, parsed.Confidence, 0.9)
+	})
+
+	t.Run("guessed and inferred fields need confirmation", func(t *testing.T) {
+		parsed := &models.DrinkParsed{
+			Name: "Guinness", Type: "beer", SizeValue: 568, SizeUnit: "ml", ABV: 0.042, ABVInferred: true, Success: true,
+			Matches: []models.DrinkMatch{{Type: "beer", Score: 0.8}},
+		}
+
+		ScoreConfidence(parsed, nil)
+		assert.Less(t, parsed.FieldConfidence[models.ParsedFieldSize], LowConfidenceThreshold)
+		assert.Less(t, parsed.FieldConfidence[models.ParsedFieldABV], LowConfidenceThreshold)
+		assert.Contains(t, parsed.LowConfidenceFields, models.ParsedFieldSize)
+		assert.Contains(t, parsed.LowConfidenceFields, models.ParsedFieldABV)
+		assert.GreaterOrEqual(t, parsed.FieldConfidence[models.ParsedFieldName], 0.9)
+	})
+
+	t.Run("a name written in the input needs no confirmation", func(t *testing.T) {
+		newParsed := func(name string) *models.DrinkParsed {
+			return &models.DrinkParsed{
+				Name: name, Type: "beer", SizeValue: 568, SizeUnit: "ml", ABV: 0.042, Success: true,
+				Evidence: models.ParseEvidence{NameExplicit: nameInText("pint of guinness 4.2%", name)},
+			}
+		}
+		typed, madeUp := newParsed("Guinness"), newParsed("Irish Stout")
+
+		ScoreConfidence(typed, nil)
+		ScoreConfidence(madeUp, nil)
+		assert.NotContains(t, typed.LowConfidenceFields, models.ParsedFieldName)
+		assert.Contains(t, madeUp.LowConfidenceFields, models.ParsedFieldName)
+	})
+
+	t.Run("disagreement lowers confidence", func(t *testing.T) {
+		newParsed := func() *models.DrinkParsed {
+			return &models.DrinkParsed{
+				Type: "beer", SizeValue: 330, SizeUnit: "ml", ABV: 0.05, Success: true,
+				Evidence: models.ParseEvidence{ABVExplicit: true, SizeExplicit: true},
+			}
+		}
+		agreeing, disagreeing := newParsed(), newParsed()
+
+		ScoreConfidence(agreeing, &models.DrinkParsed{Type: "beer", SizeValue: 330, SizeUnit: "ml", ABV: 0.05, Success: true})
+		ScoreConfidence(disagreeing, &models.DrinkParsed{Type: "cider", SizeValue: 500, SizeUnit: "ml", ABV: 0.045, Success: true})
+
+		for _, field := range []string{models.ParsedFieldType, models.ParsedFieldSize, models.ParsedFieldABV} {
+			assert.Greater(t, agreeing.FieldConfidence[field], disagreeing.FieldConfidence[field], field)
+		}
+	})
+
+	t.Run("failed parse has no confidence", func(t *testing.T) {
+		parsed := &models.DrinkParsed{Success: false}
+
+		ScoreConfidence(parsed, nil)
+		assert.Equal(t, 0.0, parsed.Confidence)
+		assert.Len(t, parsed.LowConfidenceFields, 4)
+	})
+}
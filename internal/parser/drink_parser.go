@@ -22,27 +22,37 @@ type LLMResponse struct {
 	} `json:"beverages"`
 }
 
-// DrinkParser handles the parsing of drink descriptions using LLM
-type DrinkParser struct{}
+// LLMInput formats the input text as JSON for the LLM
+func LLMInput(text string) string {
+	return fmt.Sprintf(`{"text": %q}`, text)
+}
+
+// DrinkParser turns a drink description into a parsed drink.
+// Parsing failures are reported in the result, errors are unexpected ones.
+type DrinkParser interface {
+	Parse(text string) (*models.DrinkParsed, error)
+}
+
+// LLMParser handles the parsing of drink descriptions using LLM
+type LLMParser struct {
+	provider llm.Provider
+}
 
-// NewDrinkParser creates a new DrinkParser instance
-func NewDrinkParser() *DrinkParser {
-	return &DrinkParser{}
+// NewLLMParser creates a new LLMParser instance
+func NewLLMParser(provider llm.Provider) *LLMParser {
+	return &LLMParser{provider: provider}
 }
 
 // Parse takes a drink description text and returns the parsed drink details
-func (p *DrinkParser) Parse(text string) (*models.DrinkParsed, error) {
+func (p *LLMParser) Parse(text string) (*models.DrinkParsed, error) {
 	result := &models.DrinkParsed{
 		Success:       false,
 		OriginalInput: text,
 		Confidence:    0,
 	}
 
-	// Format the input text as JSON for the LLM
-	input := fmt.Sprintf(`{"text": %q}`, text)
-
 	// Call the LLM with the beverage parser prompt
-	llmResult, err := llm.Call(BEVERAGE_PARSER_PROMPT, input)
+	llmResult, err := p.provider.Call(BEVERAGE_PARSER_PROMPT, LLMInput(text))
 	if err != nil {
 		result.ErrorMessage = fmt.Sprintf("error calling LLM: %v", err)
 		return result, nil
@@ -62,6 +72,7 @@ func (p *DrinkParser) Parse(text string) (*models.DrinkParsed, error) {
 
 	// Convert the first beverage to DrinkTemplate
 	beverage := response.Beverages[0]
+	language := DetectLanguage(text)
 
 	// Parse alcohol content
 	var abv float64 = -1
@@ -79,20 +90,32 @@ func (p *DrinkParser) Parse(text string) (*models.DrinkParsed, error) {
 		volume = parsed
 	}
 
+	// Convert units the database does not understand (L, oz...)
+	volume, unit, normalized := normalizeVolume(volume, beverage.ContainerUnit)
+
 	// Set the parsed values
 	result.Name = beverage.Name
-	result.Type = beverage.Type
+	result.Type = canonicalType(beverage.Type, language)
 	result.SizeValue = volume
-	result.SizeUnit = beverage.ContainerUnit
+	result.SizeUnit = unit
 	result.ABV = abv
+	result.Language = string(language)
 	result.Success = true
-	result.Confidence = 1.0
+
+	// Record what the input says explicitly, the LLM may have guessed the rest
+	result.Evidence.NameExplicit = nameInText(text, result.Name)
+	_, result.Evidence.ABVExplicit = findABV(text)
+	_, _, result.Evidence.SizeExplicit = findVolume(text)
+	if !result.Evidence.SizeExplicit {
+		_, result.Evidence.SizeFromServe = findServingVolume(text, language)
+	}
+	result.Evidence.UnitNormalized = normalized
+	result.Evidence.TypeKeyword, _ = findType(text, language)
 
 	// Validate the parsed values
 	if result.Type == "" && (result.SizeValue <= 0 && result.SizeUnit == "") && result.ABV == -1 {
 		result.Success = false
 		result.ErrorMessage = "incomplete or invalid drink information"
-		result.Confidence = 0.0
 	}
 
 	return result, nil
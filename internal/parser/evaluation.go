@@ -0,0 +1,269 @@
+package parser
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"go-sober/internal/models"
+)
+
+// Tolerances used to decide whether a parsed value is correct
+const (
+	evalVolumeTolerance = 0.05  // 5% of the expected volume
+	evalABVTolerance    = 0.001 // 0.1 percentage point
+)
+
+// EvalExpectation is the labelled answer for a drink phrase
+type EvalExpectation struct {
+	Unparseable bool    `json:"unparseable,omitempty"` // The parser should report a failure
+	Name        string  `json:"name"`                  // Words the parsed name must contain
+	Type        string  `json:"type"`                  // Canonical drink type
+	SizeMl      float64 `json:"size_ml"`               // Volume in ml, 0 if unknown
+	ABV         float64 `json:"abv"`                   // Ratio, -1 if unknown
+}
+
+// EvalCase is a labelled drink phrase of the golden dataset
+type EvalCase struct {
+	Input    string          `json:"input"`
+	Expected EvalExpectation `json:"expected"`
+}
+
+// EvalCaseResult is the outcome of a single phrase
+type EvalCaseResult struct {
+	Input       string              `json:"input"`
+	Parsed      *models.DrinkParsed `json:"parsed,omitempty"`
+	Error       string              `json:"error,omitempty"`
+	FieldsOK    map[string]bool     `json:"fields_ok"`
+	VolumeError float64             `json:"volume_error_ml"` // Absolute error, when both volumes are known
+	ABVError    float64             `json:"abv_error"`       // Absolute error, when both ABVs are known
+	Latency     time.Duration       `json:"latency"`
+}
+
+// EvalLatency summarises parse durations
+type EvalLatency struct {
+	Mean time.Duration `json:"mean"`
+	P50  time.Duration `json:"p50"`
+	P95  time.Duration `json:"p95"`
+	Max  time.Duration `json:"max"`
+}
+
+// EvalReport aggregates the results of an evaluation run
+type EvalReport struct {
+	Backend          string             `json:"backend"`
+	Cases            int                `json:"cases"`
+	Errors           int                `json:"errors"`
+	FieldAccuracy    map[string]float64 `json:"field_accuracy"`
+	VolumeMeanAbsErr float64            `json:"volume_mean_abs_error_ml"`
+	ABVMeanAbsErr    float64            `json:"abv_mean_abs_error"`
+	Latency          EvalLatency        `json:"latency"`
+	Results          []EvalCaseResult   `json:"results"`
+}
+
+// Fields reported by an evaluation, "success" tells if the parser accepted the phrase
+var evalFields = []string{"success", models.ParsedFieldName, models.ParsedFieldType, models.ParsedFieldSize, models.ParsedFieldABV}
+
+// LoadEvalCorpus reads a golden dataset from a JSON file
+func LoadEvalCorpus(path string) ([]EvalCase, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not read corpus: %w", err)
+	}
+
+	var cases []EvalCase
+	if err := json.Unmarshal(data, &cases); err != nil {
+		return nil, fmt.Errorf("could not decode corpus: %w", err)
+	}
+	return cases, nil
+}
+
+// Evaluate runs every case through a parser and compares the results with the labels
+func Evaluate(backend string, drinkParser DrinkParser, cases []EvalCase) EvalReport {
+	report := EvalReport{
+		Backend:       backend,
+		Cases:         len(cases),
+		FieldAccuracy: make(map[string]float64, len(evalFields)),
+	}
+
+	correct := make(map[string]int, len(evalFields))
+	var volumeErrors, abvErrors []float64
+	var latencies []time.Duration
+
+	for _, evalCase := range cases {
+		start := time.Now()
+		parsed, err := drinkParser.Parse(evalCase.Input)
+		latency := time.Since(start)
+		latencies = append(latencies, latency)
+
+		result := EvalCaseResult{
+			Input:    evalCase.Input,
+			Parsed:   parsed,
+			FieldsOK: make(map[string]bool, len(evalFields)),
+			Latency:  latency,
+		}
+
+		if err != nil {
+			report.Errors++
+			result.Error = err.Error()
+			report.Results = append(report.Results, result)
+			continue
+		}
+
+		expected := evalCase.Expected
+		result.FieldsOK["success"] = parsed.Success != expected.Unparseable
+
+		if !expected.Unparseable {
+			result.FieldsOK[models.ParsedFieldName] = nameContains(parsed.Name, expected.Name)
+			result.FieldsOK[models.ParsedFieldType] = strings.EqualFold(parsed.Type, expected.Type)
+
+			parsedMl, ok := models.VolumeInMl(parsed.SizeValue, parsed.SizeUnit)
+			if !ok || parsed.SizeValue <= 0 {
+				parsedMl = 0
+			}
+			result.FieldsOK[models.ParsedFieldSize] = volumeMatches(parsedMl, expected.SizeMl)
+			if parsedMl > 0 && expected.SizeMl > 0 {
+				result.VolumeError = math.Abs(parsedMl - expected.SizeMl)
+				volumeErrors = append(volumeErrors, result.VolumeError)
+			}
+
+			result.FieldsOK[models.ParsedFieldABV] = abvMatches(parsed.ABV, expected.ABV)
+			if parsed.ABV >= 0 && expected.ABV >= 0 {
+				result.ABVError = math.Abs(parsed.ABV - expected.ABV)
+				abvErrors = append(abvErrors, result.ABVError)
+			}
+		}
+
+		for field, ok := range result.FieldsOK {
+			if ok {
+				correct[field]++
+			}
+		}
+		report.Results = append(report.Results, result)
+	}
+
+	// Fields other than success are only scored on phrases expected to parse
+	expectedSuccesses := 0
+	for _, evalCase := range cases {
+		if !evalCase.Expected.Unparseable {
+			expectedSuccesses++
+		}
+	}
+	for _, field := range evalFields {
+		total := expectedSuccesses
+		if field == "success" {
+			total = len(cases)
+		}
+		if total > 0 {
+			report.FieldAccuracy[field] = roundRatio(float64(correct[field]) / float64(total))
+		}
+	}
+
+	report.VolumeMeanAbsErr = roundRatio(mean(volumeErrors))
+	report.ABVMeanAbsErr = math.Round(mean(abvErrors)*100000) / 100000
+	report.Latency = summariseLatencies(latencies)
+
+	return report
+}
+
+// String renders the report as a human readable summary
+func (r EvalReport) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Backend: %s\n", r.Backend)
+	fmt.Fprintf(&b, "Cases: %d (errors: %d)\n", r.Cases, r.Errors)
+	fmt.Fprintf(&b, "Field accuracy:\n")
+	for _, field := range evalFields {
+		fmt.Fprintf(&b, "  %-8s %6.1f%%\n", field, r.FieldAccuracy[field]*100)
+	}
+	fmt.Fprintf(&b, "Volume mean abs error: %.1f ml\n", r.VolumeMeanAbsErr)
+	fmt.Fprintf(&b, "ABV mean abs error: %.2f pt\n", r.ABVMeanAbsErr*100)
+	fmt.Fprintf(&b, "Latency: mean %s, p50 %s, p95 %s, max %s\n", r.Latency.Mean, r.Latency.P50, r.Latency.P95, r.Latency.Max)
+	return b.String()
+}
+
+// Failures lists the cases with at least one wrong field
+func (r EvalReport) Failures() []EvalCaseResult {
+	var failures []EvalCaseResult
+	for _, result := range r.Results {
+		if result.Error != "" {
+			failures = append(failures, result)
+			continue
+		}
+		for _, ok := range result.FieldsOK {
+			if !ok {
+				failures = append(failures, result)
+				break
+			}
+		}
+	}
+	return failures
+}
+
+// nameContains checks that every expected word appears in the parsed name
+func nameContains(parsedName, expectedWords string) bool {
+	parsedName = strings.ToLower(parsedName)
+	for _, word := range strings.Fields(strings.ToLower(expectedWords)) {
+		if !strings.Contains(parsedName, word) {
+			return false
+		}
+	}
+	return true
+}
+
+func volumeMatches(parsedMl, expectedMl float64) bool {
+	if expectedMl <= 0 {
+		return parsedMl <= 0
+	}
+	return math.Abs(parsedMl-expectedMl) <= expectedMl*evalVolumeTolerance
+}
+
+func abvMatches(parsed, expected float64) bool {
+	if expected < 0 {
+		return parsed < 0
+	}
+	return math.Abs(parsed-expected) <= evalABVTolerance
+}
+
+func summariseLatencies(latencies []time.Duration) EvalLatency {
+	if len(latencies) == 0 {
+		return EvalLatency{}
+	}
+
+	sorted := append([]time.Duration(nil), latencies...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	var total time.Duration
+	for _, latency := range sorted {
+		total += latency
+	}
+
+	percentile := func(p float64) time.Duration {
+		index := int(math.Ceil(p*float64(len(sorted)))) - 1
+		return sorted[max(0, index)]
+	}
+
+	return EvalLatency{
+		Mean: total / time.Duration(len(sorted)),
+		P50:  percentile(0.5),
+		P95:  percentile(0.95),
+		Max:  sorted[len(sorted)-1],
+	}
+}
+
+func mean(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	var sum float64
+	for _, value := range values {
+		sum += value
+	}
+	return sum / float64(len(values))
+}
+
+func roundRatio(value float64) float64 {
+	return math.Round(value*1000) / 1000
+}
@@ -0,0 +1,136 @@
+package parser
+
+import (
+	"testing"
+	"time"
+
+	"go-sober/internal/llm"
+	"go-sober/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	goldenCorpusPath       = "testdata/golden.json"
+	frenchGoldenCorpusPath = "testdata/golden_fr.json"
+	llmRecordingPath       = "testdata/llm_recording.json"
+	frenchLLMRecordingPath = "testdata/llm_recording_fr.json"
+)
+
+// Minimum accuracy per field, a prompt or rule change going below fails the build
+var (
+	llmAccuracyThresholds = map[string]float64{
+		"success":              1,
+		models.ParsedFieldName: 0.9,
+		models.ParsedFieldType: 0.8,
+		models.ParsedFieldSize: 0.9,
+		models.ParsedFieldABV:  0.95,
+	}
+	ruleAccuracyThresholds = map[string]float64{
+		"success":              0.9,
+		models.ParsedFieldName: 0.8,
+		models.ParsedFieldType: 0.6,
+		models.ParsedFieldSize: 0.9,
+		models.ParsedFieldABV:  0.95,
+	}
+)
+
+func TestGoldenCorpusLLM(t *testing.T) {
+	assertLLMReplay(t, "llm-replay", goldenCorpusPath, llmRecordingPath)
+}
+
+func TestGoldenCorpusLLMFrench(t *testing.T) {
+	assertLLMReplay(t, "llm-replay-fr", frenchGoldenCorpusPath, frenchLLMRecordingPath)
+}
+
+// assertLLMReplay evaluates the LLM parser on a corpus with the recorded responses
+func assertLLMReplay(t *testing.T, backend, corpusPath, recordingPath string) {
+	t.Helper()
+	cases, err := LoadEvalCorpus(corpusPath)
+	require.NoError(t, err)
+
+	recording, err := llm.LoadRecording(recordingPath)
+	require.NoError(t, err)
+
+	// Every phrase must have a response recorded with the current prompt
+	for _, evalCase := range cases {
+		_, err := recording.Response(BEVERAGE_PARSER_PROMPT, LLMInput(evalCase.Input))
+		assert.NoError(t, err)
+	}
+
+	report := Evaluate(backend, NewLLMParser(llm.NewReplayProvider(recording)), cases)
+	assert.Zero(t, report.Errors)
+	assertAccuracy(t, report, llmAccuracyThresholds)
+}
+
+func TestGoldenCorpusRules(t *testing.T) {
+	cases, err := LoadEvalCorpus(goldenCorpusPath)
+	require.NoError(t, err)
+
+	report := Evaluate("rules", NewRuleParser(), cases)
+	assert.Zero(t, report.Errors)
+	assertAccuracy(t, report, ruleAccuracyThresholds)
+}
+
+func TestGoldenCorpusRulesFrench(t *testing.T) {
+	cases, err := LoadEvalCorpus(frenchGoldenCorpusPath)
+	require.NoError(t, err)
+
+	report := Evaluate("rules-fr", NewRuleParser(), cases)
+	assert.Zero(t, report.Errors)
+	assertAccuracy(t, report, ruleAccuracyThresholds)
+}
+
+func assertAccuracy(t *testing.T, report EvalReport, thresholds map[string]float64) {
+	t.Helper()
+	for field, threshold := range thresholds {
+		assert.GreaterOrEqual(t, report.FieldAccuracy[field], threshold, "accuracy of %s", field)
+	}
+	if t.Failed() {
+		t.Log(report.String())
+		for _, failure := range report.Failures() {
+			t.Logf("%q: %v", failure.Input, failure.FieldsOK)
+		}
+	}
+}
+
+// stubParser answers with canned results and a fixed latency
+type stubParser struct {
+	results map[string]*models.DrinkParsed
+	latency time.Duration
+}
+
+func (p *stubParser) Parse(text string) (*models.DrinkParsed, error) {
+	time.Sleep(p.latency)
+	return p.results[text], nil
+}
+
+func TestEvaluate(t *testing.T) {
+	cases := []EvalCase{
+		{Input: "pint of stout 4.2%", Expected: EvalExpectation{Name: "stout", Type: "beer", SizeMl: 500, ABV: 0.042}},
+		{Input: "glass of merlot", Expected: EvalExpectation{Name: "merlot", Type: "wine", SizeMl: 150, ABV: -1}},
+		{Input: "hello", Expected: EvalExpectation{Unparseable: true}},
+	}
+	parser := &stubParser{
+		latency: time.Millisecond,
+		results: map[string]*models.DrinkParsed{
+			"pint of stout 4.2%": {Name: "Stout, 500ml, 4.2%", Type: "beer", SizeValue: 50, SizeUnit: "cl", ABV: 0.042, Success: true},
+			"glass of merlot":    {Name: "Merlot Wine, 175ml", Type: "wine", SizeValue: 175, SizeUnit: "ml", ABV: 0.13, Success: true},
+			"hello":              {Success: false},
+		},
+	}
+
+	report := Evaluate("stub", parser, cases)
+
+	assert.Equal(t, 3, report.Cases)
+	assert.Equal(t, 1.0, report.FieldAccuracy["success"])
+	assert.Equal(t, 1.0, report.FieldAccuracy[models.ParsedFieldName])
+	assert.Equal(t, 1.0, report.FieldAccuracy[models.ParsedFieldType])
+	assert.Equal(t, 0.5, report.FieldAccuracy[models.ParsedFieldSize])
+	assert.Equal(t, 0.5, report.FieldAccuracy[models.ParsedFieldABV])
+	assert.Equal(t, 12.5, report.VolumeMeanAbsErr)
+	assert.Equal(t, 0.0, report.ABVMeanAbsErr)
+	assert.GreaterOrEqual(t, report.Latency.Max, time.Millisecond)
+	assert.Len(t, report.Failures(), 1)
+}
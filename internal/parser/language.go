@@ -0,0 +1,56 @@
+package parser
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Language of a drink description, as an ISO 639-1 code
+type Language string
+
+const (
+	LanguageEnglish Language = "en"
+	LanguageFrench  Language = "fr"
+)
+
+// Words that are frequent in drink descriptions of a single language
+var languageMarkers = map[Language]map[string]bool{
+	LanguageEnglish: {
+		"a": true, "an": true, "the": true, "of": true, "glass": true, "bottle": true,
+		"pint": true, "pints": true, "half": true, "had": true, "drank": true, "with": true,
+		"beer": true, "wine": true, "red": true, "white": true, "can": true, "shot": true,
+	},
+	LanguageFrench: {
+		"un": true, "une": true, "de": true, "du": true, "des": true, "d": true, "le": true,
+		"la": true, "les": true, "verre": true, "bouteille": true, "canette": true,
+		"demi": true, "pinte": true, "ballon": true, "galopin": true, "coupe": true,
+		"bière": true, "biere": true, "vin": true, "rouge": true, "blanc": true,
+		"blonde": true, "brune": true, "blanche": true, "ambrée": true, "pression": true,
+		"j": true, "ai": true, "bu": true, "avec": true, "cidre": true, "rhum": true,
+	},
+}
+
+// DetectLanguage guesses the language of a drink description from its marker words.
+// Descriptions without any marker, such as "IPA 33cl 6%", are considered English.
+func DetectLanguage(text string) Language {
+	scores := make(map[Language]int, len(languageMarkers))
+	for _, word := range languageWords(text) {
+		for language, markers := range languageMarkers {
+			if markers[word] {
+				scores[language]++
+			}
+		}
+	}
+
+	if scores[LanguageFrench] > scores[LanguageEnglish] {
+		return LanguageFrench
+	}
+	return LanguageEnglish
+}
+
+// languageWords splits a text in lowercase words, elisions such as "d'IPA" give "d" and "ipa"
+func languageWords(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r)
+	})
+}
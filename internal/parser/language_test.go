@@ -0,0 +1,35 @@
+package parser
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDetectLanguage(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected Language
+	}{
+		{"un demi de blonde", LanguageFrench},
+		{"verre de rouge 12°", LanguageFrench},
+		{"j'ai bu une pinte d'IPA", LanguageFrench},
+		{"pint of Guinness", LanguageEnglish},
+		{"a glass of red wine", LanguageEnglish},
+		{"IPA 33cl 6%", LanguageEnglish},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			assert.Equal(t, tt.expected, DetectLanguage(tt.input))
+		})
+	}
+}
+
+func TestCanonicalType(t *testing.T) {
+	assert.Equal(t, "beer", canonicalType("Bière", LanguageFrench))
+	assert.Equal(t, "wine", canonicalType("vin rouge", LanguageFrench))
+	assert.Equal(t, "wine", canonicalType("champagne", LanguageEnglish))
+	assert.Equal(t, "spirit", canonicalType("spirit", LanguageEnglish))
+	assert.Equal(t, "sake", canonicalType("Sake", LanguageEnglish))
+}
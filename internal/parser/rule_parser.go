@@ -0,0 +1,122 @@
+package parser
+
+import (
+	"strings"
+	"unicode"
+
+	"go-sober/internal/models"
+)
+
+// Words of a description that are not part of the drink name
+var fillerWords = map[string]bool{
+	"i": true, "had": true, "have": true, "got": true, "drank": true, "ordered": true,
+	"enjoyed": true, "sipped": true, "on": true, "a": true, "an": true, "the": true,
+	"of": true, "at": true, "in": true, "and": true, "one": true, "two": true,
+	"some": true, "this": true, "nice": true, "glass": true, "bottle": true,
+	"can": true, "cans": true, "pint": true, "pints": true, "half": true, "shot": true, "shots": true,
+	"each": true, "alcohol": true, "vol": true, "tap": true, "once": true, "again": true,
+	// French
+	"un": true, "une": true, "de": true, "du": true, "des": true, "d": true, "l": true,
+	"le": true, "la": true, "les": true, "j": true, "ai": true, "bu": true, "pris": true,
+	"au": true, "à": true, "et": true, "deux": true, "avec": true, "verre": true, "verres": true,
+	"bouteille": true, "canette": true, "demi": true, "demis": true, "pinte": true, "pintes": true,
+	"ballon": true, "galopin": true, "coupe": true,
+}
+
+// French articles and pronouns elided before a vowel, e.g. "d'IPA"
+var elisionPrefixes = []string{"d'", "l'", "j'", "qu'"}
+
+// RuleParser parses drink descriptions with regular expressions and keywords.
+// It is less clever than the LLM but deterministic and free.
+type RuleParser struct{}
+
+// NewRuleParser creates a new RuleParser instance
+func NewRuleParser() *RuleParser {
+	return &RuleParser{}
+}
+
+// Parse takes a drink description text and returns the parsed drink details
+func (p *RuleParser) Parse(text string) (*models.DrinkParsed, error) {
+	result := &models.DrinkParsed{
+		Success:       false,
+		OriginalInput: text,
+		ABV:           -1,
+	}
+
+	language := DetectLanguage(text)
+	result.Language = string(language)
+
+	if abv, ok := findABV(text); ok {
+		result.ABV = abv
+		result.Evidence.ABVExplicit = true
+	}
+
+	if value, unit, ok := findVolume(text); ok {
+		value, unit, normalized := normalizeVolume(value, unit)
+		result.SizeValue = value
+		result.SizeUnit = unit
+		result.Evidence.SizeExplicit = true
+		result.Evidence.UnitNormalized = normalized
+	} else if volume, ok := findServingVolume(text, language); ok {
+		result.SizeValue = volume
+		result.SizeUnit = "ml"
+		result.Evidence.SizeFromServe = true
+	}
+
+	if drinkType, ok := findType(text, language); ok {
+		result.Type = drinkType
+		result.Evidence.TypeKeyword = drinkType
+	}
+
+	result.Name = ruleName(text)
+	result.Evidence.NameExplicit = nameInText(text, result.Name)
+
+	if result.Type == "" && result.SizeValue <= 0 && result.ABV == -1 {
+		result.ErrorMessage = "incomplete or invalid drink information"
+		return result, nil
+	}
+
+	result.Success = true
+	return result, nil
+}
+
+// ruleName keeps the descriptive words of a text, e.g. "pint of stout 4.2%" gives "Stout"
+func ruleName(text string) string {
+	text = abvPattern.ReplaceAllString(text, " ")
+	text = volumePattern.ReplaceAllString(text, " ")
+
+	var words []string
+	for _, word := range strings.FieldsFunc(text, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '\'' && r != '-'
+	}) {
+		word = stripElision(word)
+		lower := strings.ToLower(word)
+		if fillerWords[lower] || !strings.ContainsFunc(word, unicode.IsLetter) {
+			continue
+		}
+		words = append(words, titleWord(word))
+	}
+
+	return strings.Join(words, " ")
+}
+
+// stripElision removes an elided French article, "d'IPA" gives "IPA"
+func stripElision(word string) string {
+	lower := strings.ToLower(word)
+	for _, prefix := range elisionPrefixes {
+		if strings.HasPrefix(lower, prefix) && len(word) > len(prefix) {
+			return word[len(prefix):]
+		}
+	}
+	return word
+}
+
+// titleWord upper-cases the first letter of a word, keeping acronyms such as "IPA"
+func titleWord(word string) string {
+	if strings.ToUpper(word) == word {
+		return word
+	}
+	runes := []rune(strings.ToLower(word))
+	runes[0] = unicode.ToUpper(runes[0])
+	return string(runes)
+}
@@ -0,0 +1,87 @@
+package parser
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRuleParser(t *testing.T) {
+	tests := []struct {
+		name         string
+		text         string
+		expectedName string
+		expectedType string
+		expectedSize float64
+		expectedUnit string
+		expectedABV  float64
+	}{
+		{
+			name:         "pint with ABV",
+			text:         "pint of stout 4.2%",
+			expectedName: "Stout",
+			expectedType: "beer",
+			expectedSize: 500,
+			expectedUnit: "ml",
+			expectedABV:  0.042,
+		},
+		{
+			name:         "centilitres and degrees",
+			text:         "one IPA 33cl 6.7°",
+			expectedName: "IPA",
+			expectedType: "beer",
+			expectedSize: 33,
+			expectedUnit: "cl",
+			expectedABV:  0.067,
+		},
+		{
+			name:         "litres are converted",
+			text:         "1.5L of red wine",
+			expectedName: "Red Wine",
+			expectedType: "wine",
+			expectedSize: 1500,
+			expectedUnit: "ml",
+			expectedABV:  -1,
+		},
+		{
+			name:         "decimal comma",
+			text:         "Merlot 150 ml 13,5%",
+			expectedName: "Merlot",
+			expectedType: "wine",
+			expectedSize: 150,
+			expectedUnit: "ml",
+			expectedABV:  0.135,
+		},
+		{
+			name:         "spirit shot",
+			text:         "had a shot of tequila",
+			expectedName: "Tequila",
+			expectedType: "spirit",
+			expectedSize: 25,
+			expectedUnit: "ml",
+			expectedABV:  -1,
+		},
+	}
+
+	parser := NewRuleParser()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := parser.Parse(tt.text)
+			assert.NoError(t, err)
+			assert.True(t, result.Success)
+			assert.Equal(t, tt.expectedName, result.Name)
+			assert.True(t, result.Evidence.NameExplicit)
+			assert.Equal(t, tt.expectedType, result.Type)
+			assert.Equal(t, tt.expectedSize, result.SizeValue)
+			assert.Equal(t, tt.expectedUnit, result.SizeUnit)
+			assert.InDelta(t, tt.expectedABV, result.ABV, 0.0001)
+		})
+	}
+
+	t.Run("nothing to parse", func(t *testing.T) {
+		result, err := parser.Parse("hello there")
+		assert.NoError(t, err)
+		assert.False(t, result.Success)
+		assert.NotEmpty(t, result.ErrorMessage)
+	})
+}
@@ -0,0 +1,243 @@
+package parser
+
+import (
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"go-sober/internal/models"
+)
+
+var (
+	// e.g. "33cl", "500 ml", "1.5L", "12 oz"
+	volumePattern = regexp.MustCompile(`(?i)(\d+(?:[.,]\d+)?)\s*(ml|cl|dl|l|litres?|liters?|fl\.?\s?oz|oz)\b`)
+	// e.g. "4.2%", "6,7°", "12 % vol"
+	abvPattern = regexp.MustCompile(`(\d+(?:[.,]\d+)?)\s*(%|°)`)
+)
+
+// serving is a standard volume implied by a serving name
+type serving struct {
+	keyword string
+	volume  float64 // in ml
+}
+
+// Serving names per language, longest first so that "half pint" wins over "pint"
+var servings = map[Language][]serving{
+	LanguageEnglish: {
+		{"half pint", 250},
+		{"half a pint", 250},
+		{"pint", 500},
+		{"pints", 500},
+		{"shot", 25},
+		{"shots", 25},
+		{"can", 330},
+	},
+	LanguageFrench: {
+		{"demi", 250},
+		{"demis", 250},
+		{"pinte", 500},
+		{"pintes", 500},
+		{"ballon", 125},
+		{"ballons", 125},
+		{"galopin", 125},
+		{"coupe", 120},
+		{"coupes", 120},
+		{"canette", 330},
+	},
+}
+
+// typeKeyword maps a word of the input to a canonical drink type
+type typeKeyword struct {
+	keyword   string
+	drinkType string
+}
+
+// Keywords per language, checked in order so that specific words come before generic ones
+var typeKeywords = map[Language][]typeKeyword{
+	LanguageEnglish: {
+		{"cider", "cider"},
+		{"spritz", "aperitif"}, {"negroni", "aperitif"}, {"martini", "aperitif"},
+		{"vermouth", "aperitif"}, {"lillet", "aperitif"}, {"pastis", "aperitif"},
+		{"cocktail", "cocktail"}, {"mojito", "cocktail"}, {"margarita", "cocktail"},
+		{"daiquiri", "cocktail"}, {"cosmopolitan", "cocktail"}, {"pina colada", "cocktail"},
+		{"whisky", "spirit"}, {"whiskey", "spirit"}, {"bourbon", "spirit"}, {"scotch", "spirit"},
+		{"vodka", "spirit"}, {"gin", "spirit"}, {"rum", "spirit"}, {"tequila", "spirit"},
+		{"cognac", "spirit"}, {"brandy", "spirit"}, {"mezcal", "spirit"},
+		{"wine", "wine"}, {"champagne", "wine"}, {"prosecco", "wine"}, {"cava", "wine"},
+		{"merlot", "wine"}, {"riesling", "wine"}, {"chardonnay", "wine"}, {"sauvignon", "wine"},
+		{"pinot", "wine"}, {"rosé", "wine"}, {"rose", "wine"}, {"malbec", "wine"},
+		{"beer", "beer"}, {"ipa", "beer"}, {"lager", "beer"}, {"stout", "beer"},
+		{"ale", "beer"}, {"pilsner", "beer"}, {"porter", "beer"}, {"guinness", "beer"},
+		{"hefeweizen", "beer"}, {"tripel", "beer"}, {"heineken", "beer"}, {"stella", "beer"},
+	},
+	LanguageFrench: {
+		{"cidre", "cider"}, {"poiré", "cider"},
+		{"kir", "aperitif"}, {"ricard", "aperitif"}, {"suze", "aperitif"}, {"picon", "aperitif"},
+		{"apéritif", "aperitif"}, {"apéro", "aperitif"},
+		{"rhum", "spirit"}, {"calvados", "spirit"}, {"calva", "spirit"}, {"armagnac", "spirit"},
+		{"eau-de-vie", "spirit"}, {"génépi", "spirit"}, {"digestif", "spirit"},
+		{"vin", "wine"}, {"rouge", "wine"}, {"blanc", "wine"}, {"crémant", "wine"},
+		{"mousseux", "wine"}, {"bordeaux", "wine"}, {"bourgogne", "wine"}, {"beaujolais", "wine"},
+		{"bière", "beer"}, {"biere", "beer"}, {"blonde", "beer"}, {"brune", "beer"},
+		{"blanche", "beer"}, {"ambrée", "beer"}, {"ambree", "beer"}, {"pression", "beer"},
+	},
+}
+
+// Canonical drink types, the ones used by the drink templates
+var canonicalTypes = map[string]bool{
+	"beer": true, "wine": true, "cider": true, "spirit": true,
+	"aperitif": true, "cocktail": true, "shot": true,
+}
+
+// findVolume extracts an explicit volume from a text
+func findVolume(text string) (value float64, unit string, ok bool) {
+	match := volumePattern.FindStringSubmatch(text)
+	if match == nil {
+		return 0, "", false
+	}
+
+	value, err := parseDecimal(match[1])
+	if err != nil {
+		return 0, "", false
+	}
+	return value, normalizeUnitName(match[2]), true
+}
+
+// findServingVolume deduces a volume from a serving name such as "pint" or "demi"
+func findServingVolume(text string, language Language) (float64, bool) {
+	lower := strings.ToLower(text)
+	for _, language := range languagesFrom(language) {
+		for _, serving := range servings[language] {
+			if containsWord(lower, serving.keyword) {
+				return serving.volume, true
+			}
+		}
+	}
+	return 0, false
+}
+
+// findABV extracts an explicit alcohol content from a text, as a ratio
+func findABV(text string) (float64, bool) {
+	match := abvPattern.FindStringSubmatch(text)
+	if match == nil {
+		return 0, false
+	}
+
+	value, err := parseDecimal(match[1])
+	if err != nil || value > 100 {
+		return 0, false
+	}
+	return value / 100, true
+}
+
+// findType deduces a canonical drink type from the keywords of a text
+func findType(text string, language Language) (string, bool) {
+	lower := strings.ToLower(text)
+	for _, language := range languagesFrom(language) {
+		for _, keyword := range typeKeywords[language] {
+			if containsWord(lower, keyword.keyword) {
+				return keyword.drinkType, true
+			}
+		}
+	}
+	return "", false
+}
+
+// canonicalType maps a drink type such as "bière" or "champagne" to a canonical type.
+// Unknown types are kept as they are.
+func canonicalType(drinkType string, language Language) string {
+	drinkType = strings.ToLower(strings.TrimSpace(drinkType))
+	if drinkType == "" || canonicalTypes[drinkType] {
+		return drinkType
+	}
+	if canonical, ok := findType(drinkType, language); ok {
+		return canonical
+	}
+	return drinkType
+}
+
+// languagesFrom lists the languages to look up, the detected one first.
+// Descriptions mix languages, e.g. "une pinte d'IPA".
+func languagesFrom(language Language) []Language {
+	languages := []Language{language}
+	for _, other := range []Language{LanguageEnglish, LanguageFrench} {
+		if other != language {
+			languages = append(languages, other)
+		}
+	}
+	return languages
+}
+
+// normalizeVolume converts a volume to a unit the database understands (ml or cl).
+// The boolean tells whether a conversion happened.
+func normalizeVolume(value float64, unit string) (float64, string, bool) {
+	unit = normalizeUnitName(unit)
+	if unit == "ml" || unit == "cl" {
+		return value, unit, false
+	}
+
+	ml, ok := models.VolumeInMl(value, unit)
+	if !ok {
+		return value, unit, false
+	}
+	return math.Round(ml), "ml", true
+}
+
+// normalizeUnitName maps the spellings of a unit to its symbol
+func normalizeUnitName(unit string) string {
+	unit = strings.ToLower(strings.TrimSpace(unit))
+	switch {
+	case strings.HasPrefix(unit, "lit"):
+		return "l"
+	case strings.Contains(unit, "oz"):
+		return "oz"
+	}
+	return unit
+}
+
+// nameInText reports whether every word of a name is written in a text, e.g. "Guinness" in "pint of guinness"
+func nameInText(text, name string) bool {
+	words := strings.FieldsFunc(strings.ToLower(name), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	if len(words) == 0 {
+		return false
+	}
+
+	text = strings.ToLower(text)
+	for _, word := range words {
+		if !containsWord(text, word) {
+			return false
+		}
+	}
+	return true
+}
+
+// containsWord reports whether a lowercase text contains a keyword as a whole word
+func containsWord(text, keyword string) bool {
+	for start := 0; ; {
+		index := strings.Index(text[start:], keyword)
+		if index < 0 {
+			return false
+		}
+		index += start
+		end := index + len(keyword)
+
+		before := index == 0 || !isWordByte(text[index-1])
+		after := end == len(text) || !isWordByte(text[end])
+		if before && after {
+			return true
+		}
+		start = index + 1
+	}
+}
+
+func isWordByte(b byte) bool {
+	return b >= 'a' && b <= 'z' || b >= '0' && b <= '9' || b >= 0x80
+}
+
+func parseDecimal(s string) (float64, error) {
+	return strconv.ParseFloat(strings.Replace(s, ",", ".", 1), 64)
+}
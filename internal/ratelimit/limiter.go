@@ -0,0 +1,82 @@
+package ratelimit
+
+import (
+	"sync"
+	"time"
+)
+
+// Buckets untouched for this long are full again and can be forgotten
+const idleBucketTTL = 10 * time.Minute
+
+type bucket struct {
+	tokens   float64
+	lastSeen time.Time
+}
+
+// Limiter is a token bucket rate limiter keyed by an arbitrary string (user, IP...)
+type Limiter struct {
+	mu        sync.Mutex
+	rate      float64 // Tokens added per second
+	burst     float64
+	buckets   map[string]*bucket
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+// NewLimiter allows requestsPerMinute on average per key, with bursts of up to burst requests
+func NewLimiter(requestsPerMinute int, burst int) *Limiter {
+	if burst < 1 {
+		burst = 1
+	}
+
+	return &Limiter{
+		rate:    float64(requestsPerMinute) / 60,
+		burst:   float64(burst),
+		buckets: make(map[string]*bucket),
+		now:     time.Now,
+	}
+}
+
+// Allow consumes a token for the key. When none is left, it returns false
+// and how long to wait before the next token.
+func (l *Limiter) Allow(key string) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.sweep(now)
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: l.burst, lastSeen: now}
+		l.buckets[key] = b
+	}
+
+	b.tokens = min(l.burst, b.tokens+now.Sub(b.lastSeen).Seconds()*l.rate)
+	b.lastSeen = now
+
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+
+	if l.rate <= 0 {
+		return false, idleBucketTTL
+	}
+	wait := time.Duration((1 - b.tokens) / l.rate * float64(time.Second))
+	return false, wait
+}
+
+// sweep forgets the idle buckets, at most once per idle period
+func (l *Limiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < idleBucketTTL {
+		return
+	}
+	l.lastSweep = now
+
+	for key, b := range l.buckets {
+		if now.Sub(b.lastSeen) >= idleBucketTTL {
+			delete(l.buckets, key)
+		}
+	}
+}
@@ -0,0 +1,31 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLimiter(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	limiter := NewLimiter(6, 2) // One token every 10 seconds
+	limiter.now = func() time.Time { return now }
+
+	allowed, _ := limiter.Allow("1")
+	assert.True(t, allowed)
+	allowed, _ = limiter.Allow("1")
+	assert.True(t, allowed)
+
+	allowed, retryAfter := limiter.Allow("1")
+	assert.False(t, allowed)
+	assert.Equal(t, 10*time.Second, retryAfter)
+
+	// Other keys have their own bucket
+	allowed, _ = limiter.Allow("2")
+	assert.True(t, allowed)
+
+	now = now.Add(10 * time.Second)
+	allowed, _ = limiter.Allow("1")
+	assert.True(t, allowed)
+}
@@ -1,12 +1,19 @@
 package user
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
 	"net/http"
+	"time"
 
+	"go-sober/internal/auth"
 	"go-sober/internal/constants"
 	"go-sober/internal/dtos"
 	"go-sober/internal/models"
+	"go-sober/internal/validation"
 )
 
 type Controller struct {
@@ -18,11 +25,11 @@ func NewController(service *Service) *Controller {
 }
 
 // @Summary Update user profile
-// @Description Update the current user's profile information
+// @Description Update the current user's profile information. A new weight is added to the weight history and applies from now on. The drinks before the day rollover hour count for the previous day in the analytics.
 // @Tags users
 // @Accept json
 // @Produce json
-// @Param Authorization header string true "Bearer token"
+// @Param Authorization header string true "Bearer token, or personal access token with the profile:write scope"
 // @Param profile body dtos.UpdateUserProfileRequest true "User profile"
 // @Success 200 {object} dtos.UserProfileResponse
 // @Failure 400 {object} dtos.ClientError
@@ -35,8 +42,8 @@ func (c *Controller) UpdateProfile(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var req dtos.UpdateUserProfileRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+	if err := validation.DecodeJSON(r, &req); err != nil {
+		validation.WriteError(w, err)
 		return
 	}
 
@@ -57,10 +64,12 @@ func (c *Controller) UpdateProfile(w http.ResponseWriter, r *http.Request) {
 	}
 
 	response := dtos.UserProfileResponse{
-		WeightKg:  profile.WeightKg,
-		Gender:    profile.Gender,
-		CreatedAt: profile.CreatedAt,
-		UpdatedAt: profile.UpdatedAt,
+		WeightKg:        profile.WeightKg,
+		Gender:          profile.Gender,
+		Timezone:        profile.Timezone,
+		DayRolloverHour: profile.DayRolloverHour,
+		CreatedAt:       profile.CreatedAt,
+		UpdatedAt:       profile.UpdatedAt,
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -72,7 +81,7 @@ func (c *Controller) UpdateProfile(w http.ResponseWriter, r *http.Request) {
 // @Tags users
 // @Accept json
 // @Produce json
-// @Param Authorization header string true "Bearer token"
+// @Param Authorization header string true "Bearer token, or personal access token with the profile:read scope"
 // @Success 200 {object} dtos.UserProfileResponse
 // @Failure 404 {object} dtos.ClientError
 // @Router /users/profile [get]
@@ -95,11 +104,227 @@ func (c *Controller) GetProfile(w http.ResponseWriter, r *http.Request) {
 	}
 
 	response := dtos.UserProfileResponse{
-		WeightKg:  profile.WeightKg,
-		Gender:    profile.Gender,
-		UpdatedAt: profile.UpdatedAt,
+		WeightKg:        profile.WeightKg,
+		Gender:          profile.Gender,
+		Timezone:        profile.Timezone,
+		DayRolloverHour: profile.DayRolloverHour,
+		UpdatedAt:       profile.UpdatedAt,
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(response)
 }
+
+// @Summary Get the weight history
+// @Description Get every weight of the current user's profile with the date it applies from, oldest first. The BAC of a drink uses the weight of its time.
+// @Tags users
+// @Produce json
+// @Param Authorization header string true "Bearer token, or personal access token with the profile:read scope"
+// @Success 200 {object} dtos.WeightHistoryResponse
+// @Failure 401 {object} dtos.ClientError
+// @Failure 500 {object} dtos.ClientError
+// @Router /users/profile/weight-history [get]
+func (c *Controller) GetWeightHistory(w http.ResponseWriter, r *http.Request) {
+	claims := r.Context().Value(constants.UserContextKey).(*models.Claims)
+
+	history, err := c.service.GetWeightHistory(claims.UserID)
+	if err != nil {
+		slog.Error("Could not get weight history", "user_id", claims.UserID, "error", err)
+		http.Error(w, "Could not get weight history", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(dtos.WeightHistoryResponse{Weights: history})
+}
+
+// @Summary Get the drink limits
+// @Description Get the daily and weekly limits the current user set for themselves, in standard drinks. The analytics compare them with the drinks like the guidelines.
+// @Tags users
+// @Produce json
+// @Param Authorization header string true "Bearer token, or personal access token with the profile:read scope"
+// @Success 200 {object} dtos.DrinkLimitsResponse
+// @Failure 401 {object} dtos.ClientError
+// @Failure 500 {object} dtos.ClientError
+// @Router /users/profile/limits [get]
+func (c *Controller) GetDrinkLimits(w http.ResponseWriter, r *http.Request) {
+	claims := r.Context().Value(constants.UserContextKey).(*models.Claims)
+
+	limits, err := c.service.GetDrinkLimits(claims.UserID)
+	if err != nil {
+		slog.Error("Could not get drink limits", "user_id", claims.UserID, "error", err)
+		http.Error(w, "Could not get drink limits", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(dtos.DrinkLimitsResponse{Limits: *limits})
+}
+
+// @Summary Set the drink limits
+// @Description Replace the daily and weekly limits of the current user, in standard drinks. A null limit does not apply, setting none removes the personal limits.
+// @Tags users
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Bearer token, or personal access token with the profile:write scope"
+// @Param limits body dtos.DrinkLimitsRequest true "Drink limits"
+// @Success 200 {object} dtos.DrinkLimitsResponse
+// @Failure 400 {object} dtos.ClientError
+// @Failure 401 {object} dtos.ClientError
+// @Failure 500 {object} dtos.ClientError
+// @Router /users/profile/limits [put]
+func (c *Controller) SetDrinkLimits(w http.ResponseWriter, r *http.Request) {
+	claims := r.Context().Value(constants.UserContextKey).(*models.Claims)
+
+	var req dtos.DrinkLimitsRequest
+	if err := validation.DecodeJSON(r, &req); err != nil {
+		validation.WriteError(w, err)
+		return
+	}
+
+	limits, err := c.service.SetDrinkLimits(claims.UserID, req)
+	if err != nil {
+		slog.Error("Could not set drink limits", "user_id", claims.UserID, "error", err)
+		http.Error(w, "Could not set drink limits", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(dtos.DrinkLimitsResponse{Limits: *limits})
+}
+
+// @Summary Delete the account
+// @Description Schedule the deletion of the account and all its data after a grace period. The user is logged out of every device; logging in again before the date cancels the deletion.
+// @Tags users
+// @Produce json
+// @Param Authorization header string true "Bearer token"
+// @Success 202 {object} dtos.AccountDeletionResponse
+// @Failure 401 {object} dtos.ClientError
+// @Failure 500 {object} dtos.ClientError
+// @Router /users/me [delete]
+func (c *Controller) DeleteAccount(w http.ResponseWriter, r *http.Request) {
+	claims := r.Context().Value(constants.UserContextKey).(*models.Claims)
+
+	deleteAt, err := c.service.RequestAccountDeletion(claims.UserID)
+	if err != nil {
+		slog.Error("Could not delete account", "user_id", claims.UserID, "error", err)
+		http.Error(w, "Could not delete account", http.StatusInternalServerError)
+		return
+	}
+
+	response := dtos.AccountDeletionResponse{
+		Message:  "Your account will be deleted, log in again before the date to keep it",
+		DeleteAt: deleteAt,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(response)
+}
+
+// @Summary Export the user data
+// @Description Download a zip archive of every record tied to the current user, one JSON file per kind of record
+// @Tags users
+// @Produce application/zip
+// @Param Authorization header string true "Bearer token"
+// @Success 200 {file} file
+// @Failure 401 {object} dtos.ClientError
+// @Failure 500 {object} dtos.ClientError
+// @Router /users/me/export [get]
+func (c *Controller) ExportData(w http.ResponseWriter, r *http.Request) {
+	claims := r.Context().Value(constants.UserContextKey).(*models.Claims)
+
+	// Built in memory first, to answer with an error rather than a truncated archive
+	var archive bytes.Buffer
+	if err := c.service.ExportUserData(claims.UserID, &archive); err != nil {
+		slog.Error("Could not export user data", "user_id", claims.UserID, "error", err)
+		http.Error(w, "Could not export user data", http.StatusInternalServerError)
+		return
+	}
+
+	filename := fmt.Sprintf("sober-export-%s.zip", time.Now().UTC().Format("2006-01-02"))
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+	w.Write(archive.Bytes())
+}
+
+// @Summary Change the password
+// @Description Set a new password, with the current one. The other devices are logged out.
+// @Tags users
+// @Accept json
+// @Param Authorization header string true "Bearer token"
+// @Param request body dtos.ChangePasswordRequest true "Change password request"
+// @Success 204
+// @Failure 400 {object} dtos.ClientError
+// @Failure 401 {object} dtos.ClientError
+// @Failure 403 {object} dtos.ClientError
+// @Failure 429 {object} dtos.ClientError
+// @Failure 500 {object} dtos.ClientError
+// @Router /users/me/password [put]
+func (c *Controller) ChangePassword(w http.ResponseWriter, r *http.Request) {
+	claims := r.Context().Value(constants.UserContextKey).(*models.Claims)
+
+	var req dtos.ChangePasswordRequest
+	if err := validation.DecodeJSON(r, &req); err != nil {
+		validation.WriteError(w, err)
+		return
+	}
+
+	if err := c.service.ChangePassword(claims, req); err != nil {
+		writeAccountError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// @Summary Change the email address
+// @Description Move the account to a new email address, with the current password. The new address must be verified with the link sent to it, and the previous one is told about the change.
+// @Tags users
+// @Accept json
+// @Param Authorization header string true "Bearer token"
+// @Param request body dtos.ChangeEmailRequest true "Change email request"
+// @Success 204
+// @Failure 400 {object} dtos.ClientError
+// @Failure 401 {object} dtos.ClientError
+// @Failure 403 {object} dtos.ClientError
+// @Failure 409 {object} dtos.ClientError
+// @Failure 429 {object} dtos.ClientError
+// @Failure 500 {object} dtos.ClientError
+// @Router /users/me/email [put]
+func (c *Controller) ChangeEmail(w http.ResponseWriter, r *http.Request) {
+	claims := r.Context().Value(constants.UserContextKey).(*models.Claims)
+
+	var req dtos.ChangeEmailRequest
+	if err := validation.DecodeJSON(r, &req); err != nil {
+		validation.WriteError(w, err)
+		return
+	}
+
+	if err := c.service.ChangeEmail(claims.UserID, req); err != nil {
+		writeAccountError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func writeAccountError(w http.ResponseWriter, err error) {
+	var validationError *validation.Error
+	switch {
+	case errors.As(err, &validationError):
+		validation.WriteError(w, err)
+	case errors.Is(err, auth.ErrIncorrectPassword):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	case errors.Is(err, auth.ErrEmailTaken):
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		slog.Error("Could not update account", "error", err)
+		http.Error(w, "Could not update account", http.StatusInternalServerError)
+	}
+}
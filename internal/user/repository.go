@@ -2,6 +2,8 @@ package user
 
 import (
 	"database/sql"
+	"fmt"
+	"time"
 
 	"go-sober/internal/models"
 )
@@ -14,30 +16,128 @@ func NewRepository(db *sql.DB) *Repository {
 	return &Repository{db: db}
 }
 
+// UpsertUserProfile saves the profile of a user, and adds the weight to their history when it changed
 func (r *Repository) UpsertUserProfile(userID int64, profile *models.UserProfile) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var previousWeightKg float64
+	err = tx.QueryRow("SELECT weight_kg FROM user_profiles WHERE user_id = ?", userID).Scan(&previousWeightKg)
+	if err != nil && err != sql.ErrNoRows {
+		return fmt.Errorf("error querying user profile: %w", err)
+	}
+	weightChanged := err == sql.ErrNoRows || previousWeightKg != profile.WeightKg
+
+	// Without a time zone, a new profile is in UTC and an existing one keeps its time zone
 	query := `
-        INSERT INTO user_profiles (user_id, weight_kg, gender, updated_at)
-        VALUES (?, ?, ?, CURRENT_TIMESTAMP)
+        INSERT INTO user_profiles (user_id, weight_kg, gender, timezone, day_rollover_hour, updated_at)
+        VALUES (?1, ?2, ?3, COALESCE(NULLIF(?4, ''), 'UTC'), ?5, CURRENT_TIMESTAMP)
         ON CONFLICT(user_id) DO UPDATE SET
             weight_kg = excluded.weight_kg,
             gender = excluded.gender,
+            timezone = COALESCE(NULLIF(?4, ''), user_profiles.timezone),
+            day_rollover_hour = excluded.day_rollover_hour,
             updated_at = CURRENT_TIMESTAMP
     `
-	_, err := r.db.Exec(query, userID, profile.WeightKg, profile.Gender)
-	return err
+	if _, err := tx.Exec(query, userID, profile.WeightKg, profile.Gender, profile.Timezone, profile.DayRolloverHour); err != nil {
+		return fmt.Errorf("error saving user profile: %w", err)
+	}
+
+	if weightChanged {
+		_, err := tx.Exec(`
+            INSERT INTO user_weight_history (user_id, weight_kg, recorded_at)
+            VALUES (?, ?, ?)
+        `, userID, profile.WeightKg, time.Now().UTC())
+		if err != nil {
+			return fmt.Errorf("error saving weight history: %w", err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+// GetWeightHistory returns the weights of a user, oldest first
+func (r *Repository) GetWeightHistory(userID int64) ([]models.WeightEntry, error) {
+	rows, err := r.db.Query(`
+        SELECT weight_kg, recorded_at
+        FROM user_weight_history
+        WHERE user_id = ?
+        ORDER BY recorded_at, id
+    `, userID)
+	if err != nil {
+		return nil, fmt.Errorf("error querying weight history: %w", err)
+	}
+	defer rows.Close()
+
+	history := []models.WeightEntry{}
+	for rows.Next() {
+		var entry models.WeightEntry
+		if err := rows.Scan(&entry.WeightKg, &entry.RecordedAt); err != nil {
+			return nil, fmt.Errorf("error scanning weight entry: %w", err)
+		}
+		history = append(history, entry)
+	}
+	return history, rows.Err()
+}
+
+// GetDrinkLimits returns the limits a user set for themselves, none when they did not
+func (r *Repository) GetDrinkLimits(userID int64) (*models.DrinkLimits, error) {
+	limits := &models.DrinkLimits{}
+	err := r.db.QueryRow(`
+        SELECT daily_limit, weekly_limit, dry_days_per_week, updated_at
+        FROM user_drink_limits
+        WHERE user_id = ?
+    `, userID).Scan(&limits.DailyLimit, &limits.WeeklyLimit, &limits.DryDaysPerWeek, &limits.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return &models.DrinkLimits{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error querying drink limits: %w", err)
+	}
+	return limits, nil
+}
+
+// SetDrinkLimits replaces the limits of a user, removing them when none is set
+func (r *Repository) SetDrinkLimits(userID int64, limits *models.DrinkLimits) error {
+	if !limits.IsSet() {
+		if _, err := r.db.Exec("DELETE FROM user_drink_limits WHERE user_id = ?", userID); err != nil {
+			return fmt.Errorf("error deleting drink limits: %w", err)
+		}
+		return nil
+	}
+
+	_, err := r.db.Exec(`
+        INSERT INTO user_drink_limits (user_id, daily_limit, weekly_limit, dry_days_per_week, updated_at)
+        VALUES (?1, ?2, ?3, ?4, ?5)
+        ON CONFLICT(user_id) DO UPDATE SET
+            daily_limit = excluded.daily_limit,
+            weekly_limit = excluded.weekly_limit,
+            dry_days_per_week = excluded.dry_days_per_week,
+            updated_at = excluded.updated_at
+    `, userID, limits.DailyLimit, limits.WeeklyLimit, limits.DryDaysPerWeek, time.Now().UTC())
+	if err != nil {
+		return fmt.Errorf("error saving drink limits: %w", err)
+	}
+	return nil
 }
 
 func (r *Repository) GetUserProfile(userID int64) (*models.UserProfile, error) {
 	query := `
-        SELECT user_id, weight_kg, gender, created_at, updated_at
+        SELECT user_id, weight_kg, gender, timezone, day_rollover_hour, created_at, updated_at
         FROM user_profiles
         WHERE user_id = ?
     `
-	profile := &models.UserProfile{}
+	// Without a profile, the defaults of the columns apply
+	profile := &models.UserProfile{Timezone: "UTC", DayRolloverHour: models.DefaultDayRolloverHour}
 	err := r.db.QueryRow(query, userID).Scan(
 		&profile.UserID,
 		&profile.WeightKg,
 		&profile.Gender,
+		&profile.Timezone,
+		&profile.DayRolloverHour,
 		&profile.CreatedAt,
 		&profile.UpdatedAt,
 	)
@@ -46,3 +146,193 @@ func (r *Repository) GetUserProfile(userID int64) (*models.UserProfile, error) {
 	}
 	return profile, err
 }
+
+// GetUsersToDelete returns the users who asked to delete their account before the given time
+func (r *Repository) GetUsersToDelete(requestedBefore time.Time) ([]int64, error) {
+	rows, err := r.db.Query(`
+        SELECT id
+        FROM users
+        WHERE deletion_requested_at IS NOT NULL AND deletion_requested_at <= ?
+        ORDER BY id
+    `, requestedBefore.UTC())
+	if err != nil {
+		return nil, fmt.Errorf("error querying users to delete: %w", err)
+	}
+	defer rows.Close()
+
+	var userIDs []int64
+	for rows.Next() {
+		var userID int64
+		if err := rows.Scan(&userID); err != nil {
+			return nil, fmt.Errorf("error scanning user id: %w", err)
+		}
+		userIDs = append(userIDs, userID)
+	}
+	return userIDs, rows.Err()
+}
+
+// userDeletions remove every record of a user, in order. The details of the drinks are shared
+// between users, they are removed afterwards by DeleteUser. The audit events are kept for
+// security, without the data identifying the person.
+var userDeletions = []string{
+	"DELETE FROM drink_logs WHERE user_id = ?1",
+	"DELETE FROM user_profiles WHERE user_id = ?1",
+	"DELETE FROM user_weight_history WHERE user_id = ?1",
+	"DELETE FROM user_drink_limits WHERE user_id = ?1",
+	"DELETE FROM goals WHERE user_id = ?1",
+	"DELETE FROM sessions WHERE user_id = ?1",
+	"DELETE FROM revoked_tokens WHERE user_id = ?1",
+	"DELETE FROM user_tokens WHERE user_id = ?1",
+	"DELETE FROM user_mfa WHERE user_id = ?1",
+	"DELETE FROM mfa_recovery_codes WHERE user_id = ?1",
+	"DELETE FROM user_identities WHERE user_id = ?1",
+	"DELETE FROM personal_access_tokens WHERE user_id = ?1",
+	"UPDATE audit_events SET email = '', ip_address = '', user_agent = '' WHERE user_id = ?1",
+}
+
+// DeleteUser deletes a user who asked for it and all their records, in one transaction. The
+// details of the drinks no other user logged are deleted after the logs referencing them.
+// It returns sql.ErrNoRows if the user does not exist or no longer wants to be deleted.
+func (r *Repository) DeleteUser(userID int64) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	detailIDs, err := drinkDetailIDs(tx, userID)
+	if err != nil {
+		return err
+	}
+
+	for _, query := range userDeletions {
+		if _, err := tx.Exec(query, userID); err != nil {
+			return fmt.Errorf("error deleting user records: %w", err)
+		}
+	}
+
+	for _, detailID := range detailIDs {
+		_, err := tx.Exec(`DELETE FROM drink_log_details
+            WHERE id = ?1 AND NOT EXISTS (SELECT 1 FROM drink_logs WHERE drink_details_id = ?1)`, detailID)
+		if err != nil {
+			return fmt.Errorf("error deleting drink details: %w", err)
+		}
+	}
+
+	result, err := tx.Exec("DELETE FROM users WHERE id = ? AND deletion_requested_at IS NOT NULL", userID)
+	if err != nil {
+		return fmt.Errorf("error deleting user: %w", err)
+	}
+	if rows, err := result.RowsAffected(); err != nil || rows == 0 {
+		return sql.ErrNoRows
+	}
+
+	return tx.Commit()
+}
+
+// drinkDetailIDs returns the details of the drinks logged by a user
+func drinkDetailIDs(tx *sql.Tx, userID int64) ([]int64, error) {
+	rows, err := tx.Query("SELECT DISTINCT drink_details_id FROM drink_logs WHERE user_id = ?", userID)
+	if err != nil {
+		return nil, fmt.Errorf("error getting drink details: %w", err)
+	}
+	defer rows.Close()
+
+	var detailIDs []int64
+	for rows.Next() {
+		var detailID int64
+		if err := rows.Scan(&detailID); err != nil {
+			return nil, fmt.Errorf("error scanning drink details id: %w", err)
+		}
+		detailIDs = append(detailIDs, detailID)
+	}
+	return detailIDs, rows.Err()
+}
+
+// userExports are the files of a data export and the records they hold. Secrets (password
+// and token hashes, TOTP secret) are left out.
+var userExports = []struct {
+	file  string
+	query string
+}{
+	{"account.json", `SELECT id, email, role, email_verified_at, disabled_at, deletion_requested_at, created_at, updated_at
+        FROM users WHERE id = ?`},
+	{"profile.json", `SELECT weight_kg, gender, timezone, day_rollover_hour, created_at, updated_at FROM user_profiles WHERE user_id = ?`},
+	{"weight_history.json", `SELECT weight_kg, recorded_at FROM user_weight_history WHERE user_id = ? ORDER BY recorded_at, id`},
+	{"drink_limits.json", `SELECT daily_limit, weekly_limit, dry_days_per_week, updated_at FROM user_drink_limits WHERE user_id = ?`},
+	{"goals.json", `SELECT id, name, type, period, target, start_date, end_date, created_at, updated_at
+        FROM goals WHERE user_id = ? ORDER BY id`},
+	{"drink_logs.json", `SELECT dl.id, dld.name, dld.type, dld.size_value, dld.size_unit, dld.abv, dld.standard_drinks,
+            dld.template_id, dl.logged_at, dl.updated_at
+        FROM drink_logs dl
+        JOIN drink_log_details dld ON dl.drink_details_id = dld.id
+        WHERE dl.user_id = ?
+        ORDER BY dl.logged_at, dl.id`},
+	{"sessions.json", `SELECT family_id, user_agent, ip_address, created_at, expires_at, rotated_at, revoked_at
+        FROM sessions WHERE user_id = ? ORDER BY id`},
+	{"revoked_tokens.json", `SELECT jti, expires_at, revoked_at FROM revoked_tokens WHERE user_id = ? ORDER BY revoked_at`},
+	{"email_tokens.json", `SELECT purpose, created_at, expires_at, used_at FROM user_tokens WHERE user_id = ? ORDER BY id`},
+	{"mfa.json", `SELECT created_at, enabled_at FROM user_mfa WHERE user_id = ?`},
+	{"mfa_recovery_codes.json", `SELECT created_at, used_at FROM mfa_recovery_codes WHERE user_id = ? ORDER BY id`},
+	{"identities.json", `SELECT issuer, subject, email, created_at, last_login_at FROM user_identities WHERE user_id = ? ORDER BY id`},
+	{"personal_access_tokens.json", `SELECT id, name, token_prefix, scopes, created_at, expires_at, last_used_at, revoked_at
+        FROM personal_access_tokens WHERE user_id = ? ORDER BY id`},
+	{"audit_events.json", `SELECT event_type, email, ip_address, user_agent, details, created_at
+        FROM audit_events WHERE user_id = ? ORDER BY id`},
+}
+
+// ExportFile is a file of a data export, with one object per record
+type ExportFile struct {
+	Name    string
+	Records []map[string]any
+}
+
+// GetUserExport returns every record of a user, grouped in the files of the data export
+func (r *Repository) GetUserExport(userID int64) ([]ExportFile, error) {
+	files := make([]ExportFile, 0, len(userExports))
+	for _, export := range userExports {
+		records, err := r.queryRecords(export.query, userID)
+		if err != nil {
+			return nil, fmt.Errorf("error exporting %s: %w", export.file, err)
+		}
+		files = append(files, ExportFile{Name: export.file, Records: records})
+	}
+	return files, nil
+}
+
+// queryRecords returns the rows of a query as maps from column names to values
+func (r *Repository) queryRecords(query string, args ...any) ([]map[string]any, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+
+	records := []map[string]any{}
+	for rows.Next() {
+		values := make([]any, len(columns))
+		pointers := make([]any, len(columns))
+		for i := range values {
+			pointers[i] = &values[i]
+		}
+		if err := rows.Scan(pointers...); err != nil {
+			return nil, err
+		}
+
+		record := make(map[string]any, len(columns))
+		for i, column := range columns {
+			// Text can come back as bytes, which would be encoded in base64
+			if b, ok := values[i].([]byte); ok {
+				values[i] = string(b)
+			}
+			record[column] = values[i]
+		}
+		records = append(records, record)
+	}
+	return records, rows.Err()
+}
@@ -0,0 +1,236 @@
+package user
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"go-sober/internal/models"
+
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// setupTestDB applies the real migrations, deleting an account must cover every table
+func setupTestDB(t *testing.T) *Repository {
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("Failed to create database: %v", err)
+	}
+	db.SetMaxOpenConns(1)
+
+	migrations, err := filepath.Glob("../../db/migrations/*.up.sql")
+	require.NoError(t, err)
+	require.NotEmpty(t, migrations)
+	for _, migration := range migrations {
+		statements, err := os.ReadFile(migration)
+		require.NoError(t, err)
+		if _, err := db.Exec(string(statements)); err != nil {
+			t.Fatalf("Failed to apply %s: %v", migration, err)
+		}
+	}
+
+	return NewRepository(db)
+}
+
+// createUserWithData creates a user with a record in every table tied to users
+func createUserWithData(t *testing.T, repo *Repository, email, drink string) int64 {
+	result, err := repo.db.Exec("INSERT INTO users (email, password) VALUES (?, 'hash')", email)
+	require.NoError(t, err)
+	userID, err := result.LastInsertId()
+	require.NoError(t, err)
+
+	exec := func(query string, args ...any) {
+		_, err := repo.db.Exec(query, args...)
+		require.NoError(t, err, query)
+	}
+	exec(`INSERT INTO drink_log_details (name, type, size_value, size_unit, abv, hash_key)
+		SELECT ?1, 'beer', 500, 'ml', 0.05, ?1 WHERE NOT EXISTS (SELECT 1 FROM drink_log_details WHERE hash_key = ?1)`, drink)
+	exec("INSERT INTO drink_logs (user_id, drink_details_id) SELECT ?, id FROM drink_log_details WHERE hash_key = ?", userID, drink)
+	exec("INSERT INTO user_profiles (user_id, weight_kg, gender) VALUES (?, 70, 'male')", userID)
+	exec("INSERT INTO user_weight_history (user_id, weight_kg, recorded_at) VALUES (?, 70, CURRENT_TIMESTAMP)", userID)
+	exec(`INSERT INTO goals (user_id, name, type, period, target, start_date, created_at, updated_at)
+		VALUES (?, 'Ten a week', 'max_standard_drinks', 'weekly', 10, '2025-01-06', CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)`, userID)
+	exec("INSERT INTO user_drink_limits (user_id, weekly_limit, updated_at) VALUES (?, 10, CURRENT_TIMESTAMP)", userID)
+	exec(`INSERT INTO sessions (user_id, family_id, token_hash, user_agent, ip_address, expires_at)
+		VALUES (?1, 'family-' || ?1, 'session-' || ?1, 'phone', '10.0.0.1', '2030-01-01')`, userID)
+	exec("INSERT INTO revoked_tokens (jti, user_id, expires_at) VALUES ('jti-' || ?1, ?1, '2030-01-01')", userID)
+	exec(`INSERT INTO user_tokens (user_id, purpose, token_hash, expires_at)
+		VALUES (?1, 'email_verification', 'user-token-' || ?1, '2030-01-01')`, userID)
+	exec("INSERT INTO audit_events (user_id, event_type, email, ip_address) VALUES (?, 'login_failed', ?, '10.0.0.1')", userID, email)
+	exec("INSERT INTO user_mfa (user_id, totp_secret) VALUES (?, 'secret')", userID)
+	exec("INSERT INTO mfa_recovery_codes (user_id, code_hash) VALUES (?1, 'code-' || ?1)", userID)
+	exec("INSERT INTO user_identities (user_id, issuer, subject) VALUES (?1, 'https://id.example.com', 'subject-' || ?1)", userID)
+	exec(`INSERT INTO personal_access_tokens (user_id, name, token_hash, token_prefix, scopes)
+		VALUES (?1, 'script', 'pat-' || ?1, 'sober_pat_abcd', 'bac:read')`, userID)
+	require.NoError(t, err)
+	return userID
+}
+
+// countUserRows counts the rows of the user in every table having a user_id column
+func countUserRows(t *testing.T, repo *Repository, userID int64) map[string]int {
+	rows, err := repo.db.Query(`
+		SELECT m.name
+		FROM sqlite_master m, pragma_table_info(m.name) c
+		WHERE m.type = 'table' AND c.name = 'user_id'
+	`)
+	require.NoError(t, err)
+	var tables []string
+	for rows.Next() {
+		var table string
+		require.NoError(t, rows.Scan(&table))
+		tables = append(tables, table)
+	}
+	require.NoError(t, rows.Err())
+	rows.Close()
+
+	counts := map[string]int{}
+	for _, table := range tables {
+		var count int
+		require.NoError(t, repo.db.QueryRow(fmt.Sprintf("SELECT COUNT(*) FROM %q WHERE user_id = ?", table), userID).Scan(&count))
+		counts[table] = count
+	}
+	return counts
+}
+
+func TestUpsertUserProfileKeepsTheWeightHistory(t *testing.T) {
+	repo := setupTestDB(t)
+	result, err := repo.db.Exec("INSERT INTO users (email, password) VALUES ('weight@example.com', 'hash')")
+	require.NoError(t, err)
+	userID, err := result.LastInsertId()
+	require.NoError(t, err)
+
+	require.NoError(t, repo.UpsertUserProfile(userID, &models.UserProfile{WeightKg: 80, Gender: models.Male}))
+	// Only weight changes are recorded
+	require.NoError(t, repo.UpsertUserProfile(userID, &models.UserProfile{WeightKg: 80, Gender: models.Unknown}))
+	require.NoError(t, repo.UpsertUserProfile(userID, &models.UserProfile{WeightKg: 76.5, Gender: models.Unknown}))
+
+	profile, err := repo.GetUserProfile(userID)
+	require.NoError(t, err)
+	assert.Equal(t, 76.5, profile.WeightKg)
+
+	history, err := repo.GetWeightHistory(userID)
+	require.NoError(t, err)
+	require.Len(t, history, 2)
+	assert.Equal(t, 80.0, history[0].WeightKg)
+	assert.Equal(t, 76.5, history[1].WeightKg)
+	assert.False(t, history[1].RecordedAt.Before(history[0].RecordedAt))
+
+	history, err = repo.GetWeightHistory(userID + 1)
+	require.NoError(t, err)
+	assert.Empty(t, history)
+}
+
+func TestDeleteUser(t *testing.T) {
+	repo := setupTestDB(t)
+	// The records must be deleted in an order the foreign keys accept
+	_, err := repo.db.Exec("PRAGMA foreign_keys = ON")
+	require.NoError(t, err)
+
+	userID := createUserWithData(t, repo, "deleted@example.com", "Own beer")
+	otherID := createUserWithData(t, repo, "other@example.com", "Shared beer")
+	_, err = repo.db.Exec(`
+		INSERT INTO drink_logs (user_id, drink_details_id) SELECT ?, id FROM drink_log_details WHERE hash_key = 'Shared beer'
+	`, userID)
+	require.NoError(t, err)
+
+	// Only the users who asked for it are deleted
+	assert.ErrorIs(t, repo.DeleteUser(userID), sql.ErrNoRows)
+
+	requestedAt := time.Now().Add(-time.Hour)
+	_, err = repo.db.Exec("UPDATE users SET deletion_requested_at = ? WHERE id = ?", requestedAt.UTC(), userID)
+	require.NoError(t, err)
+
+	toDelete, err := repo.GetUsersToDelete(requestedAt.Add(-time.Minute))
+	require.NoError(t, err)
+	assert.Empty(t, toDelete)
+	toDelete, err = repo.GetUsersToDelete(time.Now())
+	require.NoError(t, err)
+	assert.Equal(t, []int64{userID}, toDelete)
+
+	require.NoError(t, repo.DeleteUser(userID))
+
+	for table, count := range countUserRows(t, repo, userID) {
+		if table == "audit_events" {
+			continue
+		}
+		assert.Zero(t, count, table)
+	}
+
+	// The audit events are kept, anonymised
+	var email, ipAddress string
+	require.NoError(t, repo.db.QueryRow("SELECT email, ip_address FROM audit_events WHERE user_id = ?", userID).Scan(&email, &ipAddress))
+	assert.Empty(t, email)
+	assert.Empty(t, ipAddress)
+
+	// The drinks only the user logged are gone, the shared ones stay
+	var details []string
+	rows, err := repo.db.Query("SELECT name FROM drink_log_details ORDER BY name")
+	require.NoError(t, err)
+	for rows.Next() {
+		var name string
+		require.NoError(t, rows.Scan(&name))
+		details = append(details, name)
+	}
+	rows.Close()
+	assert.Equal(t, []string{"Shared beer"}, details)
+
+	// The other user is untouched
+	for table, count := range countUserRows(t, repo, otherID) {
+		assert.Equal(t, 1, count, table)
+	}
+}
+
+func TestGetUserExport(t *testing.T) {
+	repo := setupTestDB(t)
+	userID := createUserWithData(t, repo, "export@example.com", "Export beer")
+	createUserWithData(t, repo, "other@example.com", "Other beer")
+
+	files, err := repo.GetUserExport(userID)
+	require.NoError(t, err)
+
+	byName := map[string][]map[string]any{}
+	for _, file := range files {
+		byName[file.Name] = file.Records
+		assert.Len(t, file.Records, 1, file.Name)
+	}
+
+	account := byName["account.json"][0]
+	assert.Equal(t, "export@example.com", account["email"])
+	assert.NotContains(t, account, "password")
+
+	drink := byName["drink_logs.json"][0]
+	assert.Equal(t, "Export beer", drink["name"])
+	assert.IsType(t, time.Time{}, drink["logged_at"])
+
+	assert.NotContains(t, byName["sessions.json"][0], "token_hash")
+	assert.NotContains(t, byName["mfa.json"][0], "totp_secret")
+	assert.Equal(t, "bac:read", byName["personal_access_tokens.json"][0]["scopes"])
+}
+
+func TestDrinkLimits(t *testing.T) {
+	repo := setupTestDB(t)
+	userID := createUserWithData(t, repo, "limits@example.com", "limits-beer")
+	require.NoError(t, repo.SetDrinkLimits(userID, &models.DrinkLimits{}))
+
+	limits, err := repo.GetDrinkLimits(userID)
+	require.NoError(t, err)
+	assert.False(t, limits.IsSet())
+
+	weekly := 7.5
+	require.NoError(t, repo.SetDrinkLimits(userID, &models.DrinkLimits{WeeklyLimit: &weekly, DryDaysPerWeek: 2}))
+	limits, err = repo.GetDrinkLimits(userID)
+	require.NoError(t, err)
+	assert.True(t, limits.IsSet())
+	assert.Nil(t, limits.DailyLimit)
+	assert.Equal(t, 7.5, *limits.WeeklyLimit)
+	assert.Equal(t, 2, limits.DryDaysPerWeek)
+
+	// Setting no limit removes them
+	require.NoError(t, repo.SetDrinkLimits(userID, &models.DrinkLimits{}))
+	assert.Equal(t, 0, countUserRows(t, repo, userID)["user_drink_limits"])
+}
@@ -1,23 +1,46 @@
 package user
 
 import (
+	"archive/zip"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"time"
+
+	"go-sober/internal/auth"
 	"go-sober/internal/dtos"
 	"go-sober/internal/models"
+	"go-sober/platform"
 )
 
 type Service struct {
-	repo *Repository
+	repo     *Repository
+	accounts *auth.Service
+	config   platform.AccountConfig
 }
 
-func NewService(repo *Repository) *Service {
-	return &Service{repo: repo}
+func NewService(repo *Repository, accounts *auth.Service, config platform.AccountConfig) *Service {
+	return &Service{repo: repo, accounts: accounts, config: config}
 }
 
 func (s *Service) UpdateUserProfile(userID int64, req dtos.UpdateUserProfileRequest) error {
+	current, err := s.repo.GetUserProfile(userID)
+	if err != nil {
+		return err
+	}
+
 	profile := &models.UserProfile{
-		UserID:   userID,
-		WeightKg: req.WeightKg,
-		Gender:   req.Gender,
+		UserID:          userID,
+		WeightKg:        req.WeightKg,
+		Gender:          req.Gender,
+		Timezone:        req.Timezone,
+		DayRolloverHour: current.DayRolloverHour,
+	}
+	if req.DayRolloverHour != nil {
+		profile.DayRolloverHour = *req.DayRolloverHour
 	}
 	return s.repo.UpsertUserProfile(userID, profile)
 }
@@ -25,3 +48,95 @@ func (s *Service) UpdateUserProfile(userID int64, req dtos.UpdateUserProfileRequ
 func (s *Service) GetUserProfile(userID int64) (*models.UserProfile, error) {
 	return s.repo.GetUserProfile(userID)
 }
+
+func (s *Service) GetWeightHistory(userID int64) ([]models.WeightEntry, error) {
+	return s.repo.GetWeightHistory(userID)
+}
+
+func (s *Service) GetDrinkLimits(userID int64) (*models.DrinkLimits, error) {
+	return s.repo.GetDrinkLimits(userID)
+}
+
+func (s *Service) SetDrinkLimits(userID int64, req dtos.DrinkLimitsRequest) (*models.DrinkLimits, error) {
+	limits := &models.DrinkLimits{
+		DailyLimit:     req.DailyLimit,
+		WeeklyLimit:    req.WeeklyLimit,
+		DryDaysPerWeek: req.DryDaysPerWeek,
+	}
+	if err := s.repo.SetDrinkLimits(userID, limits); err != nil {
+		return nil, err
+	}
+	return s.repo.GetDrinkLimits(userID)
+}
+
+// ChangePassword sets a new password, checking the current one, and logs the other devices out
+func (s *Service) ChangePassword(claims *models.Claims, req dtos.ChangePasswordRequest) error {
+	return s.accounts.ChangePassword(claims, req.CurrentPassword, req.NewPassword)
+}
+
+// ChangeEmail moves the account to a new address, checking the password, and sends a link to verify it
+func (s *Service) ChangeEmail(userID int64, req dtos.ChangeEmailRequest) error {
+	return s.accounts.ChangeEmail(userID, req.Password, req.Email)
+}
+
+// RequestAccountDeletion schedules the deletion of the account after the grace period, and
+// returns when it will be deleted
+func (s *Service) RequestAccountDeletion(userID int64) (time.Time, error) {
+	return s.accounts.RequestAccountDeletion(userID)
+}
+
+// PurgeDeletedAccounts deletes the accounts whose grace period is over, and returns how many
+func (s *Service) PurgeDeletedAccounts() (int, error) {
+	userIDs, err := s.repo.GetUsersToDelete(time.Now().Add(-s.config.DeletionGracePeriod))
+	if err != nil {
+		return 0, err
+	}
+
+	deleted := 0
+	for _, userID := range userIDs {
+		err := s.repo.DeleteUser(userID)
+		if errors.Is(err, sql.ErrNoRows) {
+			// The user logged in again in the meantime
+			continue
+		}
+		if err != nil {
+			return deleted, err
+		}
+		slog.Info("Deleted account", "user_id", userID)
+		deleted++
+	}
+	return deleted, nil
+}
+
+// PurgeDeletedAccountsEvery purges the deleted accounts now, and then at every interval
+func (s *Service) PurgeDeletedAccountsEvery(interval time.Duration) {
+	for {
+		if _, err := s.PurgeDeletedAccounts(); err != nil {
+			slog.Error("Could not purge deleted accounts", "error", err)
+		}
+		time.Sleep(interval)
+	}
+}
+
+// ExportUserData writes a zip archive of every record of the user, one JSON file per kind
+func (s *Service) ExportUserData(userID int64, w io.Writer) error {
+	files, err := s.repo.GetUserExport(userID)
+	if err != nil {
+		return err
+	}
+
+	archive := zip.NewWriter(w)
+	for _, file := range files {
+		f, err := archive.Create(file.Name)
+		if err != nil {
+			return fmt.Errorf("error adding %s to the export: %w", file.Name, err)
+		}
+
+		encoder := json.NewEncoder(f)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(file.Records); err != nil {
+			return fmt.Errorf("error encoding %s: %w", file.Name, err)
+		}
+	}
+	return archive.Close()
+}
@@ -0,0 +1,45 @@
+package user
+
+import (
+	"testing"
+
+	"go-sober/internal/dtos"
+	"go-sober/internal/models"
+	"go-sober/platform"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUpdateUserProfileDayRolloverHour(t *testing.T) {
+	repo := setupTestDB(t)
+	service := NewService(repo, nil, platform.AccountConfig{})
+	result, err := repo.db.Exec("INSERT INTO users (email, password) VALUES ('rollover@example.com', 'hash')")
+	require.NoError(t, err)
+	userID, err := result.LastInsertId()
+	require.NoError(t, err)
+
+	// Without a profile, the defaults apply
+	profile, err := service.GetUserProfile(userID)
+	require.NoError(t, err)
+	assert.Equal(t, models.DefaultDayRolloverHour, profile.DayRolloverHour)
+	assert.Equal(t, "UTC", profile.Timezone)
+
+	require.NoError(t, service.UpdateUserProfile(userID, dtos.UpdateUserProfileRequest{WeightKg: 70, Gender: models.Male}))
+	profile, err = service.GetUserProfile(userID)
+	require.NoError(t, err)
+	assert.Equal(t, models.DefaultDayRolloverHour, profile.DayRolloverHour)
+
+	midnight := 0
+	require.NoError(t, service.UpdateUserProfile(userID, dtos.UpdateUserProfileRequest{WeightKg: 70, Gender: models.Male, DayRolloverHour: &midnight}))
+	profile, err = service.GetUserProfile(userID)
+	require.NoError(t, err)
+	assert.Equal(t, 0, profile.DayRolloverHour)
+
+	// Omitting the hour keeps it
+	require.NoError(t, service.UpdateUserProfile(userID, dtos.UpdateUserProfileRequest{WeightKg: 72, Gender: models.Male, Timezone: "Europe/Paris"}))
+	profile, err = service.GetUserProfile(userID)
+	require.NoError(t, err)
+	assert.Equal(t, 0, profile.DayRolloverHour)
+	assert.Equal(t, "Europe/Paris", profile.Timezone)
+}
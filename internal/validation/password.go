@@ -0,0 +1,149 @@
+package validation
+
+import (
+	"bufio"
+	_ "embed"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode/utf8"
+
+	"go-sober/internal/dtos"
+	"go-sober/platform"
+)
+
+// bcrypt ignores the bytes after the 72nd
+const maxPasswordBytes = 72
+
+//go:embed common_passwords.txt
+var commonPasswords string
+
+// PasswordPolicy decides whether a password is strong enough
+type PasswordPolicy struct {
+	minLength          int
+	maxEmailSimilarity float64
+	breached           map[string]bool
+}
+
+// NewPasswordPolicy builds the policy from the configuration. The embedded list of
+// common passwords is always used, the configured list file extends it.
+func NewPasswordPolicy(config platform.PasswordConfig) (*PasswordPolicy, error) {
+	policy := &PasswordPolicy{
+		minLength:          config.MinLength,
+		maxEmailSimilarity: config.MaxEmailSimilarity,
+		breached:           make(map[string]bool),
+	}
+
+	if err := policy.loadBreached(strings.NewReader(commonPasswords)); err != nil {
+		return nil, err
+	}
+
+	if config.BreachedListFile != "" {
+		file, err := os.Open(config.BreachedListFile)
+		if err != nil {
+			return nil, fmt.Errorf("could not open breached password list: %w", err)
+		}
+		defer file.Close()
+
+		if err := policy.loadBreached(file); err != nil {
+			return nil, err
+		}
+	}
+
+	return policy, nil
+}
+
+func (p *PasswordPolicy) loadBreached(reader io.Reader) error {
+	scanner := bufio.NewScanner(reader)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		p.breached[strings.ToLower(line)] = true
+	}
+
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("could not read breached password list: %w", err)
+	}
+	return nil
+}
+
+// Check returns an *Error listing every rule the password breaks, or nil
+func (p *PasswordPolicy) Check(password, email string) error {
+	var fields []dtos.FieldError
+	reject := func(rule, message string) {
+		fields = append(fields, dtos.FieldError{Field: "password", Rule: rule, Message: message})
+	}
+
+	if utf8.RuneCountInString(password) < p.minLength {
+		reject("min", fmt.Sprintf("must be at least %d characters long", p.minLength))
+	}
+	if len(password) > maxPasswordBytes {
+		reject("max", fmt.Sprintf("must be at most %d bytes long", maxPasswordBytes))
+	}
+
+	lower := strings.ToLower(password)
+	if p.breached[lower] {
+		reject("breached", "is too common, it appears in known data breaches")
+	}
+
+	if p.similarToEmail(lower, strings.ToLower(email)) {
+		reject("similar_to_email", "is too similar to the email address")
+	}
+
+	if len(fields) == 0 {
+		return nil
+	}
+	return &Error{Message: "Password is too weak", Fields: fields}
+}
+
+// similarToEmail tells whether a lowercase password is derived from a lowercase email
+func (p *PasswordPolicy) similarToEmail(password, email string) bool {
+	if email == "" {
+		return false
+	}
+
+	localPart, _, _ := strings.Cut(email, "@")
+	for _, part := range []string{email, localPart} {
+		if len(part) >= 4 && strings.Contains(password, part) {
+			return true
+		}
+		if similarity(password, part) > p.maxEmailSimilarity {
+			return true
+		}
+	}
+	return false
+}
+
+// similarity is 1 for equal strings and 0 for completely different ones, based on the edit distance
+func similarity(a, b string) float64 {
+	runesA, runesB := []rune(a), []rune(b)
+	longest := max(len(runesA), len(runesB))
+	if longest == 0 {
+		return 1
+	}
+	return 1 - float64(levenshtein(runesA, runesB))/float64(longest)
+}
+
+func levenshtein(a, b []rune) int {
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+	return previous[len(b)]
+}
@@ -0,0 +1,62 @@
+package validation
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"go-sober/platform"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPasswordPolicy(t *testing.T) {
+	listFile := filepath.Join(t.TempDir(), "breached.txt")
+	require.NoError(t, os.WriteFile(listFile, []byte("# extra entries\nCorrectHorseBattery\n"), 0o600))
+
+	policy, err := NewPasswordPolicy(platform.PasswordConfig{
+		MinLength:          10,
+		BreachedListFile:   listFile,
+		MaxEmailSimilarity: 0.7,
+	})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		password string
+		rules    []string
+	}{
+		{"strong", "tram-lantern-47-quiet", nil},
+		{"too short", "x9!kq", []string{"min"}},
+		{"too long for bcrypt", string(make([]byte, 73)), []string{"max"}},
+		{"built-in list", "password123", []string{"breached"}},
+		{"configured list, any case", "correcthorsebattery", []string{"breached"}},
+		{"contains the email name", "jeanne.dupont-2024", []string{"similar_to_email"}},
+		{"close to the email", "jeanedupond", []string{"similar_to_email"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := policy.Check(tt.password, "Jeanne.Dupont@example.com")
+			if tt.rules == nil {
+				assert.NoError(t, err)
+				return
+			}
+
+			var validationError *Error
+			require.ErrorAs(t, err, &validationError)
+			var rules []string
+			for _, field := range validationError.Fields {
+				assert.Equal(t, "password", field.Field)
+				rules = append(rules, field.Rule)
+			}
+			assert.Equal(t, tt.rules, rules)
+		})
+	}
+}
+
+func TestNewPasswordPolicyMissingFile(t *testing.T) {
+	_, err := NewPasswordPolicy(platform.PasswordConfig{MinLength: 10, BreachedListFile: "does-not-exist.txt"})
+	assert.Error(t, err)
+}
@@ -0,0 +1,155 @@
+package validation
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"go-sober/internal/dtos"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// The validator caches struct metadata, it is meant to be shared
+var validate = newValidator()
+
+func newValidator() *validator.Validate {
+	v := validator.New(validator.WithRequiredStructEnabled())
+
+	// Report fields with their JSON names, as the client sent them
+	v.RegisterTagNameFunc(func(field reflect.StructField) string {
+		name := strings.SplitN(field.Tag.Get("json"), ",", 2)[0]
+		switch name {
+		case "-":
+			return ""
+		case "":
+			return field.Name
+		}
+		return name
+	})
+	return v
+}
+
+// Error is a request rejected by validation, with the reason of each invalid field
+type Error struct {
+	Message string
+	Fields  []dtos.FieldError
+}
+
+func (e *Error) Error() string {
+	if len(e.Fields) == 0 {
+		return e.Message
+	}
+
+	reasons := make([]string, 0, len(e.Fields))
+	for _, field := range e.Fields {
+		reasons = append(reasons, field.Field+" "+field.Message)
+	}
+	return fmt.Sprintf("%s: %s", e.Message, strings.Join(reasons, ", "))
+}
+
+// ClientError converts the error to the body returned to the client
+func (e *Error) ClientError() dtos.ClientError {
+	details := make([]interface{}, 0, len(e.Fields))
+	for _, field := range e.Fields {
+		details = append(details, field)
+	}
+
+	return dtos.ClientError{
+		Code:    http.StatusBadRequest,
+		Type:    dtos.ValidationErrorType,
+		Message: e.Message,
+		Details: details,
+	}
+}
+
+// Struct checks the `validate` tags of a request DTO
+func Struct(s interface{}) error {
+	err := validate.Struct(s)
+	if err == nil {
+		return nil
+	}
+
+	var validationErrors validator.ValidationErrors
+	if !errors.As(err, &validationErrors) {
+		return err
+	}
+
+	result := &Error{Message: "Invalid request"}
+	for _, fieldError := range validationErrors {
+		result.Fields = append(result.Fields, dtos.FieldError{
+			Field:   fieldPath(fieldError),
+			Rule:    fieldError.Tag(),
+			Message: fieldMessage(fieldError),
+		})
+	}
+	return result
+}
+
+// DecodeJSON decodes a JSON request body into a DTO and validates it
+func DecodeJSON(r *http.Request, dst interface{}) error {
+	if err := json.NewDecoder(r.Body).Decode(dst); err != nil {
+		return &Error{Message: "Invalid request body"}
+	}
+	return Struct(dst)
+}
+
+// WriteError sends a validation error as a ClientError, other errors as an internal error
+func WriteError(w http.ResponseWriter, err error) {
+	var validationError *Error
+	if !errors.As(err, &validationError) {
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusBadRequest)
+	json.NewEncoder(w).Encode(validationError.ClientError())
+}
+
+// fieldPath is the JSON path of a field, without the name of the request struct
+func fieldPath(fieldError validator.FieldError) string {
+	namespace := fieldError.Namespace()
+	if index := strings.Index(namespace, "."); index >= 0 {
+		return namespace[index+1:]
+	}
+	return namespace
+}
+
+func fieldMessage(fieldError validator.FieldError) string {
+	param := fieldError.Param()
+	switch fieldError.Tag() {
+	case "required", "required_without":
+		return "is required"
+	case "email":
+		return "must be a valid email address"
+	case "gt":
+		return "must be greater than " + param
+	case "gte":
+		return "must be at least " + param
+	case "lt":
+		return "must be less than " + param
+	case "lte":
+		return "must be at most " + param
+	case "min":
+		if fieldError.Kind() == reflect.String {
+			return fmt.Sprintf("must be at least %s characters long", param)
+		}
+		return "must be at least " + param
+	case "max":
+		if fieldError.Kind() == reflect.String {
+			return fmt.Sprintf("must be at most %s characters long", param)
+		}
+		return "must be at most " + param
+	case "oneof":
+		return "must be one of: " + strings.ReplaceAll(param, " ", ", ")
+	case "datetime":
+		return "must be a date such as 2025-01-31"
+	case "timezone":
+		return "must be an IANA time zone, such as Europe/Paris"
+	}
+	return "is invalid"
+}
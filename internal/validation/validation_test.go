@@ -0,0 +1,118 @@
+package validation
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"go-sober/internal/dtos"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStruct(t *testing.T) {
+	templateID := 3
+	name, drinkType, size, unit, percentage, alcoholFree := "IPA", "beer", 50, "cl", 6.5, 0.0
+
+	tests := []struct {
+		name   string
+		input  interface{}
+		fields []dtos.FieldError
+	}{
+		{
+			name:  "valid signup",
+			input: dtos.UserSignupRequest{Email: "john@example.com", Password: "correct horse"},
+		},
+		{
+			name:  "malformed email",
+			input: dtos.UserSignupRequest{Email: "john@", Password: "correct horse"},
+			fields: []dtos.FieldError{
+				{Field: "email", Rule: "email", Message: "must be a valid email address"},
+			},
+		},
+		{
+			name:  "missing fields",
+			input: dtos.UserLoginRequest{},
+			fields: []dtos.FieldError{
+				{Field: "email", Rule: "required", Message: "is required"},
+				{Field: "password", Rule: "required", Message: "is required"},
+			},
+		},
+		{
+			name:  "drink log from a template",
+			input: dtos.CreateDrinkLogRequest{TemplateID: &templateID},
+		},
+		{
+			name:  "alcohol-free drink log from a template",
+			input: dtos.CreateDrinkLogRequest{ABV: &alcoholFree, TemplateID: &templateID},
+		},
+		{
+			name:  "drink log with a percentage as ABV",
+			input: dtos.CreateDrinkLogRequest{Name: &name, Type: &drinkType, SizeValue: &size, SizeUnit: &unit, ABV: &percentage},
+			fields: []dtos.FieldError{
+				{Field: "abv", Rule: "lte", Message: "must be at most 1"},
+			},
+		},
+		{
+			name:  "drink log without details nor template",
+			input: dtos.CreateDrinkLogRequest{Name: &name, Type: &drinkType, SizeValue: &size, SizeUnit: &unit},
+			fields: []dtos.FieldError{
+				{Field: "abv", Rule: "required_without", Message: "is required"},
+			},
+		},
+		{
+			name:  "profile with an unknown gender",
+			input: dtos.UpdateUserProfileRequest{WeightKg: 70, Gender: "other"},
+			fields: []dtos.FieldError{
+				{Field: "gender", Rule: "oneof", Message: "must be one of: male, female, unknown"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Struct(tt.input)
+			if tt.fields == nil {
+				assert.NoError(t, err)
+				return
+			}
+
+			var validationError *Error
+			require.ErrorAs(t, err, &validationError)
+			assert.Equal(t, tt.fields, validationError.Fields)
+		})
+	}
+}
+
+func TestDecodeJSONAndWriteError(t *testing.T) {
+	request := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"email": "not-an-email"}`))
+
+	var signup dtos.UserSignupRequest
+	err := DecodeJSON(request, &signup)
+	require.Error(t, err)
+
+	recorder := httptest.NewRecorder()
+	WriteError(recorder, err)
+
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+	assert.Equal(t, "application/json", recorder.Header().Get("Content-Type"))
+
+	var body struct {
+		Code    int               `json:"code"`
+		Type    string            `json:"type"`
+		Details []dtos.FieldError `json:"details"`
+	}
+	require.NoError(t, json.NewDecoder(recorder.Body).Decode(&body))
+	assert.Equal(t, http.StatusBadRequest, body.Code)
+	assert.Equal(t, dtos.ValidationErrorType, body.Type)
+	assert.Len(t, body.Details, 2)
+
+	// A body that is not JSON has no field details
+	request = httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{`))
+	var validationError *Error
+	require.ErrorAs(t, DecodeJSON(request, &signup), &validationError)
+	assert.Equal(t, "Invalid request body", validationError.Message)
+}
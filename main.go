@@ -3,18 +3,28 @@ package main
 import (
 	"log"
 	"net/http"
+	_ "time/tzdata" // IANA time zones of the users, when the system has none
 
 	httpSwagger "github.com/swaggo/http-swagger/v2" // http-swagger middleware
 
 	_ "go-sober/docs" // swagger docs
+	"go-sober/internal/admin"
 	"go-sober/internal/analytics"
+	"go-sober/internal/audit"
 	"go-sober/internal/auth"
 	"go-sober/internal/bac"
 	"go-sober/internal/database"
 	"go-sober/internal/drinks"
+	"go-sober/internal/goals"
 	"go-sober/internal/health"
+	"go-sober/internal/llm"
+	"go-sober/internal/mailer"
 	"go-sober/internal/middleware"
+	"go-sober/internal/models"
+	"go-sober/internal/parser"
+	"go-sober/internal/ratelimit"
 	"go-sober/internal/user"
+	"go-sober/internal/validation"
 	"go-sober/platform"
 )
 
@@ -42,71 +52,171 @@ func main() {
 	defer db.Close()
 
 	// Initialize repository, service, controller and middleware with config
+	mail, err := mailer.New(config.Mailer)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	passwordPolicy, err := validation.NewPasswordPolicy(config.Auth.Password)
+	if err != nil {
+		log.Fatal(err)
+	}
+
 	authRepo := auth.NewRepository(db)
-	authService := auth.NewService(authRepo, config)
+	auditRepo := audit.NewRepository(db)
+	authService, err := auth.NewService(authRepo, config, mail, passwordPolicy, auditRepo)
+	if err != nil {
+		log.Fatal(err)
+	}
 	authController := auth.NewController(authService)
 	authMiddleware := middleware.NewAuthMiddleware(authService)
+	// Routes sending emails: a few per minute, per client address or per user
+	passwordResetRateLimiter := middleware.NewRateLimitMiddleware(ratelimit.NewLimiter(2, 3))
+	verificationRateLimiter := middleware.NewRateLimitMiddleware(ratelimit.NewLimiter(2, 3))
+	// Routes checking a second factor code, on top of the login throttling
+	mfaRateLimiter := middleware.NewRateLimitMiddleware(ratelimit.NewLimiter(5, 5))
+	// Routes checking the current password, which also send emails
+	accountRateLimiter := middleware.NewRateLimitMiddleware(ratelimit.NewLimiter(3, 5))
+	// Each OIDC login start stores a pending login
+	oidcRateLimiter := middleware.NewRateLimitMiddleware(ratelimit.NewLimiter(10, 10))
 
 	// Initialize the health components
 	healthController := health.NewController()
 
 	// Initialize the drinks components
 	drinkRepo := drinks.NewRepository(db)
-	drinkService := drinks.NewService(drinkRepo)
+	groqProvider, err := llm.NewGroqProvider(config.LLM)
+	if err != nil {
+		log.Fatal(err)
+	}
+	llmProvider := llm.NewLimitedProvider(groqProvider, config.LLM.Limits.MaxConcurrentCalls, config.LLM.Limits.QueueTimeout)
+	parseCaches := []parser.ParseCache{parser.NewMemoryParseCache(config.LLM.Cache.TTL, config.LLM.Cache.MaxEntries)}
+	if config.LLM.Cache.Persistent {
+		sqliteParseCache := parser.NewSQLiteParseCache(db, config.LLM.Cache.TTL)
+		if _, err := sqliteParseCache.PurgeExpired(); err != nil {
+			log.Fatal(err)
+		}
+		parseCaches = append(parseCaches, sqliteParseCache)
+	}
+	drinkLLMParser := parser.NewCachedParser(parser.NewLLMParser(llmProvider), parser.LLMCacheVersion(config.LLM.Groq.Model), parseCaches...)
+	drinkRuleParser := parser.NewRuleParser()
+	drinkService := drinks.NewService(drinkRepo, drinkLLMParser, drinkRuleParser)
 	drinkController := drinks.NewController(drinkService, db)
 
-	// Initialize analytics components
-	drinkStatsRepo := analytics.NewRepository(db)
-	drinkStatsService := analytics.NewService(drinkStatsRepo)
-	drinkStatsController := analytics.NewController(drinkStatsService)
-
-	// Initialize realtime components
-	bacService := bac.NewService(drinkRepo)
-	bacController := bac.NewController(bacService)
+	parseRateLimiter := middleware.NewRateLimitMiddleware(ratelimit.NewLimiter(config.LLM.Limits.UserRequestsPerMinute, config.LLM.Limits.UserBurst))
 
 	// Initialize user components
 	userRepo := user.NewRepository(db)
-	userService := user.NewService(userRepo)
+	userService := user.NewService(userRepo, authService, config.Auth.Account)
 	userController := user.NewController(userService)
+	go userService.PurgeDeletedAccountsEvery(config.Auth.Account.PurgeInterval)
+
+	// Initialize realtime components, the BAC uses the weight history of the users
+	bacService := bac.NewService(drinkRepo, userRepo)
+	bacController := bac.NewController(bacService)
+
+	// Initialize analytics components, grouped in the time zone of the users
+	drinkStatsRepo := analytics.NewRepository(db)
+	drinkStatsService := analytics.NewService(drinkStatsRepo, userRepo)
+	drinkStatsController := analytics.NewController(drinkStatsService)
+
+	// Initialize goals components, evaluated with the drinks of each day of the analytics
+	goalsRepo := goals.NewRepository(db)
+	goalsService := goals.NewService(goalsRepo, drinkStatsRepo, userRepo)
+	goalsController := goals.NewController(goalsService)
+
+	// Initialize admin components
+	adminRepo := admin.NewRepository(db)
+	adminService := admin.NewService(adminRepo, authService)
+	adminController := admin.NewController(adminService)
 
 	// Create a new ServeMux to use with the logging middleware
 	mux := http.NewServeMux()
 
 	mux.HandleFunc("GET /api/v1/health", healthController.Health)
+	mux.HandleFunc("GET /.well-known/jwks.json", authController.JWKS)
 
 	// [Public routes]
 	// Auth
 	mux.HandleFunc("POST /api/v1/auth/signup", authController.SignUp)
 	mux.HandleFunc("POST /api/v1/auth/login", authController.Login)
+	mux.HandleFunc("POST /api/v1/auth/login/mfa", authController.LoginMFA)
+	mux.HandleFunc("GET /api/v1/auth/oidc/login", oidcRateLimiter.LimitPerIP(authController.StartOIDCLogin))
+	mux.HandleFunc("POST /api/v1/auth/oidc/callback", authController.CompleteOIDCLogin)
+	mux.HandleFunc("POST /api/v1/auth/refresh", authController.Refresh)
+	mux.HandleFunc("POST /api/v1/auth/verify-email", authController.VerifyEmail)
+	mux.HandleFunc("POST /api/v1/auth/password/forgot", passwordResetRateLimiter.LimitPerIP(authController.ForgotPassword))
+	mux.HandleFunc("POST /api/v1/auth/password/reset", authController.ResetPassword)
 
 	// User
-	mux.HandleFunc("GET /api/v1/users/profile", authMiddleware.RequireAuth(userController.GetProfile))
-	mux.HandleFunc("PUT /api/v1/users/profile", authMiddleware.RequireAuth(userController.UpdateProfile))
+	mux.HandleFunc("GET /api/v1/users/profile", authMiddleware.RequireScope(models.ScopeProfileRead, userController.GetProfile))
+	mux.HandleFunc("PUT /api/v1/users/profile", authMiddleware.RequireScope(models.ScopeProfileWrite, userController.UpdateProfile))
+	mux.HandleFunc("GET /api/v1/users/profile/weight-history", authMiddleware.RequireScope(models.ScopeProfileRead, userController.GetWeightHistory))
+	mux.HandleFunc("GET /api/v1/users/profile/limits", authMiddleware.RequireScope(models.ScopeProfileRead, userController.GetDrinkLimits))
+	mux.HandleFunc("PUT /api/v1/users/profile/limits", authMiddleware.RequireScope(models.ScopeProfileWrite, userController.SetDrinkLimits))
 
 	// Drink templates
 	mux.HandleFunc("GET /api/v1/drink-templates", drinkController.GetDrinkTemplates)
 	mux.HandleFunc("GET /api/v1/drink-templates/{id}", drinkController.GetDrinkTemplate)
-	mux.HandleFunc("PUT /api/v1/drink-templates/{id}", drinkController.UpdateDrinkTemplate)
-	mux.HandleFunc("DELETE /api/v1/drink-templates/{id}", drinkController.DeleteDrinkTemplate)
 
 	// [Protected routes]
 	// Auth
 	mux.HandleFunc("GET /api/v1/auth/me", authMiddleware.RequireAuth(authController.Me))
+	mux.HandleFunc("POST /api/v1/auth/verify-email/resend", authMiddleware.RequireAuth(verificationRateLimiter.LimitPerUser(authController.ResendVerificationEmail)))
+	mux.HandleFunc("POST /api/v1/auth/logout", authMiddleware.RequireAuth(authController.Logout))
+	mux.HandleFunc("POST /api/v1/auth/logout-all", authMiddleware.RequireAuth(authController.LogoutAll))
+	mux.HandleFunc("GET /api/v1/auth/sessions", authMiddleware.RequireAuth(authController.GetSessions))
+	mux.HandleFunc("DELETE /api/v1/auth/sessions/{id}", authMiddleware.RequireAuth(authController.RevokeSession))
+	mux.HandleFunc("POST /api/v1/auth/mfa/totp", authMiddleware.RequireAuth(authController.EnrolTOTP))
+	mux.HandleFunc("POST /api/v1/auth/mfa/totp/confirm", authMiddleware.RequireAuth(mfaRateLimiter.LimitPerUser(authController.ConfirmTOTP)))
+	mux.HandleFunc("POST /api/v1/auth/mfa/totp/disable", authMiddleware.RequireAuth(mfaRateLimiter.LimitPerUser(authController.DisableTOTP)))
+	mux.HandleFunc("POST /api/v1/auth/mfa/recovery-codes", authMiddleware.RequireAuth(mfaRateLimiter.LimitPerUser(authController.RegenerateRecoveryCodes)))
+	mux.HandleFunc("POST /api/v1/auth/tokens", authMiddleware.RequireAuth(authController.CreatePersonalAccessToken))
+	mux.HandleFunc("GET /api/v1/auth/tokens", authMiddleware.RequireAuth(authController.GetPersonalAccessTokens))
+	mux.HandleFunc("DELETE /api/v1/auth/tokens/{id}", authMiddleware.RequireAuth(authController.RevokePersonalAccessToken))
+	mux.HandleFunc("PUT /api/v1/users/me/password", authMiddleware.RequireAuth(accountRateLimiter.LimitPerUser(userController.ChangePassword)))
+	mux.HandleFunc("PUT /api/v1/users/me/email", authMiddleware.RequireAuth(accountRateLimiter.LimitPerUser(userController.ChangeEmail)))
+	mux.HandleFunc("DELETE /api/v1/users/me", authMiddleware.RequireAuth(userController.DeleteAccount))
+	mux.HandleFunc("GET /api/v1/users/me/export", authMiddleware.RequireAuth(userController.ExportData))
 
 	// Blood Alcohol Content (BAC)
-	mux.HandleFunc("GET /api/v1/bac/timeline", authMiddleware.RequireAuth(bacController.GetBAC))
-	mux.HandleFunc("GET /api/v1/bac/current", authMiddleware.RequireAuth(bacController.GetCurrentBAC))
+	mux.HandleFunc("GET /api/v1/bac/timeline", authMiddleware.RequireScope(models.ScopeBACRead, bacController.GetBAC))
+	mux.HandleFunc("GET /api/v1/bac/current", authMiddleware.RequireScope(models.ScopeBACRead, bacController.GetCurrentBAC))
 
 	// Drink logging
-	mux.HandleFunc("GET /api/v1/drink-logs", authMiddleware.RequireAuth(drinkController.GetDrinkLogs))
-	mux.HandleFunc("POST /api/v1/drink-logs", authMiddleware.RequireAuth(drinkController.CreateDrinkLog))
-	mux.HandleFunc("PUT /api/v1/drink-logs", authMiddleware.RequireAuth(drinkController.UpdateDrinkLog))
-	mux.HandleFunc("DELETE /api/v1/drink-logs/{id}", authMiddleware.RequireAuth(drinkController.DeleteDrinkLog))
-	mux.HandleFunc("POST /api/v1/drink-logs/parse", authMiddleware.RequireAuth(drinkController.ParseDrinkLog))
+	mux.HandleFunc("GET /api/v1/drink-logs", authMiddleware.RequireScope(models.ScopeDrinksRead, drinkController.GetDrinkLogs))
+	mux.HandleFunc("POST /api/v1/drink-logs", authMiddleware.RequireScope(models.ScopeDrinksWrite, drinkController.CreateDrinkLog))
+	mux.HandleFunc("PUT /api/v1/drink-logs", authMiddleware.RequireScope(models.ScopeDrinksWrite, drinkController.UpdateDrinkLog))
+	mux.HandleFunc("DELETE /api/v1/drink-logs/{id}", authMiddleware.RequireScope(models.ScopeDrinksWrite, drinkController.DeleteDrinkLog))
+	mux.HandleFunc("POST /api/v1/drink-logs/parse", authMiddleware.RequireScope(models.ScopeDrinksWrite, parseRateLimiter.LimitPerUser(drinkController.ParseDrinkLog)))
 
 	// Analytics
-	mux.HandleFunc("GET /api/v1/analytics/drink-stats", authMiddleware.RequireAuth(drinkStatsController.GetDrinkStats))
-	mux.HandleFunc("GET /api/v1/analytics/monthly-bac", authMiddleware.RequireAuth(drinkStatsController.GetMonthlyBACStats))
+	mux.HandleFunc("GET /api/v1/analytics/drink-stats", authMiddleware.RequireScope(models.ScopeAnalyticsRead, drinkStatsController.GetDrinkStats))
+	mux.HandleFunc("GET /api/v1/analytics/monthly-bac", authMiddleware.RequireScope(models.ScopeAnalyticsRead, drinkStatsController.GetMonthlyBACStats))
+	mux.HandleFunc("GET /api/v1/analytics/guidelines", authMiddleware.RequireScope(models.ScopeAnalyticsRead, drinkStatsController.GetGuidelines))
+	mux.HandleFunc("GET /api/v1/analytics/streaks", authMiddleware.RequireScope(models.ScopeAnalyticsRead, drinkStatsController.GetStreaks))
+	mux.HandleFunc("GET /api/v1/analytics/heatmap", authMiddleware.RequireScope(models.ScopeAnalyticsRead, drinkStatsController.GetHeatmap))
+	mux.HandleFunc("GET /api/v1/analytics/breakdown", authMiddleware.RequireScope(models.ScopeAnalyticsRead, drinkStatsController.GetBreakdown))
+
+	// Goals
+	mux.HandleFunc("GET /api/v1/goals", authMiddleware.RequireScope(models.ScopeGoalsRead, goalsController.GetGoals))
+	mux.HandleFunc("POST /api/v1/goals", authMiddleware.RequireScope(models.ScopeGoalsWrite, goalsController.CreateGoal))
+	mux.HandleFunc("GET /api/v1/goals/{id}", authMiddleware.RequireScope(models.ScopeGoalsRead, goalsController.GetGoal))
+	mux.HandleFunc("PUT /api/v1/goals/{id}", authMiddleware.RequireScope(models.ScopeGoalsWrite, goalsController.UpdateGoal))
+	mux.HandleFunc("DELETE /api/v1/goals/{id}", authMiddleware.RequireScope(models.ScopeGoalsWrite, goalsController.DeleteGoal))
+
+	// [Admin routes]
+	// Drink template catalogue
+	mux.HandleFunc("POST /api/v1/drink-templates", authMiddleware.RequireAuth(authMiddleware.RequireRole(models.RoleAdmin, drinkController.CreateDrinkTemplate)))
+	mux.HandleFunc("PUT /api/v1/drink-templates/{id}", authMiddleware.RequireAuth(authMiddleware.RequireRole(models.RoleAdmin, drinkController.UpdateDrinkTemplate)))
+	mux.HandleFunc("DELETE /api/v1/drink-templates/{id}", authMiddleware.RequireAuth(authMiddleware.RequireRole(models.RoleAdmin, drinkController.DeleteDrinkTemplate)))
+
+	// Users and system
+	mux.HandleFunc("GET /api/v1/admin/users", authMiddleware.RequireAuth(authMiddleware.RequireRole(models.RoleAdmin, adminController.ListUsers)))
+	mux.HandleFunc("POST /api/v1/admin/users/{id}/disable", authMiddleware.RequireAuth(authMiddleware.RequireRole(models.RoleAdmin, adminController.DisableUser)))
+	mux.HandleFunc("POST /api/v1/admin/users/{id}/enable", authMiddleware.RequireAuth(authMiddleware.RequireRole(models.RoleAdmin, adminController.EnableUser)))
+	mux.HandleFunc("PUT /api/v1/admin/users/{id}/role", authMiddleware.RequireAuth(authMiddleware.RequireRole(models.RoleAdmin, adminController.SetUserRole)))
+	mux.HandleFunc("GET /api/v1/admin/stats", authMiddleware.RequireAuth(authMiddleware.RequireRole(models.RoleAdmin, adminController.GetStats)))
 
 	// Swagger documentation
 	mux.HandleFunc("GET /api/v1/swagger/doc.json", httpSwagger.WrapHandler)
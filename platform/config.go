@@ -24,6 +24,17 @@ type LLMConfig struct {
 		Model   string `env:"GROQ_MODEL" envDefault:"llama-3.2-1b-preview"`
 		APIKey  string `env:"GROQ_API_KEY"`
 	}
+	Limits struct {
+		MaxConcurrentCalls    int           `env:"LLM_MAX_CONCURRENT_CALLS" envDefault:"4"`
+		QueueTimeout          time.Duration `env:"LLM_QUEUE_TIMEOUT" envDefault:"10s"`
+		UserRequestsPerMinute int           `env:"LLM_USER_REQUESTS_PER_MINUTE" envDefault:"20"`
+		UserBurst             int           `env:"LLM_USER_BURST" envDefault:"5"`
+	}
+	Cache struct {
+		TTL        time.Duration `env:"LLM_CACHE_TTL" envDefault:"168h"`
+		MaxEntries int           `env:"LLM_CACHE_MAX_ENTRIES" envDefault:"5000"`
+		Persistent bool          `env:"LLM_CACHE_PERSISTENT" envDefault:"false"` // Also store results in SQLite
+	}
 }
 
 type DatabaseConfig struct {
@@ -35,9 +46,68 @@ type DatabaseConfig struct {
 	}
 }
 
+type PasswordConfig struct {
+	MinLength          int     `env:"PASSWORD_MIN_LENGTH" envDefault:"10"`
+	BreachedListFile   string  `env:"PASSWORD_BREACHED_LIST_FILE" envDefault:""` // One password per line, extends the built-in list
+	MaxEmailSimilarity float64 `env:"PASSWORD_MAX_EMAIL_SIMILARITY" envDefault:"0.7"`
+}
+
+// LoginConfig throttles failed logins, per account and per client address
+type LoginConfig struct {
+	FreeAttempts       int           `env:"LOGIN_FREE_ATTEMPTS" envDefault:"3"` // Failures before the backoff starts
+	BaseDelay          time.Duration `env:"LOGIN_BASE_DELAY" envDefault:"1s"`   // Doubled after each further failure
+	MaxDelay           time.Duration `env:"LOGIN_MAX_DELAY" envDefault:"1m"`
+	LockoutThreshold   int           `env:"LOGIN_LOCKOUT_THRESHOLD" envDefault:"10"` // Failures on an account before it is locked
+	IPFreeAttempts     int           `env:"LOGIN_IP_FREE_ATTEMPTS" envDefault:"10"`  // Higher, addresses can be shared
+	IPLockoutThreshold int           `env:"LOGIN_IP_LOCKOUT_THRESHOLD" envDefault:"50"`
+	LockoutDuration    time.Duration `env:"LOGIN_LOCKOUT_DURATION" envDefault:"15m"`
+}
+
+type MFAConfig struct {
+	Issuer       string        `env:"MFA_ISSUER" envDefault:"Sober"`     // Account label prefix shown by authenticator apps
+	ChallengeTTL time.Duration `env:"MFA_CHALLENGE_TTL" envDefault:"5m"` // Time to enter the code after the password
+}
+
+// OIDCConfig is the identity provider users can log in with, OIDC is disabled without an issuer
+type OIDCConfig struct {
+	Issuer       string        `env:"OIDC_ISSUER" envDefault:""`
+	ClientID     string        `env:"OIDC_CLIENT_ID" envDefault:""`
+	ClientSecret string        `env:"OIDC_CLIENT_SECRET" envDefault:""`
+	RedirectURL  string        `env:"OIDC_REDIRECT_URL" envDefault:"http://localhost:3000/login/oidc"` // Page of the UI that posts the code back
+	Scopes       []string      `env:"OIDC_SCOPES" envDefault:"openid,email,profile"`
+	LoginTTL     time.Duration `env:"OIDC_LOGIN_TTL" envDefault:"10m"` // Time to log in at the provider
+}
+
+// AccountConfig is the deletion of accounts asked by their users
+type AccountConfig struct {
+	DeletionGracePeriod time.Duration `env:"ACCOUNT_DELETION_GRACE_PERIOD" envDefault:"720h"` // Time to change one's mind, by logging in again
+	PurgeInterval       time.Duration `env:"ACCOUNT_PURGE_INTERVAL" envDefault:"1h"`          // How often the accounts past it are deleted
+}
+
 type AuthConfig struct {
 	JWT struct {
-		Secret string `env:"JWT_SECRET"`
+		Algorithm          string        `env:"JWT_ALGORITHM" envDefault:"HS256"`         // HS256 with the secret, or RS256 or EdDSA with the keys of the database
+		Secret             string        `env:"JWT_SECRET" envDefault:""`                 // Still accepted after switching to RS256 or EdDSA, until removed
+		KeyRefreshInterval time.Duration `env:"JWT_KEY_REFRESH_INTERVAL" envDefault:"1m"` // How soon rotated keys are picked up
+		AccessTokenTTL     time.Duration `env:"JWT_ACCESS_TOKEN_TTL" envDefault:"15m"`
+		RefreshTokenTTL    time.Duration `env:"JWT_REFRESH_TOKEN_TTL" envDefault:"720h"`
+	}
+	Password PasswordConfig
+	Login    LoginConfig
+	MFA      MFAConfig
+	OIDC     OIDCConfig
+	Account  AccountConfig
+}
+
+type MailerConfig struct {
+	Driver  string `env:"MAIL_DRIVER" envDefault:"log"` // smtp, file or log
+	From    string `env:"MAIL_FROM" envDefault:"Sober <no-reply@sober.local>"`
+	FileDir string `env:"MAIL_FILE_DIR" envDefault:"tmp/mails"`
+	SMTP    struct {
+		Host     string `env:"SMTP_HOST" envDefault:"localhost"`
+		Port     int    `env:"SMTP_PORT" envDefault:"1025"`
+		Username string `env:"SMTP_USERNAME" envDefault:""`
+		Password string `env:"SMTP_PASSWORD" envDefault:""`
 	}
 }
 
@@ -46,10 +116,12 @@ type Config struct {
 	AppVersion  string `env:"APP_VERSION" envDefault:"unknown"`
 	Environment string `env:"ENVIRONMENT"`
 	Port        string `env:"PORT" envDefault:"8080"`
+	BaseURL     string `env:"APP_BASE_URL" envDefault:"http://localhost:3000"` // Front-end URL used in email links
 	Logger      LoggerConfig
 	Database    DatabaseConfig
 	Auth        AuthConfig
 	LLM         LLMConfig
+	Mailer      MailerConfig
 }
 
 var AppConfig *Config = nil
@@ -29,6 +29,9 @@ type LoginFormValues = z.infer<typeof loginSchema>;
 export function LoginForm() {
   const router = useRouter();
   const [isLoading, setIsLoading] = useState(false);
+  // Set when the password is right but the account needs a second factor
+  const [mfaToken, setMfaToken] = useState<string | null>(null);
+  const [mfaCode, setMfaCode] = useState("");
 
   // Initialize form with default values
   const form = useForm<LoginFormValues>({
@@ -42,10 +45,16 @@ export function LoginForm() {
   async function onSubmit(data: LoginFormValues) {
     setIsLoading(true);
     try {
-      const response = await apiService.login(data.email, data.password);
+      const response = mfaToken
+        ? await apiService.loginMFA(mfaToken, mfaCode)
+        : await apiService.login(data.email, data.password);
+      if (response.mfa_required && response.mfa_token) {
+        setMfaToken(response.mfa_token);
+        return;
+      }
       localStorage.setItem(
         process.env.NEXT_PUBLIC_LOCALSTORAGE_TOKEN_KEY!,
-        response.token
+        response.token!
       );
       toast.success("Welcome back!", {
         description: "Signed in successfully. Taking you to your dashboard...",
@@ -55,7 +64,9 @@ export function LoginForm() {
     } catch (error) {
       console.error("Login failed:", error);
       toast.error("Unable to sign in", {
-        description: "Please check your email and password",
+        description: mfaToken
+          ? "Please check your authentication code"
+          : "Please check your email and password",
         duration: 5000,
       });
     } finally {
@@ -90,6 +101,19 @@ export function LoginForm() {
             </FormItem>
           )}
         />
+        {mfaToken && (
+          <FormItem>
+            <FormLabel>Authentication code</FormLabel>
+            <Input
+              inputMode="numeric"
+              autoComplete="one-time-code"
+              placeholder="Code from your app, or a recovery code"
+              value={mfaCode}
+              onChange={(event) => setMfaCode(event.target.value)}
+              autoFocus
+            />
+          </FormItem>
+        )}
         <Button type="submit" className="w-full" disabled={isLoading}>
           {isLoading ? "Signing you in..." : "Sign In"}
         </Button>
@@ -2,6 +2,7 @@ import type {
     ApiError,
     // Auth types
     UserLoginRequest,
+    LoginMFARequest,
     UserLoginResponse,
     UserSignupRequest,
     UserSignupResponse,
@@ -85,6 +86,20 @@ export class ApiService {
         return this.handleJsonResponse<UserLoginResponse>(response);
     }
 
+    // Second login step, for accounts with two-factor authentication
+    async loginMFA(mfaToken: string, code: string): Promise<UserLoginResponse> {
+        const request: LoginMFARequest = { mfa_token: mfaToken, code };
+        const response = await fetch(`${this.baseUrl}/auth/login/mfa`, {
+            method: 'POST',
+            headers: {
+                'Content-Type': 'application/json',
+            },
+            body: JSON.stringify(request),
+        });
+
+        return this.handleJsonResponse<UserLoginResponse>(response);
+    }
+
     async signup(email: string, password: string): Promise<UserSignupResponse> {
         const request: UserSignupRequest = { email, password };
         const response = await fetch(`${this.baseUrl}/auth/signup`, {
@@ -13,9 +13,29 @@ export interface UserLoginRequest {
     password: string;
 }
 
+// Accounts with two-factor authentication get an MFA token instead of the tokens
 export interface UserLoginResponse {
     message: string;
+    token?: string;
+    refresh_token?: string;
+    expires_in?: number;
+    mfa_required?: boolean;
+    mfa_token?: string;
+}
+
+export interface LoginMFARequest {
+    mfa_token: string;
+    code: string;
+}
+
+export interface RefreshTokenRequest {
+    refresh_token: string;
+}
+
+export interface RefreshTokenResponse {
     token: string;
+    refresh_token: string;
+    expires_in: number;
 }
 
 export interface UserSignupRequest {
@@ -30,6 +50,12 @@ export interface UserSignupResponse {
 export interface UserMeResponse {
     email: string;
     user_id: number;
+    role: 'user' | 'admin';
+}
+
+export interface AccountDeletionResponse {
+    message: string;
+    delete_at: string;
 }
 
 
@@ -38,6 +64,8 @@ export interface UserProfile {
     email: string;
     gender: 'male' | 'female' | 'unknown';
     weight_kg: number;
+    timezone: string;
+    day_rollover_hour: number;
     created_at: string;
     updated_at: string;
 }
@@ -45,6 +73,17 @@ export interface UserProfile {
 export interface UpdateUserProfileRequest {
     gender: 'male' | 'female' | 'unknown';
     weight_kg: number;
+    timezone?: string;
+    day_rollover_hour?: number;
+}
+
+export interface WeightEntry {
+    weight_kg: number;
+    recorded_at: string;
+}
+
+export interface WeightHistoryResponse {
+    weights: WeightEntry[];
 }
 export interface DrinkTemplate {
     id: number;
@@ -180,3 +219,140 @@ export interface MonthlyBACStatsResponse {
     categories: BACCategories[];
 }
 
+// Limits are in standard drinks of 10 grams of alcohol, null when they do not apply
+export interface DrinkLimits {
+    daily_limit: number | null;
+    weekly_limit: number | null;
+    dry_days_per_week: number;
+}
+
+export interface DrinkLimitsResponse {
+    limits: DrinkLimits & { updated_at: string };
+}
+
+export interface Guideline extends DrinkLimits {
+    key: string;
+    name: string;
+    description: string;
+}
+
+export interface LimitProgress {
+    standard_drinks: number;
+    limit: number | null;
+    remaining: number | null;
+    over_limit: boolean;
+}
+
+export interface GuidelineProgress {
+    guideline: Guideline;
+    today: LimitProgress & { date: string };
+    this_week: LimitProgress & { start_date: string; dry_days: number; dry_days_required: number };
+    weeks_evaluated: number;
+    weeks_within: number;
+    compliance_rate: number | null;
+    weeks_over_limit: number;
+    days_over_limit: number;
+}
+
+export interface GuidelinesResponse {
+    guidelines: GuidelineProgress[];
+}
+
+export interface Streak {
+    days: number;
+    start_date?: string;
+    end_date?: string;
+}
+
+export interface DryDaysPoint {
+    start_date: string;
+    dry_days: number;
+    days: number;
+}
+
+export interface Milestone {
+    days: number;
+    reached: boolean;
+    reached_on: string | null;
+    days_to_go: number;
+}
+
+// Rows are the days from Monday, columns the hours from midnight
+export interface HeatmapResponse {
+    weekdays: string[];
+    drink_counts: number[][];
+    standard_drinks: number[][];
+    total_drinks: number;
+    total_standard_drinks: number;
+}
+
+export type BreakdownGroupBy = 'type' | 'name' | 'abv_band' | 'size';
+
+export interface BreakdownGroup {
+    group: string;
+    drink_count: number;
+    standard_drinks: number;
+    drink_share: number;
+    standard_drinks_share: number;
+}
+
+export interface BreakdownResponse {
+    group_by: BreakdownGroupBy;
+    groups: BreakdownGroup[];
+    total_drinks: number;
+    total_standard_drinks: number;
+}
+
+export type GoalType = 'max_standard_drinks' | 'min_dry_days' | 'dry_period';
+
+export type GoalStatus = 'in_progress' | 'met' | 'failed';
+
+// Dates are days of the user's calendar, e.g. 2025-01-31
+export interface GoalRequest {
+    name: string;
+    type: GoalType;
+    period?: 'daily' | 'weekly' | 'monthly';
+    target: number;
+    start_date?: string;
+    end_date?: string | null;
+}
+
+export interface Goal extends GoalRequest {
+    id: number;
+    user_id: number;
+    start_date: string;
+    end_date: string | null;
+    created_at: string;
+    updated_at: string;
+}
+
+export interface GoalOutcome {
+    start_date: string;
+    end_date: string;
+    value: number;
+    target: number;
+    progress: number;
+    status: GoalStatus;
+}
+
+export interface GoalResponse {
+    goal: Goal;
+    current: GoalOutcome | null;
+    met: number;
+    failed: number;
+    history?: GoalOutcome[];
+}
+
+export interface GoalsResponse {
+    goals: GoalResponse[];
+}
+
+export interface StreaksResponse {
+    tracked_since: string | null;
+    current_streak: Streak;
+    longest_streak: Streak;
+    dry_days_per_week: DryDaysPoint[];
+    dry_days_per_month: DryDaysPoint[];
+    milestones: Milestone[];
+}
+